@@ -0,0 +1,283 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generatorcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configFileName is looked up in the input path directory and its parents, unless a config file is given explicitly
+const configFileName = "objectbox-generator.json"
+
+// configPathSetting is the config-only setting specifying the input path, i.e. the positional argument on the command line
+const configPathSetting = "path"
+
+// configPathSettings are resolved relative to the directory containing the config file
+var configPathSettings = map[string]bool{configPathSetting: true, "out": true, "out-headers": true, "model": true, "persist": true, "depfile": true, "manifest": true}
+
+// configPathListSettings are comma-separated lists of paths, each resolved like configPathSettings
+var configPathListSettings = map[string]bool{"changelog": true}
+
+// configNonSettings are flags that are only available on the command line
+var configNonSettings = map[string]bool{"config": true, "target": true, "help": true, "version": true, "diagnostics-format": true}
+
+// configTarget is a set of settings (flag values by flag name) to run the generator with.
+type configTarget struct {
+	name     string // empty if the config file doesn't define any targets
+	settings map[string]string
+}
+
+// config holds the contents of a configuration file, e.g.:
+//
+//	{
+//	  "optional": "std::optional",
+//	  "targets": {
+//	    "cpp": {"cpp": true, "out": "generated", "out-headers": "include"},
+//	    "c": {"c": true, "out": "c-generated"}
+//	  }
+//	}
+//
+// Top-level settings apply to all targets, target settings take precedence over them.
+type config struct {
+	file     string
+	settings map[string]string
+	targets  map[string]map[string]string
+}
+
+// findConfig looks for the config file in the directory of the given input path and its parents.
+// Returns nil if there's no config file.
+func findConfig(inPath string) (*config, error) {
+	dir, err := filepath.Abs(configSearchStart(inPath))
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var file = filepath.Join(dir, configFileName)
+		if finfo, err := os.Stat(file); err == nil && finfo.Mode().IsRegular() {
+			return loadConfig(file)
+		}
+
+		var parent = filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// configSearchStart returns the directory to start the config file lookup from, stripping any path pattern
+func configSearchStart(inPath string) string {
+	if len(inPath) == 0 {
+		return "."
+	}
+
+	// Go-style recursive pattern, e.g. "./..."
+	inPath = strings.TrimSuffix(inPath, "/...")
+
+	// glob pattern, e.g. "schemas/*.fbs"
+	if index := strings.IndexAny(inPath, "*?["); index >= 0 {
+		return filepath.Dir(inPath[0:index])
+	}
+
+	if finfo, err := os.Stat(inPath); err == nil && finfo.IsDir() {
+		return inPath
+	}
+	return filepath.Dir(inPath)
+}
+
+// loadConfig reads and validates the given config file
+func loadConfig(file string) (*config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("can't read config file %s: %s", file, err)
+	}
+
+	var values map[string]json.RawMessage
+	if err = json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("can't parse config file %s: %s", file, err)
+	}
+
+	var cfg = &config{file: file}
+
+	if targets, exists := values["targets"]; exists {
+		delete(values, "targets")
+
+		var targetValues map[string]map[string]json.RawMessage
+		if err = json.Unmarshal(targets, &targetValues); err != nil {
+			return nil, fmt.Errorf("invalid targets in config file %s: %s", file, err)
+		}
+		if len(targetValues) == 0 {
+			return nil, fmt.Errorf("invalid targets in config file %s: no target defined", file)
+		}
+
+		cfg.targets = make(map[string]map[string]string)
+		for name, values := range targetValues {
+			if cfg.targets[name], err = cfg.parseSettings(values); err != nil {
+				return nil, fmt.Errorf("invalid target %s in config file %s: %s", name, file, err)
+			}
+		}
+	}
+
+	if cfg.settings, err = cfg.parseSettings(values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %s", file, err)
+	}
+
+	return cfg, nil
+}
+
+// parseSettings converts JSON values to strings as accepted by the flags with the same name
+func (cfg *config) parseSettings(values map[string]json.RawMessage) (map[string]string, error) {
+	var settings = make(map[string]string)
+	for name, raw := range values {
+		if name != configPathSetting && (configNonSettings[name] || flag.Lookup(name) == nil) {
+			return nil, fmt.Errorf("unknown setting %s", name)
+		}
+
+		var decoder = json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("setting %s: %s", name, err)
+		}
+
+		switch v := value.(type) {
+		case string:
+			settings[name] = v
+		case bool:
+			settings[name] = strconv.FormatBool(v)
+		case json.Number:
+			settings[name] = v.String()
+		default:
+			return nil, fmt.Errorf("setting %s: expecting a string, a boolean or a number, got %s", name, string(raw))
+		}
+
+		if configPathSettings[name] && len(settings[name]) > 0 {
+			settings[name] = cfg.resolvePath(settings[name])
+		} else if configPathListSettings[name] && len(settings[name]) > 0 {
+			var paths = strings.Split(settings[name], ",")
+			for i, path := range paths {
+				if path = strings.TrimSpace(path); len(path) > 0 {
+					paths[i] = cfg.resolvePath(path)
+				}
+			}
+			settings[name] = strings.Join(paths, ",")
+		}
+	}
+	return settings, nil
+}
+
+// resolvePath makes the given path relative to the config file directory, keeping path patterns (e.g. "./...") intact
+func (cfg *config) resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	var dir = filepath.Dir(cfg.file)
+	if cwd, err := os.Getwd(); err == nil {
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if rel, err := filepath.Rel(cwd, absDir); err == nil {
+				dir = rel
+			}
+		}
+	}
+
+	if dir == "." {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// getTargets returns the settings of the given target, or all targets (sorted by name) if targetName is empty
+func (cfg *config) getTargets(targetName string) ([]configTarget, error) {
+	if len(cfg.targets) == 0 {
+		if len(targetName) > 0 {
+			return nil, fmt.Errorf("target %s requested but config file %s doesn't define any targets", targetName, cfg.file)
+		}
+		return []configTarget{{settings: cfg.settings}}, nil
+	}
+
+	var names []string
+	if len(targetName) > 0 {
+		if _, exists := cfg.targets[targetName]; !exists {
+			return nil, fmt.Errorf("target %s not found in config file %s", targetName, cfg.file)
+		}
+		names = []string{targetName}
+	} else {
+		for name := range cfg.targets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var result []configTarget
+	for _, name := range names {
+		var target = configTarget{name: name, settings: make(map[string]string)}
+		for key, value := range cfg.settings {
+			target.settings[key] = value
+		}
+		for key, value := range cfg.targets[name] {
+			target.settings[key] = value
+		}
+		result = append(result, target)
+	}
+	return result, nil
+}
+
+// applySettings sets all flags in the following order of precedence: command-line values, target settings, flag defaults
+func applySettings(settings map[string]string, cliValues map[string]string) error {
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, isCli := cliValues[f.Name]; !isCli && err == nil {
+			err = f.Value.Set(f.DefValue)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, values := range []map[string]string{settings, cliValues} {
+		var names []string
+		for name := range values {
+			if name != configPathSetting {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if err := flag.Set(name, values[name]); err != nil {
+				return fmt.Errorf("invalid value %q for setting %s: %s", values[name], name, err)
+			}
+		}
+	}
+	return nil
+}
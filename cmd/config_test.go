@@ -0,0 +1,224 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generatorcmd
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+// testFlags replaces the command-line flags with a fresh set of flags used by the tests below; returns a function
+// restoring the original ones
+func testFlags() (restore func()) {
+	var original = flag.CommandLine
+	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, name := range []string{"out", "out-headers", "model", "changelog", "optional", "config"} {
+		flag.String(name, "", "")
+	}
+	flag.Bool("cpp", false, "")
+	flag.Bool("c", false, "")
+	flag.Int("workers", 0, "")
+	return func() { flag.CommandLine = original }
+}
+
+// writeConfig writes the config file into the given directory, creating the directory if necessary
+func writeConfig(t *testing.T, dir, content string) string {
+	assert.NoErr(t, os.MkdirAll(dir, 0755))
+	var file = filepath.Join(dir, configFileName)
+	assert.NoErr(t, ioutil.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+// tempDir creates a temporary directory, resolving symlinks so that the paths can be compared to os.Getwd()
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "generator-config")
+	assert.NoErr(t, err)
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.NoErr(t, err)
+	return dir
+}
+
+func TestFindConfig(t *testing.T) {
+	defer testFlags()()
+	var dir = tempDir(t)
+	defer os.RemoveAll(dir)
+
+	var file = writeConfig(t, dir, `{"cpp": true}`)
+	var schemas = filepath.Join(dir, "schemas", "sub")
+	assert.NoErr(t, os.MkdirAll(schemas, 0755))
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(schemas, "a.fbs"), []byte("table A { id: ulong; }"), 0644))
+
+	// looked up in the directory of the input path (a file, a directory or a pattern) and its parents
+	for _, inPath := range []string{
+		filepath.Join(schemas, "a.fbs"),
+		schemas,
+		filepath.Join(dir, "schemas") + "/...",
+		filepath.Join(schemas, "*.fbs"),
+	} {
+		cfg, err := findConfig(inPath)
+		assert.NoErr(t, err)
+		if cfg == nil {
+			t.Fatalf("config file not found for %s", inPath)
+		}
+		assert.Eq(t, file, cfg.file)
+	}
+
+	// the closest one is used
+	var closer = writeConfig(t, schemas, `{"c": true}`)
+	cfg, err := findConfig(filepath.Join(schemas, "a.fbs"))
+	assert.NoErr(t, err)
+	assert.Eq(t, closer, cfg.file)
+	assert.Eq(t, map[string]string{"c": "true"}, cfg.settings)
+
+	// an invalid config file is reported, not skipped
+	writeConfig(t, schemas, `{"c": true`)
+	_, err = findConfig(filepath.Join(schemas, "a.fbs"))
+	assert.Err(t, err)
+}
+
+func TestConfigTargets(t *testing.T) {
+	defer testFlags()()
+	var dir = tempDir(t)
+	defer os.RemoveAll(dir)
+
+	cfg, err := loadConfig(writeConfig(t, dir, `{
+	"optional": "std::optional",
+	"workers": 4,
+	"targets": {
+		"cpp": {"cpp": true, "optional": "std::unique_ptr"},
+		"c": {"c": true}
+	}
+}`))
+	assert.NoErr(t, err)
+
+	// all the targets sorted by name, the target settings take precedence over the top-level ones
+	targets, err := cfg.getTargets("")
+	assert.NoErr(t, err)
+	assert.Eq(t, 2, len(targets))
+	assert.Eq(t, "c", targets[0].name)
+	assert.Eq(t, map[string]string{"optional": "std::optional", "workers": "4", "c": "true"}, targets[0].settings)
+	assert.Eq(t, "cpp", targets[1].name)
+	assert.Eq(t, map[string]string{"optional": "std::unique_ptr", "workers": "4", "cpp": "true"}, targets[1].settings)
+
+	targets, err = cfg.getTargets("cpp")
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, len(targets))
+	assert.Eq(t, "cpp", targets[0].name)
+
+	_, err = cfg.getTargets("go")
+	assert.Err(t, err)
+	assert.True(t, strings.Contains(err.Error(), "target go not found in config file"))
+
+	// without targets, the top-level settings form a single unnamed target
+	cfg, err = loadConfig(writeConfig(t, dir, `{"cpp": true}`))
+	assert.NoErr(t, err)
+	targets, err = cfg.getTargets("")
+	assert.NoErr(t, err)
+	assert.Eq(t, []configTarget{{settings: map[string]string{"cpp": "true"}}}, targets)
+
+	_, err = cfg.getTargets("cpp")
+	assert.Err(t, err)
+	assert.True(t, strings.Contains(err.Error(), "target cpp requested but config file"))
+
+	_, err = loadConfig(writeConfig(t, dir, `{"targets": {}}`))
+	assert.Err(t, err)
+	assert.True(t, strings.Contains(err.Error(), "no target defined"))
+}
+
+func TestConfigUnknownSettings(t *testing.T) {
+	defer testFlags()()
+	var dir = tempDir(t)
+	defer os.RemoveAll(dir)
+
+	for content, expected := range map[string]string{
+		`{"unknown": true}`:                          "unknown setting unknown",
+		`{"config": "other.json"}`:                   "unknown setting config",
+		`{"targets": {"cpp": {"unknown": "value"}}}`: "invalid target cpp in config file",
+		`{"out": ["a", "b"]}`:                        "setting out: expecting a string, a boolean or a number",
+	} {
+		_, err := loadConfig(writeConfig(t, dir, content))
+		assert.Err(t, err)
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("unexpected error for %s: %s", content, err)
+		}
+	}
+}
+
+func TestConfigPaths(t *testing.T) {
+	defer testFlags()()
+	var dir = tempDir(t)
+	defer os.RemoveAll(dir)
+
+	cwd, err := os.Getwd()
+	assert.NoErr(t, err)
+	defer os.Chdir(cwd)
+	assert.NoErr(t, os.Chdir(dir))
+
+	// resolved relative to the directory of the config file, as seen from the current directory
+	var absolute = filepath.Join(dir, "model.json")
+	cfg, err := loadConfig(writeConfig(t, "project", `{
+	"path": "./...",
+	"out": "generated",
+	"model": "`+filepath.ToSlash(absolute)+`",
+	"changelog": "changes.md, changes.json",
+	"optional": "std::optional"
+}`))
+	assert.NoErr(t, err)
+	assert.Eq(t, map[string]string{
+		"path":      filepath.Join("project", "..."),
+		"out":       filepath.Join("project", "generated"),
+		"model":     filepath.ToSlash(absolute),
+		"changelog": filepath.Join("project", "changes.md") + "," + filepath.Join("project", "changes.json"),
+		"optional":  "std::optional",
+	}, cfg.settings)
+
+	// a config file in the current directory keeps the paths as they are
+	cfg, err = loadConfig(writeConfig(t, ".", `{"path": "./...", "out": "generated"}`))
+	assert.NoErr(t, err)
+	assert.Eq(t, map[string]string{"path": "./...", "out": "generated"}, cfg.settings)
+}
+
+func TestApplySettings(t *testing.T) {
+	defer testFlags()()
+
+	// command-line values take precedence over the settings, the "path" setting isn't a flag
+	var settings = map[string]string{"out": "generated", "cpp": "true", "path": "schemas"}
+	var cliValues = map[string]string{"out": "cli"}
+	assert.NoErr(t, applySettings(settings, cliValues))
+	assert.Eq(t, "cli", flag.Lookup("out").Value.String())
+	assert.Eq(t, "true", flag.Lookup("cpp").Value.String())
+
+	// the flags set by the previous target are reset to their defaults, the command-line values are kept
+	assert.NoErr(t, applySettings(map[string]string{"c": "true"}, cliValues))
+	assert.Eq(t, "cli", flag.Lookup("out").Value.String())
+	assert.Eq(t, "false", flag.Lookup("cpp").Value.String())
+	assert.Eq(t, "true", flag.Lookup("c").Value.String())
+
+	err := applySettings(map[string]string{"workers": "many"}, nil)
+	assert.Err(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), `invalid value "many" for setting workers`))
+}
@@ -0,0 +1,168 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generatorcmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around the changes
+const diffContext = 3
+
+// unifiedDiff returns the line differences between the given texts in the unified format, or "" if they're the same
+func unifiedDiff(oldName, newName string, old, new []byte) string {
+	var a = splitLines(old)
+	var b = splitLines(new)
+	var ops = diffLines(a, b)
+
+	var buf strings.Builder
+	for start := 0; start < len(ops); {
+		// find the next change and the extent of its hunk, joining the changes closer than twice the context
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		var end = start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+
+		var from = start - diffContext
+		if from < 0 {
+			from = 0
+		}
+		var to = end + diffContext
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		var oldCount, newCount int
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", ops[from].oldLine, oldCount, ops[from].newLine, newCount)
+		for _, op := range ops[from:to] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.text)
+			buf.WriteByte('\n')
+		}
+		start = to
+	}
+	return buf.String()
+}
+
+func splitLines(data []byte) []string {
+	var text = strings.TrimSuffix(string(data), "\n")
+	if len(text) == 0 {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffOp is a single line of the diff: kept (' '), removed ('-') or added ('+'); the line numbers are 1-based
+// positions in the old and the new text, where the line is (or would be)
+type diffOp struct {
+	kind    byte
+	text    string
+	oldLine int
+	newLine int
+}
+
+// diffLines computes the shortest edit script using the Myers algorithm
+func diffLines(a, b []string) []diffOp {
+	var n, m = len(a), len(b)
+	var max = n + m
+	var offset = max + 1
+	var v = make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			var y = x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// backtrack from the end, collecting the operations in reverse order
+	var ops []diffOp
+	var x, y = n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		var v = trace[d]
+		var k = x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		var prevX = v[offset+prevK]
+		var prevY = prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{kind: ' ', text: a[x-1], oldLine: x, newLine: y})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{kind: '+', text: b[y-1], oldLine: x + 1, newLine: y})
+			} else {
+				ops = append(ops, diffOp{kind: '-', text: a[x-1], oldLine: x, newLine: y + 1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -24,15 +24,29 @@
 package generatorcmd
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
 )
 
 const defaultErrorCode = 2
 
+const (
+	actionGenerate = "generate"
+	actionClean    = "clean"
+	actionCheck    = "check"
+)
+
 // / generatorCommand defines an interface for command-line applications to implement
 type generatorCommand interface {
 	ShowUsage()
@@ -40,24 +54,107 @@ type generatorCommand interface {
 	ParseFlags(remainingPosArgs *[]string, options *generator.Options) error
 }
 
+// targetOptions are the generator options for a single run, i.e. a single config file target
+type targetOptions struct {
+	name          string // empty if no config file targets are used
+	options       generator.Options
+	watch         bool
+	watchInterval time.Duration
+}
+
 func Main(impl generatorCommand) {
-	clean, options := getArgs(impl)
+	if len(os.Args) > 1 && os.Args[1] == actionModel {
+		stopOnError(0, runModelCommand(os.Args[2:]))
+		return
+	}
+
+	action, targets := getArgs(impl)
+
+	for _, target := range targets {
+		stopOnError(0, run(action, target))
+	}
+	stopOnError(0, diagnostics.Flush())
+}
+
+func run(action string, target targetOptions) error {
+	var options = target.options
+
+	var description = options.InPath
+	if len(target.name) > 0 {
+		description = fmt.Sprintf("%s (target %s)", options.InPath, target.name)
+	}
 
 	var err error
-	if clean {
-		fmt.Printf("Removing ObjectBox bindings for %s\n", options.InPath)
-		err = generator.Clean(options.CodeGenerator, options.InPath)
-	} else {
-		fmt.Printf("Generating ObjectBox bindings for %s\n", options.InPath)
-		err = generator.Process(options)
+	switch action {
+	case actionClean:
+		fmt.Printf("Removing ObjectBox bindings for %s\n", description)
+		for _, codeGenerator := range options.CodeGenerators {
+			if err = generator.Clean(codeGenerator, options.InPath); err != nil {
+				break
+			}
+		}
+	case actionCheck:
+		fmt.Printf("Checking ObjectBox bindings for %s\n", description)
+		options.Check = true
+		if err = generator.Process(options); err == nil {
+			fmt.Println("ObjectBox bindings are up-to-date")
+		}
+	default:
+		fmt.Printf("Generating ObjectBox bindings for %s\n", description)
+		if target.watch {
+			err = watch(options, target.watchInterval)
+		} else {
+			var stop = interruptible(&options)
+			err = generator.Process(options)
+			stop()
+		}
 	}
+	return err
+}
 
-	stopOnError(0, err)
+// interruptible makes an interrupt (e.g. Ctrl+C) cancel the generator run instead of killing the process, so that
+// the files are never left half-updated: the generator either stops before writing, or finishes writing all files.
+// The returned function restores the default signal handling.
+func interruptible(options *generator.Options) func() {
+	var ctx, cancel = context.WithCancel(context.Background())
+	options.Context = ctx
+
+	var signals = make(chan os.Signal, 1)
+	var done = make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			fmt.Println("Interrupted, stopping...")
+			cancel()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
+
+// watch keeps regenerating until interrupted (e.g. Ctrl+C)
+func watch(options generator.Options, interval time.Duration) error {
+	var stop = make(chan struct{})
+	var signals = make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	return generator.Watch(options, interval, stop)
 }
 
 func stopOnError(code int, err error) {
 	if err != nil {
-		fmt.Println(err)
+		diagnostics.PrintError(err)
+		diagnostics.Flush()
 		if code == 0 {
 			code = defaultErrorCode
 		}
@@ -74,16 +171,57 @@ func showUsageAndExit(impl generatorCommand, a ...interface{}) {
 	os.Exit(1)
 }
 
-func getArgs(impl generatorCommand) (clean bool, options generator.Options) {
+// patternList is a comma-separated list (e.g. of glob patterns or paths) given as a single flag value
+type patternList []string
+
+func (list *patternList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *patternList) Set(value string) error {
+	*list = nil
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); len(pattern) > 0 {
+			*list = append(*list, pattern)
+		}
+	}
+	return nil
+}
+
+func getArgs(impl generatorCommand) (action string, targets []targetOptions) {
 	var printVersion bool
 	var printHelp bool
+	var configFile string
+	var targetName string
+	var diagnosticsFormat string
+	var watch bool
+	var watchInterval time.Duration
+	var options generator.Options
 	flag.Usage = impl.ShowUsage
 	impl.ConfigureFlags()
 	flag.StringVar(&options.OutPath, "out", "", "output path for generated source files")
 	flag.StringVar(&options.OutHeadersPath, "out-headers", "", "optional: output path for generated header files") // opt-in: C and C++
+	flag.Var((*patternList)(&options.Include), "include", "comma-separated glob patterns of the sources to use when generating for a directory/pattern, e.g. \"schemas/*.fbs\"")
+	flag.Var((*patternList)(&options.Exclude), "exclude", "comma-separated glob patterns of the sources to skip, e.g. \"testdata,*_mock.go\"; see also the "+generator.IgnoreFileName+" file")
+	flag.BoolVar(&options.PreserveDirs, "preserve-dirs", false, "keep the directory structure of the sources, relative to the input path, under -out and -out-headers")
 	flag.StringVar(&options.ModelInfoFile, "model", "", "path to the model information persistence file (JSON)")
 	// TODO remove in v0.15.0 or later
 	flag.StringVar(&options.ModelInfoFile, "persist", "", "[DEPRECATED, use 'model'] path to the model information persistence file (JSON)")
+	flag.BoolVar(&options.ModelDiscovery, "model-discovery", false, "use a separate model for each directory with sources: the objectbox-model.json in the directory or its closest parent within the input path, or a new one in the directory; can't be combined with -model, nor with -out and -out-headers unless using -preserve-dirs")
+	flag.BoolVar(&options.AllowDestructive, "allow-destructive", false, "allow model changes removing stored data (entities, properties, indexes, relations) without acknowledging them with a \"removed\" annotation")
+	flag.BoolVar(&options.PinUids, "pin-uids", false, "write the UIDs of the model back into the sources, annotating each entity, property and standalone relation, so that renames are always explicit")
+	flag.StringVar(&options.DepFile, "depfile", "", "optional: path to write a Makefile-style dependency file to, listing the generated files and their inputs")
+	flag.StringVar(&options.ManifestFile, "manifest", "", "optional: path to write a JSON file to, listing all the generated files and their inputs")
+	flag.Var((*patternList)(&options.ChangelogFiles), "changelog", "optional: comma-separated paths to write the model changes of the run to, e.g. for a pull request; as JSON for files ending with .json, as Markdown otherwise")
+	flag.StringVar(&configFile, "config", "", "path to the config file; by default, "+configFileName+" is looked up in the input path directory and its parents")
+	flag.StringVar(&targetName, "target", "", "name of the config file target to run; by default, all targets are run")
+	flag.StringVar(&diagnosticsFormat, "diagnostics-format", string(diagnostics.FormatText), "format of the reported errors, warnings and notices; one of: text, json (JSON lines), sarif")
+	flag.DurationVar(&options.LockTimeout, "lock-timeout", model.DefaultLockTimeout, "how long to wait for another generator process using the same model JSON file")
+	flag.BoolVar(&options.Cache, "cache", false, "skip the sources unchanged since the previous run, using a .objectbox-cache file next to the model JSON")
+	flag.IntVar(&options.Workers, "workers", 0, "number of source files to parse and generate concurrently; defaults to the number of CPUs")
+	flag.BoolVar(&options.FailFast, "fail-fast", false, "stop at the first source file with errors instead of reporting the errors of all files")
+	flag.BoolVar(&watch, "watch", false, "keep running and regenerate the bindings whenever a source file changes; implies -cache")
+	flag.DurationVar(&watchInterval, "watch-interval", time.Second, "how often to check for source file changes in the -watch mode")
 	flag.BoolVar(&printVersion, "version", false, "print the generator version info")
 	flag.BoolVar(&printHelp, "help", false, "print this help")
 	flag.Parse()
@@ -98,29 +236,95 @@ func getArgs(impl generatorCommand) (clean bool, options generator.Options) {
 		os.Exit(0)
 	}
 
+	if format, err := diagnostics.ParseFormat(diagnosticsFormat); err != nil {
+		showUsageAndExit(impl, err)
+	} else {
+		diagnostics.SetFormat(format)
+		diagnostics.SetTool(diagnostics.Tool{Name: "objectbox-generator", Version: generator.Version, URI: "https://objectbox.io"})
+	}
+
 	// process positional args
 	var args = flag.Args()
 
-	if len(args) > 0 && args[0] == "clean" {
-		clean = true
+	action = actionGenerate
+	if len(args) > 0 && (args[0] == actionClean || args[0] == actionCheck) {
+		action = args[0]
 		args = args[1:]
 	}
 
+	var inPath string
 	if len(args) > 0 {
-		options.InPath = args[0]
+		inPath = args[0]
 		args = args[1:]
 	}
 
-	if err := impl.ParseFlags(&args, &options); err != nil {
-		showUsageAndExit(impl, err)
+	// load the config file, if any, and determine the settings for each target
+	var cfg *config
+	var err error
+	if len(configFile) > 0 {
+		cfg, err = loadConfig(configFile)
+	} else {
+		cfg, err = findConfig(inPath)
 	}
+	stopOnError(0, err)
 
-	if len(options.InPath) == 0 {
-		showUsageAndExit(impl, "path not specified")
+	var configTargets = []configTarget{{}}
+	if cfg != nil {
+		configTargets, err = cfg.getTargets(targetName)
+		stopOnError(0, err)
+	} else if len(targetName) > 0 {
+		showUsageAndExit(impl, "argument -target requires a config file")
 	}
 
-	if len(args) > 0 {
-		showUsageAndExit(impl, "unknown arguments", args)
+	// flags given on the command line take precedence over config file settings
+	var cliValues = make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		cliValues[f.Name] = f.Value.String()
+	})
+
+	for _, configTarget := range configTargets {
+		var target = targetOptions{name: configTarget.name}
+		var targetArgs = append([]string{}, args...)
+
+		var errorPrefix string
+		if len(target.name) > 0 {
+			errorPrefix = fmt.Sprintf("target %s: ", target.name)
+		}
+
+		if err := applySettings(configTarget.settings, cliValues); err != nil {
+			stopOnError(0, errors.New(errorPrefix+err.Error()))
+		}
+
+		target.options = options
+		target.watch = watch
+		target.watchInterval = watchInterval
+		target.options.InPath = inPath
+		if len(target.options.InPath) == 0 {
+			target.options.InPath = configTarget.settings[configPathSetting]
+		}
+
+		if err := impl.ParseFlags(&targetArgs, &target.options); err != nil {
+			showUsageAndExit(impl, errorPrefix+err.Error())
+		}
+
+		if len(target.options.InPath) == 0 {
+			showUsageAndExit(impl, errorPrefix+"path not specified")
+		}
+
+		if len(targetArgs) > 0 {
+			showUsageAndExit(impl, "unknown arguments", targetArgs)
+		}
+
+		if target.watch {
+			if action != actionGenerate {
+				showUsageAndExit(impl, "argument -watch can't be combined with "+action)
+			}
+			if len(configTargets) > 1 {
+				showUsageAndExit(impl, "argument -watch requires a single config file target, select one using -target")
+			}
+		}
+
+		targets = append(targets, target)
 	}
 
 	return
@@ -0,0 +1,184 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generatorcmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// actionModel is followed by one of the modelCommands, working with the model JSON file instead of generating code
+const actionModel = "model"
+
+// modelCommands are run as "{executable} model {command} [flags] [args]"
+var modelCommands = map[string]func(args []string) error{
+	"resolve": modelResolve,
+	"doctor":  modelDoctor,
+	"compat":  modelCompat,
+}
+
+func runModelCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("model command not specified, e.g. \"model resolve objectbox-model.json\"")
+	}
+
+	var command = modelCommands[args[0]]
+	if command == nil {
+		return fmt.Errorf("unknown model command %q", args[0])
+	}
+	return command(args[1:])
+}
+
+// modelFlags creates the flag set for a model command; its usage shows the given positional arguments
+func modelFlags(command string, usageArgs string) *flag.FlagSet {
+	var flags = flag.NewFlagSet(actionModel+" "+command, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s %s:\n", flags.Name(), usageArgs)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// modelResolve resolves git merge conflicts in the model JSON file
+func modelResolve(args []string) error {
+	var flags = modelFlags("resolve", "[flags] {objectbox-model.json}")
+	var lockTimeout = flags.Duration("lock-timeout", model.DefaultLockTimeout, "how long to wait for another generator process using the same model JSON file")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	} else if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expecting a single model JSON file path")
+	}
+
+	var path = flags.Arg(0)
+	modelInfo, err := model.ResolveConflictsFile(vfs.OS, path, *lockTimeout)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Resolved the conflicts in %s, the model now has %d entities\n", path, len(modelInfo.Entities))
+	return nil
+}
+
+// modelDoctor lists the inconsistencies of the model JSON file and repairs the ones that can be fixed safely
+func modelDoctor(args []string) error {
+	var flags = modelFlags("doctor", "[flags] {objectbox-model.json}")
+	var fix = flags.Bool("fix", false, "repair the problems which can be fixed safely, showing the changes made to the file")
+	var lockTimeout = flags.Duration("lock-timeout", model.DefaultLockTimeout, "how long to wait for another generator process using the same model JSON file")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	} else if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expecting a single model JSON file path")
+	}
+
+	var path = flags.Arg(0)
+	problems, before, after, err := model.DoctorFile(vfs.OS, path, *lockTimeout, *fix)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("No problems found in %s\n", path)
+		return nil
+	}
+
+	var fixable, unfixable int
+	fmt.Printf("Problems found in %s:\n", path)
+	for _, problem := range problems {
+		var status = "can't be fixed automatically"
+		if problem.Fixable {
+			fixable++
+			status = "can be fixed with -fix"
+			if *fix {
+				status = "fixed"
+			}
+		} else {
+			unfixable++
+		}
+		fmt.Printf("  - %s [%s]\n", problem.Message, status)
+	}
+
+	if fixable > 0 {
+		if *fix {
+			fmt.Printf("Changes written to %s:\n", path)
+		} else {
+			fmt.Println("Changes -fix would make:")
+		}
+		fmt.Print(unifiedDiff(path, path, before, after))
+	}
+
+	if unfixable > 0 {
+		return fmt.Errorf("%d problem(s) can't be fixed automatically, please edit %s manually", unfixable, path)
+	} else if !*fix {
+		return fmt.Errorf("%d problem(s) found, run with -fix to repair them", fixable)
+	}
+	return nil
+}
+
+// modelCompat checks that the model JSON file is a safe evolution of a base model JSON file, e.g. of the last release
+func modelCompat(args []string) error {
+	var flags = modelFlags("compat", "-base {objectbox-model.json} [flags] [objectbox-model.json]")
+	var basePath = flags.String("base", "", "the base model JSON file to check against, e.g. the one of the last release")
+	var allowDestructive = flags.Bool("allow-destructive", false, "allow removals of entities, properties, indexes and relations, which remove stored data")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	} else if len(*basePath) == 0 {
+		flags.Usage()
+		return errors.New("the base model JSON file must be specified using -base")
+	} else if flags.NArg() > 1 {
+		flags.Usage()
+		return errors.New("expecting at most a single model JSON file path")
+	}
+
+	var path = "objectbox-model.json"
+	if flags.NArg() == 1 {
+		path = flags.Arg(0)
+	}
+
+	problems, err := model.CheckCompatibilityFiles(vfs.OS, *basePath, path, *allowDestructive)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("%s is compatible with %s\n", path, *basePath)
+		return nil
+	}
+
+	fmt.Printf("%s is not compatible with %s:\n", path, *basePath)
+	for _, problem := range problems {
+		fmt.Printf("  - %s\n", problem)
+	}
+	return fmt.Errorf("%d compatibility problem(s) found", len(problems))
+}
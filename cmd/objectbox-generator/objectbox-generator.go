@@ -31,10 +31,9 @@ import (
 	"strings"
 
 	generatorcmd "github.com/objectbox/objectbox-generator/v4/cmd"
+	publicgenerator "github.com/objectbox/objectbox-generator/v4/generator"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator"
-	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/flatbuffersc"
-	gogenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/go"
 )
 
 func main() {
@@ -45,6 +44,9 @@ func main() {
 	generatorcmd.Main(&command{})
 }
 
+// languages lists the supported output languages, in the order their generators are executed
+var languages = []string{"c", "cpp", "cpp11", "go"}
+
 // implements generatorcmd.generatorCommand
 type command struct {
 	langs                map[string]*bool
@@ -70,16 +72,55 @@ or
       to generate the binding code for a single file
 
 
+Multiple output languages using the same source files can be combined in a single run, e.g. "-c -cpp".
+
 or
   objectbox-generator [flags] clean {path}
       to remove the generated files instead of creating them - this removes *.obx.* and objectbox-model.h but keeps objectbox-model.json
 
+or
+  objectbox-generator [flags] check {path}
+      to verify the generated files and the model JSON are up-to-date, without writing anything.
+      Exits with a non-zero code and lists the files that would change otherwise, e.g. to be used on CI.
+
+or
+  objectbox-generator model resolve [flags] {objectbox-model.json}
+      to resolve git merge conflicts in the model JSON file, reconciling both sides by UIDs.
+      Use the diff3 conflict style (git config merge.conflictStyle diff3) to also merge elements changed on one side.
+
+or
+  objectbox-generator model doctor [-fix] {objectbox-model.json}
+      to list the inconsistencies in the model JSON file, e.g. after editing it by hand, and with -fix, to repair the ones
+      that can be fixed safely (e.g. the last IDs), showing the changes made to the file.
+
+or
+  objectbox-generator model compat -base {released/objectbox-model.json} [-allow-destructive] [objectbox-model.json]
+      to verify that the current model is a safe evolution of the base model, e.g. the one of the last release:
+      no UID reused with a different meaning or after being retired, no ID reused, no incompatible property type
+      change and, unless -allow-destructive is given, nothing removed. Exits with a non-zero code and lists the problems.
+
 or
   objectbox-generator FLATC [flatc arguments]
       to execute FlatBuffers flatc command line tool Any arguments after the FLATC keyword are passed through.
 
 path:
   * a source file path or a valid path pattern (e.g. ./...)
+  * if not given, the "path" setting of the config file is used
+
+Config file:
+  Instead of repeating the flags on each invocation, they can be stored in an objectbox-generator.json file, which is
+  looked up in the input path directory (or the current directory) and its parents, or given explicitly by -config.
+  Settings are named like the flags; paths are relative to the config file directory. Optional "targets" define named
+  sets of settings which are run one after another (or only the one selected by -target), e.g.:
+    {
+      "path": "schema",
+      "optional": "std::optional",
+      "targets": {
+        "cpp": {"cpp": true, "out": "src/generated", "out-headers": "include/generated"},
+        "c": {"c": true, "optional": "", "out": "c/generated"}
+      }
+    }
+  Flags given on the command line take precedence over the config file settings.
   
 Available flags:
 `)
@@ -100,48 +141,26 @@ func (cmd *command) ConfigureFlags() {
 }
 
 func (cmd *command) ParseFlags(remainingPosArgs *[]string, options *generator.Options) error {
-	var selectedLang string
-	for lang, val := range cmd.langs {
-		if *val {
-			if len(selectedLang) != 0 {
-				return fmt.Errorf("only one output language can be specified at the moment, you've selected %s and %s", selectedLang, lang)
-			}
-			selectedLang = lang
+	var selected = publicgenerator.Options{
+		CppOptional:          *cmd.optional,
+		CppEmptyStringAsNull: *cmd.empty_string_as_null,
+		CppNaNAsNull:         *cmd.nan_as_null,
+	}
+	for _, lang := range languages {
+		if *cmd.langs[lang] {
+			selected.Languages = append(selected.Languages, publicgenerator.Language(lang))
 		}
 	}
 
-	if len(*cmd.optional) != 0 && selectedLang != "cpp" {
-		return errors.New("argument -optional is only allowed in combination with -cpp")
+	codeGenerators, err := publicgenerator.CodeGenerators(selected)
+	if err != nil {
+		return err
 	}
 
-	switch selectedLang {
-	case "go":
-		options.CodeGenerator = &gogenerator.GoGenerator{}
-	case "c":
-		options.CodeGenerator = &cgenerator.CGenerator{
-			PlainC:      true,
-			LangVersion: -1,    // unspecified, take the default
-			Optional:    "ptr", // dummy value for checks to evaluate to true if "optional" annotation is used
-		}
-	case "cpp":
-		options.CodeGenerator = &cgenerator.CGenerator{
-			PlainC:            false,
-			LangVersion:       14,
-			Optional:          *cmd.optional,
-			EmptyStringAsNull: *cmd.empty_string_as_null,
-			NaNAsNull:         *cmd.nan_as_null,
-		}
-	case "cpp11":
-		options.CodeGenerator = &cgenerator.CGenerator{
-			PlainC:            false,
-			LangVersion:       11,
-			Optional:          *cmd.optional,
-			EmptyStringAsNull: *cmd.empty_string_as_null,
-			NaNAsNull:         *cmd.nan_as_null,
-		}
-	default:
-		return errors.New("you must specify an output language")
+	if len(*cmd.optional) != 0 && !*cmd.langs["cpp"] {
+		return errors.New("argument -optional is only allowed in combination with -cpp")
 	}
+	options.CodeGenerators = append(options.CodeGenerators, codeGenerators...)
 	return nil
 }
 
@@ -19,7 +19,7 @@
  */
 
 // Package gogen provides cmd line Main() function for objectbox-go project, actual command (executable) is part of objectbox-go project.
-// We're keeping this code here instead of objectbox-go  to minimize the exposed API (avoid exposing Options and GoGenerator symbols).
+// Tools which need to run the generator programmatically should use the public package generator instead.
 package gogen
 
 import (
@@ -53,10 +53,33 @@ or
 	objectbox-gogen clean {path}
 		to remove the generated files instead of creating them - this removes *.obx.go and objectbox-model.go but keeps objectbox-model.json
 
+or
+
+	objectbox-gogen [flags] check {path}
+		to verify the generated files and objectbox-model.json are up-to-date, without writing anything
+
+or
+
+	objectbox-gogen model resolve [flags] {objectbox-model.json}
+		to resolve git merge conflicts in the model JSON file, reconciling both sides by UIDs
+
+or
+
+	objectbox-gogen model doctor [-fix] {objectbox-model.json}
+		to list the inconsistencies in the model JSON file and with -fix, to repair the ones that can be fixed safely
+
+or
+
+	objectbox-gogen model compat -base {released/objectbox-model.json} [-allow-destructive] [objectbox-model.json]
+		to verify that the current model is a safe evolution of the base model, e.g. the one of the last release
+
 path:
   * a source file path or a valid path pattern as accepted by the go tool (e.g. ./...)
   * if not given, the generator expects GOFILE environment variable to be set
 
+Flags may also be stored in an objectbox-generator.json file (looked up in the path directory and its parents,
+or given by -config), e.g. {"byValue": true}. Flags given on the command line take precedence.
+
 Available flags:
 `)
 	flag.PrintDefaults()
@@ -67,9 +90,9 @@ func (cmd *command) ConfigureFlags() {
 }
 
 func (cmd *command) ParseFlags(remainingPosArgs *[]string, options *generator.Options) error {
-	options.CodeGenerator = &gogenerator.GoGenerator{
+	options.CodeGenerators = []generator.CodeGenerator{&gogenerator.GoGenerator{
 		ByValue: cmd.byValue,
-	}
+	}}
 
 	if len(options.InPath) == 0 {
 		// if the command is run by go:generate some environment variables are set
@@ -0,0 +1,333 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package generator is the public API of the ObjectBox Generator, e.g. for build tools which need to generate the
+// bindings without running the objectbox-generator executable.
+//
+// Process() generates and writes the bindings and the model files, Generate() produces the same output in memory and
+// Check() compares it with the files on disk. Parse() and Merge() give access to the individual steps.
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	gogenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/go"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// Version specifies the current generator version.
+const Version = generator.Version
+
+// VersionId specifies the current generator version identifier, see the generated code.
+const VersionId = generator.VersionId
+
+type (
+	// ModelInfo is the model, as stored in the model JSON file
+	ModelInfo = model.ModelInfo
+
+	// ModelChange describes a change of the model made by a generator run
+	ModelChange = model.Change
+
+	// CodeGenerator generates the bindings for a single language
+	CodeGenerator = generator.CodeGenerator
+
+	// Result lists the files produced by a generator run and the model changes
+	Result = generator.Result
+
+	// OutputFile is a single file produced by the generator
+	OutputFile = generator.OutputFile
+
+	// CheckError is returned by Check() if some files are not up-to-date
+	CheckError = generator.CheckError
+
+	// FileDiff describes a single file which is not up-to-date, see CheckError
+	FileDiff = generator.FileDiff
+
+	// Diagnostic is an error with a code, a source position and the entity/property context
+	Diagnostic = diagnostics.Diagnostic
+
+	// DiagnosticList is returned if there are errors in multiple entities or source files
+	DiagnosticList = diagnostics.List
+
+	// FS is the file system interface used for all generator I/O, see Options.FS
+	FS = vfs.FS
+
+	// MemoryFS is an in-memory FS, see NewMemoryFS()
+	MemoryFS = vfs.Memory
+)
+
+// OSFS is the file system of the operating system, used by default
+var OSFS = vfs.OS
+
+// NewMemoryFS creates an empty in-memory file system, e.g. to run the generator without temporary directories
+func NewMemoryFS() *MemoryFS {
+	return vfs.NewMemory()
+}
+
+// Language of the generated code
+type Language string
+
+const (
+	LanguageC     Language = "c"
+	LanguageCpp   Language = "cpp"   // C++14 and newer
+	LanguageCpp11 Language = "cpp11" // C++11
+	LanguageGo    Language = "go"
+)
+
+// Options configure a generator run
+type Options struct {
+	// InPath is a source file, a directory, a glob pattern or a recursive pattern (e.g. "./...")
+	InPath string
+
+	// OutPath and OutHeadersPath (C and C++ only) override the directory of the generated files.
+	OutPath        string
+	OutHeadersPath string
+
+	// Include and Exclude are glob patterns selecting the sources when InPath is a directory or a pattern. A pattern
+	// without a slash matches the name of a file or any of its parent directories, otherwise the path relative to the
+	// InPath directory. Files listed in .objectboxignore files, and hidden and "vendor" directories are skipped too.
+	Include []string
+	Exclude []string
+
+	// PreserveDirs keeps the directory structure of the sources, relative to the InPath directory, under OutPath and
+	// OutHeadersPath. Otherwise, all the files are generated directly into them.
+	PreserveDirs bool
+
+	// ModelInfoFile defaults to objectbox-model.json in the InPath directory.
+	ModelInfoFile string
+
+	// ModelDiscovery uses a separate model for each directory with sources, e.g. for each Go package: the
+	// objectbox-model.json in the directory or its closest parent within the input path, or a new one in the directory.
+	// Can't be combined with ModelInfoFile, nor with OutPath and OutHeadersPath unless using PreserveDirs.
+	ModelDiscovery bool
+
+	// AllowDestructive allows model changes which remove stored data: removed entities, properties, indexes
+	// and standalone relations. Otherwise, each of them must be acknowledged by a "removed" annotation listing its UID.
+	AllowDestructive bool
+
+	// PinUids writes the UIDs of the model back into the sources, as annotations of each entity, property and
+	// standalone relation, see Result.Sources. The cache is not used in this mode.
+	// Go: fields of embedded structs are reported as warnings instead of pinned.
+	PinUids bool
+
+	// Languages to generate; "go" can't be combined with others because it uses different source files.
+	Languages []Language
+
+	// CppOptional is the C++ wrapper type for fields annotated "optional": std::optional, std::unique_ptr, std::shared_ptr
+	CppOptional string
+
+	// CppEmptyStringAsNull and CppNaNAsNull make the C++ code treat empty strings and NaNs as 0 (null).
+	CppEmptyStringAsNull bool
+	CppNaNAsNull         bool
+
+	// GoByValue makes the Go getters return a struct value (a copy) instead of a struct pointer.
+	GoByValue bool
+
+	// FailFast stops at the first source file with errors, instead of reporting the errors of all files.
+	FailFast bool
+
+	// Workers is the number of source files parsed and generated concurrently; the number of CPUs if not positive.
+	Workers int
+
+	// Cache skips the sources unchanged since the previous Process(), using a .objectbox-cache file next to the model.
+	Cache bool
+
+	// LockTimeout is how long Process() waits for another process using the same model JSON file (30 s if zero).
+	LockTimeout time.Duration
+
+	// Rand is used to generate UIDs; a time-seeded one is used if nil.
+	Rand *rand.Rand
+
+	// FS is used to read the sources and the model, and to write the output; OSFS if nil.
+	// Go packages imported by the Go sources are always loaded from the OS file system.
+	FS FS
+
+	// Diagnostics, if set, receives the warnings and notices of the run (e.g. a property reset by a new UID) and the
+	// progress information (severity "info", e.g. a file being removed) instead of the standard error and output.
+	// It may be called concurrently when running with Workers.
+	Diagnostics func(d *Diagnostic)
+}
+
+// CodeGenerators creates the code generators for the selected languages, in the order given
+func CodeGenerators(options Options) ([]CodeGenerator, error) {
+	if len(options.Languages) == 0 {
+		return nil, errors.New("you must specify an output language")
+	}
+
+	var selected = make(map[Language]bool)
+	for _, lang := range options.Languages {
+		selected[lang] = true
+	}
+
+	if len(options.Languages) > 1 {
+		if selected[LanguageGo] {
+			var names []string
+			for _, lang := range options.Languages {
+				names = append(names, string(lang))
+			}
+			return nil, fmt.Errorf("output language go can't be combined with %s because it uses different source files", strings.Join(names, ", "))
+		}
+		if selected[LanguageCpp] && selected[LanguageCpp11] {
+			return nil, errors.New("output languages cpp and cpp11 can't be combined because they produce the same files")
+		}
+	}
+
+	var result []CodeGenerator
+	for _, lang := range options.Languages {
+		switch lang {
+		case LanguageGo:
+			result = append(result, &gogenerator.GoGenerator{ByValue: options.GoByValue})
+		case LanguageC:
+			result = append(result, &cgenerator.CGenerator{
+				PlainC:      true,
+				LangVersion: -1,    // unspecified, take the default
+				Optional:    "ptr", // dummy value for checks to evaluate to true if "optional" annotation is used
+			})
+		case LanguageCpp, LanguageCpp11:
+			var langVersion = 14
+			if lang == LanguageCpp11 {
+				langVersion = 11
+			}
+			result = append(result, &cgenerator.CGenerator{
+				PlainC:            false,
+				LangVersion:       langVersion,
+				Optional:          options.CppOptional,
+				EmptyStringAsNull: options.CppEmptyStringAsNull,
+				NaNAsNull:         options.CppNaNAsNull,
+			})
+		default:
+			return nil, fmt.Errorf("unknown output language %s", lang)
+		}
+	}
+	return result, nil
+}
+
+// internalOptions converts the options to the ones of the internal generator package
+func (options Options) internalOptions(ctx context.Context) (generator.Options, error) {
+	codeGenerators, err := CodeGenerators(options)
+	if err != nil {
+		return generator.Options{}, err
+	}
+
+	if len(options.InPath) == 0 {
+		return generator.Options{}, errors.New("input path not specified")
+	}
+
+	return generator.Options{
+		ModelInfoFile:    options.ModelInfoFile,
+		ModelDiscovery:   options.ModelDiscovery,
+		Rand:             options.Rand,
+		InPath:           options.InPath,
+		OutPath:          options.OutPath,
+		OutHeadersPath:   options.OutHeadersPath,
+		Include:          options.Include,
+		Exclude:          options.Exclude,
+		PreserveDirs:     options.PreserveDirs,
+		AllowDestructive: options.AllowDestructive,
+		PinUids:          options.PinUids,
+		CodeGenerators:   codeGenerators,
+		FailFast:         options.FailFast,
+		Workers:          options.Workers,
+		Cache:            options.Cache,
+		LockTimeout:      options.LockTimeout,
+		Context:          ctx,
+		FS:               options.FS,
+		Diagnostics:      options.Diagnostics,
+	}, nil
+}
+
+// Process generates the bindings and updates the model files on disk.
+// Nothing is written if there's an error, e.g. in any of the source files.
+func Process(ctx context.Context, options Options) (*Result, error) {
+	internalOptions, err := options.internalOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return generator.Run(internalOptions)
+}
+
+// Generate produces the same output as Process() in memory, without writing or removing any files.
+// The result can be written later by Result.Write().
+func Generate(ctx context.Context, options Options) (*Result, error) {
+	internalOptions, err := options.internalOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	internalOptions.InMemory = true
+	return generator.Run(internalOptions)
+}
+
+// Check compares the output of Process() with the files on disk, without writing anything.
+// Returns a *CheckError if any of the files are not up-to-date.
+func Check(ctx context.Context, options Options) (*Result, error) {
+	internalOptions, err := options.internalOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	internalOptions.Check = true
+	return generator.Run(internalOptions)
+}
+
+// Parse reads a single source file using the code generator of the first language.
+// The returned model has no IDs and UIDs assigned yet, see Merge().
+func Parse(ctx context.Context, options Options, sourceFile string) (*ModelInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	internalOptions, err := options.internalOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return internalOptions.CodeGenerators[0].ParseSource(sourceFile, internalOptions)
+}
+
+// LoadModel reads the model JSON file from the given file system (OSFS if nil), or creates a new empty model if the
+// file doesn't exist. The file is not kept open, changes can be written using ModelInfo.Marshal().
+func LoadModel(fsys FS, path string) (*ModelInfo, error) {
+	if fsys == nil {
+		fsys = OSFS
+	}
+
+	modelInfo, err := model.ReadModelFS(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	if err = modelInfo.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid ModelInfo loaded: %s", err)
+	}
+	modelInfo.Rand = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	return modelInfo, nil
+}
+
+// Merge merges a model returned by Parse() into the stored model, assigning IDs and UIDs to the new elements.
+// Warnings, e.g. about a property reset by a new UID, are passed to options.Diagnostics, if set.
+func Merge(options Options, parsed *ModelInfo, stored *ModelInfo) error {
+	return generator.Merge(generator.Options{Diagnostics: options.Diagnostics}, parsed, stored)
+}
@@ -23,6 +23,8 @@ package binding
 import (
 	"fmt"
 	"strings"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
 )
 
 // Annotation is a tag on a struct-field
@@ -31,6 +33,8 @@ type Annotation struct {
 	// Details is used to map complex annotations, e.g. many-to-many with brackets syntax, sync annotation.
 	// e.g. relation(name=manyToManyRelName,to=TargetEntity)
 	Details map[string]*Annotation
+	// Pos is the position of the annotation name in the source file, if known
+	Pos diagnostics.Position
 }
 
 // HasDetail checks if the annotation has a "Detail" with the given name.
@@ -44,7 +48,7 @@ func HasBooleanDetail(annotations map[string]*Annotation, parentAnnotation, name
 		a := annotations[parentAnnotation]
 		if a.HasDetail(name) {
 			if len(a.Details[name].Value) != 0 {
-				return true, fmt.Errorf("'%s' annotation's '%s' attribute value must be empty", parentAnnotation, name)
+				return true, annotationErrorf(a.Details[name], "'%s' annotation's '%s' attribute value must be empty", parentAnnotation, name)
 			}
 			return true, nil
 		}
@@ -52,6 +56,15 @@ func HasBooleanDetail(annotations map[string]*Annotation, parentAnnotation, name
 	return false, nil
 }
 
+// annotationErrorf creates an error at the position of the given annotation (may be nil if it's missing)
+func annotationErrorf(a *Annotation, format string, args ...interface{}) error {
+	var pos diagnostics.Position
+	if a != nil {
+		pos = a.Pos
+	}
+	return diagnostics.Errorf(diagnostics.CodeAnnotation, pos, format, args...)
+}
+
 // ParseAnnotations parses annotations in any of the following formats.
 // name="name",index - creates two annotations, name and index, the former having a non-empty value
 // relation(name=manyToManyRelName,to=TargetEntity) - creates a single annotation relation with two items as details
@@ -59,14 +72,21 @@ func HasBooleanDetail(annotations map[string]*Annotation, parentAnnotation, name
 // NOTE: this started as a very simple parser but it seems like the requirements are ever-increasing... maybe some form
 //
 //	of recursive tokenization would be better in case we decided to rework.
-func ParseAnnotations(str string, annotations *map[string]*Annotation, supportedAnnotations map[string]bool) error {
+//
+// The pos argument is the position of the beginning of str in the source file (zero if unknown), used to set
+// Annotation.Pos and to position the errors.
+func ParseAnnotations(str string, pos diagnostics.Position, annotations *map[string]*Annotation, supportedAnnotations map[string]bool) error {
+	var errorf = func(i int, format string, args ...interface{}) error {
+		return diagnostics.Errorf(diagnostics.CodeAnnotation, pos.Offset(i), format, args...)
+	}
+
 	var s annotationInProgress
 	for i := 0; i < len(str); i++ {
 		var char = str[i]
 
 		if !s.valueQuoted && (char == '=' || char == ':' || char == '(') { // start a value
 			if len(s.name) == 0 {
-				return fmt.Errorf("invalid annotation format: name expected before '%s' at position %d in `%s` ", string(char), i, str)
+				return errorf(i, "invalid annotation format: name expected before '%s' at position %d in `%s` ", string(char), i, str)
 			}
 			s.value = &Annotation{}
 
@@ -82,7 +102,7 @@ func ParseAnnotations(str string, annotations *map[string]*Annotation, supported
 					}
 				}
 				if len(detailsStr) == 0 {
-					return fmt.Errorf("invalid annotation details format, closing bracket ')' not found in `%s`", str[i+1:])
+					return errorf(i, "invalid annotation details format, closing bracket ')' not found in `%s`", str[i+1:])
 				}
 				s.name = strings.ToLower(strings.TrimSpace(s.name))
 				s.value.Details = make(map[string]*Annotation)
@@ -94,18 +114,18 @@ func ParseAnnotations(str string, annotations *map[string]*Annotation, supported
 				} else if s.name == "id" {
 					supportedDetails = map[string]bool{"assignable": true}
 				} else {
-					return fmt.Errorf("invalid annotation format: details only supported for `relation` & `sync` annotations, found `%s`", s.name)
+					return errorf(s.start, "invalid annotation format: details only supported for `relation` & `sync` annotations, found `%s`", s.name)
 				}
-				if err := ParseAnnotations(detailsStr, &s.value.Details, supportedDetails); err != nil {
+				if err := ParseAnnotations(detailsStr, pos.Offset(i-len(detailsStr)), &s.value.Details, supportedDetails); err != nil {
 					return err
 				}
 				if s.name == "relation" {
 					if s.value.Details["name"] == nil {
-						return fmt.Errorf("invalid annotation format: relation name missing in `%s`", str)
+						return errorf(s.start, "invalid annotation format: relation name missing in `%s`", str)
 					}
 					s.key = fmt.Sprintf("relation-%10d-%s", relationsCount(*annotations), s.value.Details["name"].Value)
 				}
-				if err := s.finishAnnotation(annotations, supportedAnnotations); err != nil {
+				if err := s.finishAnnotation(pos, annotations, supportedAnnotations); err != nil {
 					return err
 				}
 				s = annotationInProgress{} // reset
@@ -126,7 +146,7 @@ func ParseAnnotations(str string, annotations *map[string]*Annotation, supported
 					continue
 				}
 			}
-			if err := s.finishAnnotation(annotations, supportedAnnotations); err != nil {
+			if err := s.finishAnnotation(pos, annotations, supportedAnnotations); err != nil {
 				return err
 			}
 			s = annotationInProgress{} // reset
@@ -139,19 +159,23 @@ func ParseAnnotations(str string, annotations *map[string]*Annotation, supported
 					s.valueFinished = true
 				}
 			} else if s.valueFinished {
-				return fmt.Errorf("invalid annotation format: no more characters may follow after a quoted value at position %d in `%s`", i, str)
+				return errorf(i, "invalid annotation format: no more characters may follow after a quoted value at position %d in `%s`", i, str)
 			} else {
 				s.value.Value += string(char)
 			}
 		} else { // continue a name
+			if len(strings.TrimSpace(s.name)) == 0 {
+				s.start = i
+			}
 			s.name += string(char)
 		}
 	}
 
-	return s.finishAnnotation(annotations, supportedAnnotations)
+	return s.finishAnnotation(pos, annotations, supportedAnnotations)
 }
 
 type annotationInProgress struct {
+	start         int // position of the name in the parsed string
 	name          string
 	key           string
 	value         *Annotation
@@ -173,7 +197,7 @@ func skipSpacesUntil(str string, i int, fn func(uint8) bool) int {
 	return i
 }
 
-func (s *annotationInProgress) finishAnnotation(annotations *map[string]*Annotation, supportedAnnotations map[string]bool) error {
+func (s *annotationInProgress) finishAnnotation(pos diagnostics.Position, annotations *map[string]*Annotation, supportedAnnotations map[string]bool) error {
 	s.name = strings.ToLower(strings.TrimSpace(s.name))
 	if len(s.name) == 0 {
 		return nil
@@ -183,19 +207,20 @@ func (s *annotationInProgress) finishAnnotation(annotations *map[string]*Annotat
 	} else {
 		s.value.Value = strings.TrimSpace(s.value.Value)
 	}
+	s.value.Pos = pos.Offset(s.start)
 	var key = s.key
 	if len(key) == 0 {
 		key = s.name
 	}
 	if (*annotations)[key] != nil {
-		return fmt.Errorf("duplicate annotation %s", key)
+		return annotationErrorf(s.value, "duplicate annotation %s", key)
 	} else if !supportedAnnotations[s.name] {
-		return fmt.Errorf("unknown annotation '%s'", s.name)
+		return annotationErrorf(s.value, "unknown annotation '%s'", s.name)
 	} else {
 		if strings.HasPrefix(key, "hnsw-") {
 			var indexAnnotation = (*annotations)["index"]
 			if indexAnnotation == nil || indexAnnotation.Value != "hnsw" {
-				return fmt.Errorf("The HNSW annotation '%s' is only allowed after an 'index' annotation set to 'hnsw'.", key)
+				return annotationErrorf(s.value, "The HNSW annotation '%s' is only allowed after an 'index' annotation set to 'hnsw'.", key)
 			}
 		}
 		(*annotations)[key] = s.value
@@ -21,8 +21,6 @@
 package binding
 
 import (
-	"errors"
-	"fmt"
 	"sort"
 	"strconv"
 	"strings"
@@ -54,7 +52,7 @@ func (field *Field) PreProcessAnnotations(a map[string]*Annotation) error {
 	for _, alternative := range []string{"-", "transient"} {
 		if a[alternative] != nil {
 			if len(a) != 1 || a[alternative].Value != "" {
-				return errors.New("to ignore the property, use only `objectbox:\"" + alternative + "\"` as an annotation")
+				return annotationErrorf(a[alternative], "to ignore the property, use only `objectbox:\"%s\"` as an annotation", alternative)
 			}
 			field.IsSkipped = true
 			return nil
@@ -84,18 +82,23 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 
 	if a["name"] != nil {
 		if len(a["name"].Value) == 0 {
-			return fmt.Errorf("name annotation value must not be empty - it's the field name in DB")
+			return annotationErrorf(a["name"], "name annotation value must not be empty - it's the field name in DB")
 		}
 		field.ModelProperty.Name = a["name"].Value
 	}
 
 	if a["date"] != nil || a["date-nano"] != nil {
+		var dateAnnotation = a["date"]
+		if dateAnnotation == nil {
+			dateAnnotation = a["date-nano"]
+		}
+
 		if a["date"] != nil && a["date-nano"] != nil {
-			return errors.New("date and date-nano annotations cannot be used at the same time")
+			return annotationErrorf(a["date-nano"], "date and date-nano annotations cannot be used at the same time")
 		}
 
 		if field.ModelProperty.Type != model.PropertyTypeLong {
-			return fmt.Errorf("invalid underlying type '%v' for date/date-nano field; expecting long", model.PropertyTypeNames[field.ModelProperty.Type])
+			return annotationErrorf(dateAnnotation, "invalid underlying type '%v' for date/date-nano field; expecting long", model.PropertyTypeNames[field.ModelProperty.Type])
 		}
 
 		if a["date"] != nil {
@@ -107,7 +110,7 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 
 	if a["id-companion"] != nil {
 		if field.ModelProperty.Type != model.PropertyTypeDate && field.ModelProperty.Type != model.PropertyTypeDateNano {
-			return fmt.Errorf("invalid underlying type '%v' for ID companion field; expecting date/date-nano", model.PropertyTypeNames[field.ModelProperty.Type])
+			return annotationErrorf(a["id-companion"], "invalid underlying type '%v' for ID companion field; expecting date/date-nano", model.PropertyTypeNames[field.ModelProperty.Type])
 		}
 		field.ModelProperty.AddFlag(model.PropertyFlagIdCompanion)
 	}
@@ -117,7 +120,7 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 
 		// add a default index type, unless specified otherwise
 		if a["index"] == nil {
-			a["index"] = &Annotation{}
+			a["index"] = &Annotation{Pos: a["unique"].Pos}
 		}
 	}
 
@@ -141,14 +144,14 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 				field.ModelProperty.CreateHnswParams()
 				field.ModelProperty.AddFlag(model.PropertyFlagIndexed)
 			} else {
-				return fmt.Errorf("index type 'hnsw' only supported for float vectors")
+				return annotationErrorf(a["index"], "index type 'hnsw' only supported for float vectors")
 			}
 		default:
-			return fmt.Errorf("unknown index type %s", a["index"].Value)
+			return annotationErrorf(a["index"], "unknown index type %s", a["index"].Value)
 		}
 
 		if err := field.ModelProperty.SetIndex(); err != nil {
-			return err
+			return annotationErrorf(a["index"], "%s", err)
 		}
 	}
 
@@ -158,9 +161,9 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 			// this flag is handled by the merge mechanism and prints the UID of the already existing property
 			field.ModelProperty.UidRequest = true
 		} else if uid, err := strconv.ParseUint(a["uid"].Value, 10, 64); err != nil {
-			return fmt.Errorf("can't parse uid - %s", err)
+			return annotationErrorf(a["uid"], "can't parse uid - %s", err)
 		} else if id, err := field.ModelProperty.Id.GetIdAllowZero(); err != nil {
-			return fmt.Errorf("can't parse property Id - %s", err)
+			return annotationErrorf(a["uid"], "can't parse property Id - %s", err)
 		} else {
 			field.ModelProperty.Id = model.CreateIdUid(id, uid)
 		}
@@ -172,10 +175,10 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 	}
 	if toOneRelation != nil && field.ModelProperty.Type != model.PropertyTypeRelation {
 		if field.ModelProperty.Type != model.PropertyTypeLong {
-			return fmt.Errorf("invalid underlying type (PropertyType %v) for relation field; expecting long", model.PropertyTypeNames[field.ModelProperty.Type])
+			return annotationErrorf(toOneRelation, "invalid underlying type (PropertyType %v) for relation field; expecting long", model.PropertyTypeNames[field.ModelProperty.Type])
 		}
 		if len(toOneRelation.Value) == 0 {
-			return errors.New("unknown link target entity, define by changing the `link` annotation to the `link=Entity` format")
+			return annotationErrorf(toOneRelation, "unknown link target entity, define by changing the `link` annotation to the `link=Entity` format")
 		}
 		field.ModelProperty.Type = model.PropertyTypeRelation
 		field.ModelProperty.RelationTarget = toOneRelation.Value
@@ -183,7 +186,7 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 		field.ModelProperty.AddFlag(model.PropertyFlagIndexPartialSkipZero)
 
 		if err := field.ModelProperty.SetIndex(); err != nil {
-			return err
+			return annotationErrorf(toOneRelation, "%s", err)
 		}
 	}
 
@@ -193,76 +196,76 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 
 	if a["hnsw-dimensions"] != nil {
 		if err := field.ModelProperty.CheckHnswParams(); err != nil {
-			return err
+			return annotationErrorf(a["hnsw-dimensions"], "%s", err)
 		}
 		dimensions, err := strconv.ParseUint(a["hnsw-dimensions"].Value, 10, 64)
 		if err != nil {
-			return fmt.Errorf("Annotation 'hnsw-dimensions' value type mismatch: %s", err)
+			return annotationErrorf(a["hnsw-dimensions"], "Annotation 'hnsw-dimensions' value type mismatch: %s", err)
 		}
 		field.ModelProperty.HnswParams.Dimensions = &dimensions
 	}
 
 	if a["hnsw-distance-type"] != nil {
 		if err := field.ModelProperty.CheckHnswParams(); err != nil {
-			return err
+			return annotationErrorf(a["hnsw-distance-type"], "%s", err)
 		}
 		distanceType := a["hnsw-distance-type"].Value
 		switch distanceType {
 		case "Unknown", "Euclidean", "Cosine", "DotProduct", "DotProductNonNormalized":
 			break
 		default:
-			return fmt.Errorf("Annotation 'hnsw-distance-type' value type mismatch: must be one of 'Unknown', 'Euclidean', 'Cosine', 'DotProduct', 'DotPropductNonNormalized'")
+			return annotationErrorf(a["hnsw-distance-type"], "Annotation 'hnsw-distance-type' value type mismatch: must be one of 'Unknown', 'Euclidean', 'Cosine', 'DotProduct', 'DotPropductNonNormalized'")
 		}
 		field.ModelProperty.HnswParams.DistanceType = distanceType
 	}
 
 	if a["hnsw-neighbors-per-node"] != nil {
 		if err := field.ModelProperty.CheckHnswParams(); err != nil {
-			return err
+			return annotationErrorf(a["hnsw-neighbors-per-node"], "%s", err)
 		}
 		value, err := strconv.ParseUint(a["hnsw-neighbors-per-node"].Value, 10, 32)
 		if err != nil {
-			return fmt.Errorf("Annotation 'hnsw-neighbors-per-node' value type mismatch: %s", err)
+			return annotationErrorf(a["hnsw-neighbors-per-node"], "Annotation 'hnsw-neighbors-per-node' value type mismatch: %s", err)
 		}
 		var neighborsPerNode uint32 = uint32(value)
 		field.ModelProperty.HnswParams.NeighborsPerNode = &neighborsPerNode
 	}
 	if a["hnsw-indexing-search-count"] != nil {
 		if err := field.ModelProperty.CheckHnswParams(); err != nil {
-			return err
+			return annotationErrorf(a["hnsw-indexing-search-count"], "%s", err)
 		}
 		value, err := strconv.ParseUint(a["hnsw-indexing-search-count"].Value, 10, 32)
 		if err != nil {
-			return fmt.Errorf("Annotation 'hnsw-neighbors-per-node' value type mismatch: %s", err)
+			return annotationErrorf(a["hnsw-indexing-search-count"], "Annotation 'hnsw-neighbors-per-node' value type mismatch: %s", err)
 		}
 		var indexingSearchCount uint32 = uint32(value)
 		field.ModelProperty.HnswParams.IndexingSearchCount = &indexingSearchCount
 	}
 	if a["hnsw-reparation-backlink-probability"] != nil {
 		if err := field.ModelProperty.CheckHnswParams(); err != nil {
-			return err
+			return annotationErrorf(a["hnsw-reparation-backlink-probability"], "%s", err)
 		}
 		value, err := strconv.ParseFloat(a["hnsw-reparation-backlink-probability"].Value, 32)
 		if err != nil {
-			return fmt.Errorf("Annotation 'hnsw-reparation-backlink-probability' value type mismatch: %s", err)
+			return annotationErrorf(a["hnsw-reparation-backlink-probability"], "Annotation 'hnsw-reparation-backlink-probability' value type mismatch: %s", err)
 		}
 		var reparationBacklinkProbability float32 = float32(value)
 		field.ModelProperty.HnswParams.ReparationBacklinkProbability = &reparationBacklinkProbability
 	}
 	if a["hnsw-vector-cache-hint-size-kb"] != nil {
 		if err := field.ModelProperty.CheckHnswParams(); err != nil {
-			return err
+			return annotationErrorf(a["hnsw-vector-cache-hint-size-kb"], "%s", err)
 		}
 		vectorCacheHintSizeKb, err := strconv.ParseUint(a["hnsw-vector-cache-hint-size-kb"].Value, 10, 64)
 		if err != nil {
-			return fmt.Errorf("Annotation 'hnsw-dimensions' value type mismatch: %s", err)
+			return annotationErrorf(a["hnsw-vector-cache-hint-size-kb"], "Annotation 'hnsw-dimensions' value type mismatch: %s", err)
 		}
 		field.ModelProperty.HnswParams.VectorCacheHintSizeKb = &vectorCacheHintSizeKb
 
 	}
 	if a["hnsw-flags"] != nil {
 		if err := field.ModelProperty.CheckHnswParams(); err != nil {
-			return err
+			return annotationErrorf(a["hnsw-flags"], "%s", err)
 		}
 		flagsString := a["hnsw-flags"].Value
 		flagsVector := strings.Split(flagsString, "|")
@@ -279,7 +282,7 @@ func (field *Field) ProcessAnnotations(a map[string]*Annotation) error {
 				}
 				sort.Strings(keys)
 				availableFlags := strings.Join(keys, ", ")
-				return fmt.Errorf("HNSW Flag unknown: '%s' (Available flags: %s)", flagName, availableFlags)
+				return annotationErrorf(a["hnsw-flags"], "HNSW Flag unknown: '%s' (Available flags: %s)", flagName, availableFlags)
 			}
 		}
 		field.ModelProperty.HnswParams.Flags = &flags
@@ -22,11 +22,11 @@ package binding
 
 import (
 	"errors"
-	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
 )
 
@@ -62,7 +62,7 @@ func (object *Object) ProcessAnnotations(a map[string]*Annotation) error {
 	for _, alternative := range []string{"-", "transient"} {
 		if a[alternative] != nil {
 			if len(a) != 1 || a[alternative].Value != "" {
-				return errors.New("to ignore the entity, use only `objectbox:\"" + alternative + "\"` as an annotation")
+				return annotationErrorf(a[alternative], "to ignore the entity, use only `objectbox:\"%s\"` as an annotation", alternative)
 			}
 			object.IsSkipped = true
 			return nil
@@ -71,14 +71,14 @@ func (object *Object) ProcessAnnotations(a map[string]*Annotation) error {
 
 	if a["name"] != nil {
 		if len(a["name"].Value) == 0 {
-			return fmt.Errorf("name annotation value must not be empty - it's the entity name in DB")
+			return annotationErrorf(a["name"], "name annotation value must not be empty - it's the entity name in DB")
 		}
 		object.ModelEntity.Name = a["name"].Value
 	}
 
 	if a["sync"] != nil {
 		if len(a["sync"].Value) != 0 {
-			return fmt.Errorf("sync annotation value must be empty")
+			return annotationErrorf(a["sync"], "sync annotation value must be empty")
 		}
 		object.ModelEntity.AddFlag(model.EntityFlagSyncEnabled)
 		if hasDetail, err := HasBooleanDetail(a, "sync", "sharedglobalids"); err != nil {
@@ -88,15 +88,31 @@ func (object *Object) ProcessAnnotations(a map[string]*Annotation) error {
 		}
 	}
 
+	if a["removed"] != nil {
+		var uids = strings.FieldsFunc(a["removed"].Value, func(r rune) bool {
+			return r == ';' || r == ',' || r == ' '
+		})
+		if len(uids) == 0 {
+			return annotationErrorf(a["removed"], "removed annotation value must not be empty - it's the list of UIDs of the removed entities, properties, indexes or relations")
+		}
+		for _, value := range uids {
+			if uid, err := strconv.ParseUint(value, 10, 64); err != nil {
+				return annotationErrorf(a["removed"], "can't parse removed uid - %s", err)
+			} else {
+				object.ModelEntity.RemovedUids = append(object.ModelEntity.RemovedUids, uid)
+			}
+		}
+	}
+
 	if a["uid"] != nil {
 		if len(a["uid"].Value) == 0 {
 			// in case the user doesn't provide `objectbox:"uid"` value, it's considered in-process of setting up UID
 			// this flag is handled by the merge mechanism and prints the UID of the already existing entity
 			object.ModelEntity.UidRequest = true
 		} else if uid, err := strconv.ParseUint(a["uid"].Value, 10, 64); err != nil {
-			return fmt.Errorf("can't parse uid - %s", err)
+			return annotationErrorf(a["uid"], "can't parse uid - %s", err)
 		} else if id, err := object.ModelEntity.Id.GetIdAllowZero(); err != nil {
-			return fmt.Errorf("can't parse entity Id - %s", err)
+			return annotationErrorf(a["uid"], "can't parse entity Id - %s", err)
 		} else {
 			object.ModelEntity.Id = model.CreateIdUid(id, uid)
 		}
@@ -113,7 +129,7 @@ func (object *Object) ProcessAnnotations(a map[string]*Annotation) error {
 	sort.Strings(relationKeys)
 	for _, key := range relationKeys {
 		if _, err := object.AddRelation(a[key].Details); err != nil {
-			return err
+			return diagnostics.WithPosition(err, a[key].Pos)
 		}
 	}
 
@@ -123,12 +139,12 @@ func (object *Object) ProcessAnnotations(a map[string]*Annotation) error {
 func (object *Object) AddRelation(details map[string]*Annotation) (*model.StandaloneRelation, error) {
 	var relation = model.CreateStandaloneRelation(object.ModelEntity, model.CreateIdUid(0, 0))
 	if details["name"] == nil || len(details["name"].Value) == 0 {
-		return nil, fmt.Errorf("name annotation value must not be empty on relation - it's the relation name in DB")
+		return nil, errors.New("name annotation value must not be empty on relation - it's the relation name in DB")
 	}
 	relation.Name = details["name"].Value
 
 	if details["to"] == nil || len(details["to"].Value) == 0 {
-		return nil, fmt.Errorf("to annotation value must not be empty on relation %s - specify target entity", relation.Name)
+		return nil, annotationErrorf(details["to"], "to annotation value must not be empty on relation %s - specify target entity", relation.Name)
 	}
 
 	// NOTE: we don't need an actual entity pointer, it's resolved during stored model merging.
@@ -140,7 +156,7 @@ func (object *Object) AddRelation(details map[string]*Annotation) (*model.Standa
 			// this flag is handled by the merge mechanism and prints the UID of the already existing entity
 			relation.UidRequest = true
 		} else if uid, err := strconv.ParseUint(details["uid"].Value, 10, 64); err != nil {
-			return nil, fmt.Errorf("can't parse uid on relation %s - %s", relation.Name, err)
+			return nil, annotationErrorf(details["uid"], "can't parse uid on relation %s - %s", relation.Name, err)
 		} else {
 			relation.Id = model.CreateIdUid(0, uid)
 		}
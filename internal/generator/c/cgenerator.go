@@ -30,6 +30,7 @@ import (
 
 	"github.com/objectbox/objectbox-generator/v4/internal/generator"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/c/templates"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/flatbuffersc"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
 )
@@ -45,8 +46,9 @@ type CGenerator struct {
 // BindingFiles returns the names of the generated C or C++ language binding files for the given entity file.
 func (gen *CGenerator) BindingFiles(forFile string, options generator.Options) []string {
 
+	var sourceFile = forFile
 	if len(options.OutPath) > 0 {
-		forFile = filepath.Join(options.OutPath, filepath.Base(forFile))
+		forFile = options.OutputFile(options.OutPath, sourceFile)
 	}
 	var extension = filepath.Ext(forFile)
 	var base = forFile[0 : len(forFile)-len(extension)]
@@ -56,7 +58,7 @@ func (gen *CGenerator) BindingFiles(forFile string, options generator.Options) [
 	}
 	var headerBase = base
 	if len(options.OutHeadersPath) > 0 {
-		headerBase = filepath.Join(options.OutHeadersPath, filepath.Base(forFile))
+		headerBase = options.OutputFile(options.OutHeadersPath, sourceFile)
 		headerBase = headerBase[0 : len(headerBase)-len(extension)]
 	}
 
@@ -67,9 +69,9 @@ func (gen *CGenerator) BindingFiles(forFile string, options generator.Options) [
 func (gen *CGenerator) ModelFile(forFile string, options generator.Options) string {
 
 	if len(options.OutHeadersPath) > 0 {
-		forFile = filepath.Join(options.OutHeadersPath, filepath.Base(forFile))
+		forFile = options.OutputFile(options.OutHeadersPath, forFile)
 	} else if len(options.OutPath) > 0 {
-		forFile = filepath.Join(options.OutPath, filepath.Base(forFile))
+		forFile = options.OutputFile(options.OutPath, forFile)
 	}
 	var extension = filepath.Ext(forFile)
 	return forFile[0:len(forFile)-len(extension)] + ".h"
@@ -87,14 +89,20 @@ func (CGenerator) IsSourceFile(file string) bool {
 	return strings.HasSuffix(file, ".fbs")
 }
 
-func (gen *CGenerator) ParseSource(sourceFile string) (*model.ModelInfo, error) {
-	schemaReflection, err := flatbuffersc.ParseSchemaFile(sourceFile)
+func (gen *CGenerator) ParseSource(sourceFile string, options generator.Options) (*model.ModelInfo, error) {
+	// the schema and its includes are read using the configured file system and passed to the parser in memory
+	var source = loadSchemaSource(options.FileSystem(), sourceFile)
+	schemaReflection, err := flatbuffersc.ParseSchema(sourceFile, source.contents())
 	if err != nil {
 		return nil, err // already includes file name so no more context should be necessary
 	}
 
-	reader := fbSchemaReader{model: &model.ModelInfo{}, optional: gen.Optional}
+	reader := fbSchemaReader{model: &model.ModelInfo{}, optional: gen.Optional, source: source}
 	if err = reader.read(schemaReflection); err != nil {
+		// positioned errors already name the source file
+		if diagnostics.HasPosition(err) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("error generating model from schema %s: %s", sourceFile, err)
 	}
 
@@ -106,9 +114,11 @@ func (gen *CGenerator) WriteBindingFiles(sourceFile string, options generator.Op
 
 	var bindingFiles = gen.BindingFiles(sourceFile, options)
 
+	gen.applyOptional(mergedModel)
+
 	for _, bindingFile := range bindingFiles {
 		var bindingSource []byte
-		if bindingSource, err = gen.generateBindingFile(bindingFile, bindingFiles[0], mergedModel); err != nil {
+		if bindingSource, err = gen.generateBindingFile(bindingFile, bindingFiles[0], headerInclude(bindingFiles[0], options), mergedModel); err != nil {
 			return fmt.Errorf("can't generate binding file %s: %s", sourceFile, err)
 		}
 
@@ -119,7 +129,7 @@ func (gen *CGenerator) WriteBindingFiles(sourceFile string, options generator.Op
 			bindingSource = formattedSource
 		}
 
-		if err = generator.WriteFile(bindingFile, bindingSource, sourceFile); err != nil {
+		if err = options.WriteFile(bindingFile, bindingSource, sourceFile); err != nil {
 			return fmt.Errorf("can't write binding file %s: %s", sourceFile, err)
 		} else if err2 != nil {
 			// now when the binding has been written (for debugging purposes), we can return the error
@@ -130,7 +140,30 @@ func (gen *CGenerator) WriteBindingFiles(sourceFile string, options generator.Op
 	return nil
 }
 
-func (gen *CGenerator) generateBindingFile(bindingFile, headerFile string, m *model.ModelInfo) (data []byte, err error) {
+// applyOptional sets this generator's optional wrapper type on all fields annotated as "optional".
+// The model may have been parsed by another generator, e.g. when generating C and C++ bindings in a single run.
+func (gen *CGenerator) applyOptional(m *model.ModelInfo) {
+	for _, entity := range m.EntitiesWithMeta() {
+		for _, property := range entity.Properties {
+			if field, isFbsField := property.Meta.(*fbsField); isFbsField && field.isOptional {
+				field.Optional = gen.Optional
+			}
+		}
+	}
+}
+
+// headerInclude returns the path of the given header file as included by the other binding files: relative to the
+// headers output path, if set, or just the file name because the files are generated in the same directory otherwise
+func headerInclude(headerFile string, options generator.Options) string {
+	if len(options.OutHeadersPath) > 0 {
+		if rel, err := filepath.Rel(options.OutHeadersPath, headerFile); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(headerFile)
+}
+
+func (gen *CGenerator) generateBindingFile(bindingFile, headerFile, headerInclude string, m *model.ModelInfo) (data []byte, err error) {
 	var b bytes.Buffer
 	writer := bufio.NewWriter(&b)
 
@@ -147,7 +180,7 @@ func (gen *CGenerator) generateBindingFile(bindingFile, headerFile string, m *mo
 		LangVersion       int
 		EmptyStringAsNull bool
 		NaNAsNull         bool
-	}{m, generator.VersionId, fileIdentifier, filepath.Base(headerFile), gen.Optional, gen.LangVersion, gen.EmptyStringAsNull, gen.NaNAsNull}
+	}{m, generator.VersionId, fileIdentifier, headerInclude, gen.Optional, gen.LangVersion, gen.EmptyStringAsNull, gen.NaNAsNull}
 
 	var tpl *template.Template
 
@@ -187,7 +220,7 @@ func (gen *CGenerator) WriteModelBindingFile(options generator.Options, mergedMo
 		modelSource = formattedSource
 	}
 
-	if err = generator.WriteFile(modelFile, modelSource, options.ModelInfoFile); err != nil {
+	if err = options.WriteFile(modelFile, modelSource, options.ModelInfoFile); err != nil {
 		return fmt.Errorf("can't write model file %s: %s", modelFile, err)
 	} else if err2 != nil {
 		// now when the model has been written (for debugging purposes), we can return the error
@@ -142,6 +142,9 @@ func (mo *fbsObject) PreDeclareCppRelTargets() (string, error) {
 type fbsField struct {
 	*binding.Field
 	fbsField *reflection.Field
+
+	// whether the field is annotated as "optional"; the actual wrapper type (Field.Optional) depends on the generator
+	isOptional bool
 }
 
 // Merge implements model.PropertyMeta interface
@@ -296,8 +299,8 @@ func (mp *fbsField) FbIsFloatingPoint() bool {
 	return false
 }
 
-// Try to determine the namespace of the target entity but don't fail if we can't because it's declared in a different
-// file. Assume no namespace in that case and hope for the best.
+// relTargetNamespace returns the namespace of the target entity, which may be declared in any of the source files;
+// they're all parsed and merged before generating. Assume no namespace if the target isn't in the model at all.
 func (mp *fbsField) relTargetNamespace() string {
 	if targetEntity, err := mp.ModelProperty.Entity.Model.FindEntityByName(mp.ModelProperty.RelationTarget); err == nil {
 		if targetEntity.Meta != nil {
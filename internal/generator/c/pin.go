@@ -0,0 +1,336 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cgenerator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/binding"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+)
+
+// schemaEdit is a change of a single line of a schema file, see schemaPinner
+type schemaEdit struct {
+	line   int // 0-based line index
+	column int // 0-based byte offset in the line, -1 to insert the text as a new line before the line
+	length int // number of bytes replaced
+	text   string
+}
+
+// schemaPinner collects the edits of the schema files adding the missing UID annotations, see PinUids()
+type schemaPinner struct {
+	source *schemaSource
+	edits  map[*schemaFile][]schemaEdit
+	split  map[*schemaFile]bool // files with compact declarations put on separate lines, see splitFields()
+}
+
+// uidValueRegexp matches an empty uid annotation, i.e. the part replaced by "uid=123"
+var uidValueRegexp = regexp.MustCompile(`^uid(\s*[=:]\s*("")?)?`)
+
+// PinUids implements generator.UidPinner: it adds "/// objectbox:uid=..." comments to the tables and fields declared
+// without a uid annotation, fills in empty uid annotations and adds the uid to the relation annotations.
+// The declarations are looked up in the schema text the same way as for the error positions, see schemaSource. Fields
+// declared on the same line as the table or another field are put on lines of their own first, see splitFields().
+func (gen *CGenerator) PinUids(sourceFile string, options generator.Options, mergedModel *model.ModelInfo) (map[string][]byte, error) {
+	var pinner = &schemaPinner{
+		source: loadSchemaSource(options.FileSystem(), sourceFile),
+		edits:  make(map[*schemaFile][]schemaEdit),
+		split:  make(map[*schemaFile]bool),
+	}
+
+	// the lines are split before collecting any edits, so that the line indexes of the edits stay valid
+	var entities = mergedModel.EntitiesWithMeta()
+	for _, entity := range entities {
+		if file, line, err := pinner.object(entity); err == nil {
+			pinner.splitFields(file, line)
+		}
+	}
+
+	for _, entity := range entities {
+		if err := pinner.pinEntity(entity); err != nil {
+			return nil, fmt.Errorf("can't pin the UIDs of entity %s: %s", entity.Name, err)
+		}
+	}
+
+	var result = make(map[string][]byte)
+	for _, file := range pinner.source.files {
+		if edits := pinner.edits[file]; len(edits) > 0 || pinner.split[file] {
+			result[file.path] = []byte(strings.Join(applySchemaEdits(file.lines, edits), "\n"))
+		}
+	}
+	return result, nil
+}
+
+// object finds the declaration of the entity, see schemaSource.object()
+func (pinner *schemaPinner) object(entity *model.Entity) (*schemaFile, int, error) {
+	var name = string(entity.Meta.(*fbsObject).fbsObject.Name())
+	var file, line = pinner.source.object(name)
+	if file == nil {
+		return nil, -1, fmt.Errorf("declaration of %s not found, it must be at the start of a line", name)
+	}
+	return file, line, nil
+}
+
+func (pinner *schemaPinner) pinEntity(entity *model.Entity) error {
+	file, line, err := pinner.object(entity)
+	if err != nil {
+		return err
+	}
+
+	annotations, err := pinner.annotations(file, line, supportedEntityAnnotations)
+	if err != nil {
+		return err
+	}
+	pinner.pinUid(file, line, annotations["uid"], entity.Id)
+
+	for _, relation := range entity.Relations {
+		var annotation *binding.Annotation
+		for key, a := range annotations {
+			if strings.HasPrefix(key, "relation-") && a.Details["name"].Value == relation.Name {
+				annotation = a
+			}
+		}
+		if annotation == nil {
+			return fmt.Errorf("relation %s annotation not found", relation.Name)
+		}
+		if err := pinner.pinRelationUid(file, annotation, relation.Id); err != nil {
+			return fmt.Errorf("relation %s: %s", relation.Name, err)
+		}
+	}
+
+	for _, property := range entity.Properties {
+		var fieldMeta, isFbsField = property.Meta.(*fbsField)
+		if !isFbsField {
+			continue
+		}
+		var name = string(fieldMeta.fbsField.Name())
+		var fieldLine = file.field(line, name)
+		if fieldLine < 0 {
+			return fmt.Errorf("declaration of field %s not found, it must be at the start of a line", name)
+		}
+
+		annotations, err := pinner.annotations(file, fieldLine, supportedPropertyAnnotations)
+		if err != nil {
+			return fmt.Errorf("field %s: %s", name, err)
+		}
+		pinner.pinUid(file, fieldLine, annotations["uid"], property.Id)
+	}
+	return nil
+}
+
+// splitFields puts each field of the object declared at the given line on a line of its own if any of them is declared
+// after the opening brace or after another field on the same line, e.g. "table A { id: ulong; name: string; }", so
+// that the uid annotations can be added as comments before them. The closing brace is put on a line of its own, too.
+// Objects with all the fields at the start of a line are left as they are.
+func (pinner *schemaPinner) splitFields(file *schemaFile, objectLine int) {
+	// find the lines of the object and split each of them into the declarations, keeping the trailing comment
+	type splitLine struct {
+		pieces  []string
+		depths  []int // the depth at the start of each piece, 1 inside the object body
+		comment string
+		cr      bool
+	}
+	var lines []splitLine
+	var needed, opened = false, false
+	var depth = 0
+	for i := objectLine; i < len(file.lines); i++ {
+		var text = file.lines[i]
+		var current = splitLine{cr: strings.HasSuffix(text, "\r")}
+		text = strings.TrimSuffix(text, "\r")
+		var code = stripLineComment(text)
+		current.comment = strings.TrimSpace(text[len(code):])
+
+		var start, startDepth = 0, depth
+		var cut = func(end int) {
+			if piece := strings.TrimSpace(code[start:end]); len(piece) > 0 {
+				current.pieces = append(current.pieces, piece)
+				current.depths = append(current.depths, startDepth)
+			}
+			start, startDepth = end, depth
+		}
+		for pos, char := range code {
+			switch {
+			case char == '{':
+				depth++
+				if depth == 1 {
+					opened = true
+					cut(pos + 1)
+				}
+			case char == '}':
+				if depth == 1 {
+					cut(pos)
+				}
+				depth--
+				if opened && depth == 0 && len(strings.TrimSpace(code[pos+1:])) > 0 {
+					return // another declaration follows on the same line, not supported
+				}
+			case char == ';' && depth == 1:
+				cut(pos + 1)
+			}
+		}
+		cut(len(code))
+		lines = append(lines, current)
+
+		// a field not at the start of its line, i.e. not the first piece
+		for p := 1; p < len(current.pieces); p++ {
+			if current.depths[p] == 1 && !strings.HasPrefix(current.pieces[p], "}") {
+				needed = true
+			}
+		}
+		if opened && depth <= 0 {
+			break
+		}
+	}
+	if !needed {
+		return
+	}
+
+	var objectIndent = leadingSpace(file.lines[objectLine])
+	var fieldIndent = objectIndent + file.indentUnit()
+	var result []string
+	for i, line := range lines {
+		var newLines []string
+		for p, piece := range line.pieces {
+			var indent = fieldIndent
+			if p == 0 {
+				indent = leadingSpace(file.lines[objectLine+i])
+			} else if line.depths[p] != 1 || strings.HasPrefix(piece, "}") {
+				indent = objectIndent
+			}
+			newLines = append(newLines, indent+piece)
+		}
+		if len(newLines) == 0 {
+			newLines = append(newLines, strings.TrimSuffix(file.lines[objectLine+i], "\r"))
+		} else if len(line.comment) > 0 {
+			newLines[len(newLines)-1] += " " + line.comment
+		}
+		for _, text := range newLines {
+			if line.cr {
+				text = text + "\r"
+			}
+			result = append(result, text)
+		}
+	}
+
+	file.lines = append(file.lines[:objectLine], append(result, file.lines[objectLine+len(lines):]...)...)
+	pinner.split[file] = true
+}
+
+// annotations reads the annotations from the documentation comments of the declaration on the given line
+func (pinner *schemaPinner) annotations(file *schemaFile, line int, supportedAnnotations map[string]bool) (map[string]*binding.Annotation, error) {
+	var annotations = make(map[string]*binding.Annotation)
+	for _, pos := range file.docComments(line) {
+		var text = file.lines[pos.Line-1][pos.Column-1:]
+		var comment, commentPos = docComment(text, 0, []diagnostics.Position{pos}, 1)
+		if _, err := parseCommentAsAnnotations(comment, commentPos, &annotations, supportedAnnotations); err != nil {
+			return nil, err
+		}
+	}
+	return annotations, nil
+}
+
+// pinUid adds the uid annotation on a new line before the declaration, or fills in an existing empty one
+func (pinner *schemaPinner) pinUid(file *schemaFile, line int, annotation *binding.Annotation, id model.IdUid) {
+	var uid = uidString(id)
+	if annotation == nil {
+		var declaration = file.lines[line]
+		var text = leadingSpace(declaration) + "/// objectbox:uid=" + uid
+		if strings.HasSuffix(declaration, "\r") {
+			text = text + "\r"
+		}
+		pinner.edits[file] = append(pinner.edits[file], schemaEdit{line: line, column: -1, text: text})
+	} else if len(annotation.Value) == 0 {
+		pinner.replaceEmptyUid(file, annotation, uid)
+	}
+}
+
+// pinRelationUid adds the uid detail to the relation annotation, or fills in an existing empty one
+func (pinner *schemaPinner) pinRelationUid(file *schemaFile, annotation *binding.Annotation, id model.IdUid) error {
+	var uid = uidString(id)
+	if detail := annotation.Details["uid"]; detail != nil {
+		if len(detail.Value) == 0 {
+			pinner.replaceEmptyUid(file, detail, uid)
+		}
+		return nil
+	}
+
+	var line = annotation.Pos.Line - 1
+	var end = strings.Index(file.lines[line][annotation.Pos.Column-1:], ")")
+	if end < 0 {
+		return fmt.Errorf("end of the annotation not found on line %d", annotation.Pos.Line)
+	}
+	var column = annotation.Pos.Column - 1 + end
+	pinner.edits[file] = append(pinner.edits[file], schemaEdit{line: line, column: column, text: ",uid=" + uid})
+	return nil
+}
+
+func (pinner *schemaPinner) replaceEmptyUid(file *schemaFile, annotation *binding.Annotation, uid string) {
+	var line, column = annotation.Pos.Line - 1, annotation.Pos.Column - 1
+	var length = len(uidValueRegexp.FindString(file.lines[line][column:]))
+	pinner.edits[file] = append(pinner.edits[file], schemaEdit{line: line, column: column, length: length, text: "uid=" + uid})
+}
+
+// applySchemaEdits returns a copy of the lines with the edits applied, starting from the end so that the lines and
+// columns of the remaining edits stay valid
+func applySchemaEdits(lines []string, edits []schemaEdit) []string {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line > edits[j].line
+		}
+		return edits[i].column > edits[j].column
+	})
+
+	var result = append([]string{}, lines...)
+	for _, edit := range edits {
+		if edit.column < 0 {
+			result = append(result[:edit.line], append([]string{edit.text}, result[edit.line:]...)...)
+		} else {
+			var text = result[edit.line]
+			result[edit.line] = text[:edit.column] + edit.text + text[edit.column+edit.length:]
+		}
+	}
+	return result
+}
+
+func uidString(id model.IdUid) string {
+	uid, _ := id.GetUid()
+	return fmt.Sprintf("%d", uid)
+}
+
+// leadingSpace returns the indentation of the line
+func leadingSpace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// indentUnit returns the indentation of the first indented line of the file, used for the fields put on new lines
+func (file *schemaFile) indentUnit() string {
+	for _, line := range file.lines {
+		if indent := leadingSpace(line); len(indent) > 0 && len(strings.TrimSpace(line)) > 0 {
+			return indent
+		}
+	}
+	return "\t"
+}
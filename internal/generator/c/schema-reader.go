@@ -27,6 +27,7 @@ import (
 	"strings"
 
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/binding"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/flatbuffersc/reflection"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
 )
@@ -34,6 +35,7 @@ import (
 var supportedEntityAnnotations = map[string]bool{
 	"name":      true,
 	"relation":  true, // to-many, standalone
+	"removed":   true,
 	"sync":      true,
 	"transient": true,
 	"uid":       true,
@@ -67,11 +69,16 @@ type fbSchemaReader struct {
 
 	// see CGenerator.Optional
 	optional string
+
+	// source text of the schema, used to determine positions for errors
+	source *schemaSource
 }
 
 // const annotationPrefix = "objectbox:"
 
+// read processes all objects in the schema; errors are collected so that all invalid objects are reported at once
 func (r *fbSchemaReader) read(schema *reflection.Schema) error {
+	var errs diagnostics.List
 	for i := 0; i < schema.ObjectsLength(); i++ {
 		var object reflection.Object
 		if !schema.Objects(&object, i) {
@@ -79,11 +86,11 @@ func (r *fbSchemaReader) read(schema *reflection.Schema) error {
 		}
 
 		if err := r.readObject(&object); err != nil {
-			return fmt.Errorf("object %d %s: %v", i, string(object.Name()), err)
+			errs.Add(diagnostics.Wrapf(err, "object %[2]d %[3]s: %[1]s", i, string(object.Name())))
 		}
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func (r *fbSchemaReader) readObject(object *reflection.Object) error {
@@ -92,19 +99,23 @@ func (r *fbSchemaReader) readObject(object *reflection.Object) error {
 	entity.Meta = metaEntity
 	metaEntity.SetName(string(object.Name()))
 
+	var sourceFile, sourceLine = r.source.object(string(object.Name()))
+	var pos = sourceFile.position(sourceLine, metaEntity.Name)
+
 	// look for annotations: "/// objectbox:..."
 	var annotations = make(map[string]*binding.Annotation)
+	var docPositions = sourceFile.docComments(sourceLine)
 	for i := 0; i < object.DocumentationLength(); i++ {
-		var comment = strings.TrimSpace(string(object.Documentation(i)))
-		if isAnnotation, err := parseCommentAsAnnotations(comment, &annotations, supportedEntityAnnotations); err != nil {
-			return err
+		var comment, commentPos = docComment(string(object.Documentation(i)), i, docPositions, object.DocumentationLength())
+		if isAnnotation, err := parseCommentAsAnnotations(comment, commentPos, &annotations, supportedEntityAnnotations); err != nil {
+			return diagnostics.WithPosition(err, pos)
 		} else if !isAnnotation {
 			entity.Comments = append(entity.Comments, comment)
 		}
 	}
 
 	if err := metaEntity.ProcessAnnotations(annotations); err != nil {
-		return err
+		return diagnostics.WithPosition(err, pos)
 	}
 
 	if metaEntity.IsSkipped {
@@ -122,8 +133,10 @@ func (r *fbSchemaReader) readObject(object *reflection.Object) error {
 			return fmt.Errorf("can't access field %d", i)
 		}
 
-		if err := r.readObjectField(entity, &field); err != nil {
-			return fmt.Errorf("field %d %s: %v", i, string(field.Name()), err)
+		var fieldLine = sourceFile.field(sourceLine, string(field.Name()))
+		if err := r.readObjectField(entity, &field, sourceFile, fieldLine); err != nil {
+			err = diagnostics.WithPosition(err, sourceFile.position(fieldLine, string(field.Name())))
+			return diagnostics.Wrapf(err, "field %[2]d %[3]s: %[1]s", i, string(field.Name()))
 		}
 	}
 
@@ -138,17 +151,18 @@ func (r *fbSchemaReader) readObject(object *reflection.Object) error {
 	return nil
 }
 
-func (r *fbSchemaReader) readObjectField(entity *model.Entity, field *reflection.Field) error {
+func (r *fbSchemaReader) readObjectField(entity *model.Entity, field *reflection.Field, sourceFile *schemaFile, sourceLine int) error {
 	var property = model.CreateProperty(entity, 0, 0)
-	var metaProperty = &fbsField{binding.CreateField(property), field}
+	var metaProperty = &fbsField{Field: binding.CreateField(property), fbsField: field}
 	property.Meta = metaProperty
 	metaProperty.SetName(string(field.Name()))
 
 	// look for annotations: "/// objectbox:..."
 	var annotations = make(map[string]*binding.Annotation)
+	var docPositions = sourceFile.docComments(sourceLine)
 	for i := 0; i < field.DocumentationLength(); i++ {
-		var comment = strings.TrimSpace(string(field.Documentation(i)))
-		if isAnnotation, err := parseCommentAsAnnotations(comment, &annotations, supportedPropertyAnnotations); err != nil {
+		var comment, commentPos = docComment(string(field.Documentation(i)), i, docPositions, field.DocumentationLength())
+		if isAnnotation, err := parseCommentAsAnnotations(comment, commentPos, &annotations, supportedPropertyAnnotations); err != nil {
 			return err
 		} else if !isAnnotation {
 			property.Comments = append(property.Comments, comment)
@@ -195,9 +209,10 @@ func (r *fbSchemaReader) readObjectField(entity *model.Entity, field *reflection
 
 	if annotations["optional"] != nil {
 		if len(annotations["optional"].Value) != 0 {
-			return errors.New("optional annotation value must be empty")
+			return diagnostics.Errorf(diagnostics.CodeAnnotation, annotations["optional"].Pos, "optional annotation value must be empty")
 		}
 		annotations["optional"].Value = r.optional
+		metaProperty.isOptional = true
 	}
 
 	if err := metaProperty.ProcessAnnotations(annotations); err != nil {
@@ -208,14 +223,25 @@ func (r *fbSchemaReader) readObjectField(entity *model.Entity, field *reflection
 	return nil
 }
 
+// docComment returns the trimmed documentation comment and its position in the source file, if known
+func docComment(text string, index int, positions []diagnostics.Position, count int) (string, diagnostics.Position) {
+	var comment = strings.TrimSpace(text)
+	// only trust the positions if the source text matches what FlatBuffers parser has seen
+	if len(positions) != count {
+		return comment, diagnostics.Position{}
+	}
+	return comment, positions[index].Offset(strings.Index(text, comment))
+}
+
 // NOTE this is a copy of gogenerator.parseAnnotations with changes to accommodate a different format
-func parseCommentAsAnnotations(comment string, annotations *map[string]*binding.Annotation, supportedAnnotations map[string]bool) (bool, error) {
+func parseCommentAsAnnotations(comment string, pos diagnostics.Position, annotations *map[string]*binding.Annotation, supportedAnnotations map[string]bool) (bool, error) {
 	if strings.HasPrefix(comment, "objectbox:") || strings.HasPrefix(comment, "ObjectBox:") {
-		comment = strings.TrimSpace(comment[len("objectbox:"):])
-		if len(comment) == 0 {
+		var annotationsStr = strings.TrimSpace(comment[len("objectbox:"):])
+		if len(annotationsStr) == 0 {
 			return true, nil
 		}
-		return true, binding.ParseAnnotations(comment, annotations, supportedAnnotations)
+		pos = pos.Offset(strings.Index(comment, annotationsStr))
+		return true, binding.ParseAnnotations(annotationsStr, pos, annotations, supportedAnnotations)
 	}
 	return false, nil
 }
@@ -0,0 +1,170 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cgenerator
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// schemaSource holds the text of a FlatBuffers schema file and the files it includes.
+// FlatBuffers reflection doesn't provide source positions so declarations are looked up in the text instead, in order
+// to report errors at the right place. The lookup is best-effort, a zero position is returned if not found.
+type schemaSource struct {
+	files []*schemaFile
+}
+
+type schemaFile struct {
+	path  string
+	lines []string
+}
+
+var schemaIncludeRegexp = regexp.MustCompile(`^\s*include\s+"([^"]+)"\s*;`)
+
+// loadSchemaSource reads the given schema file and (recursively) all the files it includes
+func loadSchemaSource(fsys vfs.FS, path string) *schemaSource {
+	var src = &schemaSource{}
+	src.load(fsys, path)
+	return src
+}
+
+// load reads the file, unless already loaded; returns false if it can't be read
+func (src *schemaSource) load(fsys vfs.FS, path string) bool {
+	for _, file := range src.files {
+		if file.path == path {
+			return true // already loaded
+		}
+	}
+
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	var file = &schemaFile{path: path, lines: strings.Split(string(data), "\n")}
+	src.files = append(src.files, file)
+
+	for _, line := range file.lines {
+		if match := schemaIncludeRegexp.FindStringSubmatch(line); match != nil {
+			// like flatc, look relative to the including file first and then relative to the current directory
+			if !src.load(fsys, filepath.Join(filepath.Dir(path), match[1])) {
+				src.load(fsys, match[1])
+			}
+		}
+	}
+	return true
+}
+
+// contents returns the text of all the loaded files, by their paths
+func (src *schemaSource) contents() map[string][]byte {
+	var result = make(map[string][]byte, len(src.files))
+	for _, file := range src.files {
+		result[file.path] = []byte(strings.Join(file.lines, "\n"))
+	}
+	return result
+}
+
+// object finds the declaration of a table/struct with the given (fully qualified) name.
+// Returns nil if not found, otherwise the file and the (0-based) line index.
+func (src *schemaSource) object(name string) (*schemaFile, int) {
+	if src == nil {
+		return nil, -1
+	}
+
+	if lastDot := strings.LastIndex(name, "."); lastDot >= 0 {
+		name = name[lastDot+1:]
+	}
+
+	var declaration = regexp.MustCompile(`^\s*(table|struct)\s+` + regexp.QuoteMeta(name) + `\b`)
+	for _, file := range src.files {
+		for i, line := range file.lines {
+			if declaration.MatchString(stripLineComment(line)) {
+				return file, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// field finds the declaration of a field inside the object declared at the given line, returns -1 if not found
+func (file *schemaFile) field(objectLine int, name string) int {
+	if file == nil {
+		return -1
+	}
+
+	var declaration = regexp.MustCompile(`^\s*` + regexp.QuoteMeta(name) + `\s*:`)
+	var depth = 0
+	for i := objectLine; i < len(file.lines); i++ {
+		var line = stripLineComment(file.lines[i])
+		if depth > 0 && declaration.MatchString(line) {
+			return i
+		}
+		depth = depth + strings.Count(line, "{") - strings.Count(line, "}")
+		if depth <= 0 && strings.Contains(line, "}") {
+			break
+		}
+	}
+	return -1
+}
+
+// position returns the position of the given identifier on the given line, or at its first non-space character
+func (file *schemaFile) position(line int, identifier string) diagnostics.Position {
+	if file == nil || line < 0 || line >= len(file.lines) {
+		return diagnostics.Position{}
+	}
+
+	var text = file.lines[line]
+	var column = strings.Index(text, identifier)
+	if len(identifier) == 0 || column < 0 {
+		column = len(text) - len(strings.TrimLeft(text, " \t"))
+	}
+	return diagnostics.Position{File: file.path, Line: line + 1, Column: column + 1}
+}
+
+// docComments returns the positions of the text of each documentation comment ("/// text") preceding a declaration
+func (file *schemaFile) docComments(declarationLine int) []diagnostics.Position {
+	if file == nil {
+		return nil
+	}
+
+	var result []diagnostics.Position
+	for i := declarationLine - 1; i >= 0; i-- {
+		var text = file.lines[i]
+		var index = strings.Index(text, "///")
+		if index < 0 || len(strings.TrimSpace(text[:index])) > 0 {
+			break
+		}
+		var pos = diagnostics.Position{File: file.path, Line: i + 1, Column: index + len("///") + 1}
+		result = append([]diagnostics.Position{pos}, result...)
+	}
+	return result
+}
+
+func stripLineComment(line string) string {
+	if index := strings.Index(line, "//"); index >= 0 {
+		return line[:index]
+	}
+	return line
+}
@@ -79,7 +79,7 @@ struct {{$entity.Meta.CppName}}_ {
 	{{- end}} {{$property.Meta.CppName}};
 {{- end}}
 {{- range $relation := $entity.Relations}}
-	static const obx::RelationStandalone<{{$entity.Meta.CppName}}, {{$relation.Target.Meta.CppName}}> {{$relation.Meta.CppName}};
+	static const obx::RelationStandalone<{{$entity.Meta.CppName}}, {{$relation.Target.Meta.CppNamespacePrefix}}{{$relation.Target.Meta.CppName}}> {{$relation.Meta.CppName}};
 {{- end}}
 };
 {{with $entity.Meta.CppNamespaceEnd}}{{.}}{{end -}}
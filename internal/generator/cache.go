@@ -0,0 +1,271 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// CacheFile returns the incremental generation cache file path for the given model JSON file, see Options.Cache
+func CacheFile(modelInfoFile string) string {
+	return filepath.Join(filepath.Dir(modelInfoFile), ".objectbox-cache")
+}
+
+// cacheData is the contents of the cache file
+type cacheData struct {
+	VersionId int                      `json:"versionId"`
+	Options   string                   `json:"options"` // hash of the options affecting the output, see cacheOptionsKey()
+	Sources   map[string]*cachedSource `json:"sources"` // by the source file path
+}
+
+// cachedSource describes what the bindings of a single source were generated from, using content hashes
+type cachedSource struct {
+	Inputs   map[string]string `json:"inputs"`            // by path: the source file itself, included files, etc.
+	Depends  map[string]string `json:"depends,omitempty"` // by path: the inputs of the sources declaring relation targets
+	Entities map[string]string `json:"entities"`          // by entity UID: the entity as stored in the model JSON file
+	Outputs  map[string]string `json:"outputs"`           // by path: the generated binding files
+}
+
+// cacheState keeps track of the sources which don't need to be parsed and generated again
+type cacheState struct {
+	file      string
+	options   string
+	previous  *cacheData       // nil if there's no usable cache file
+	modelInfo *model.ModelInfo // the model the entity hashes are taken from when saving
+
+	mutex   sync.Mutex
+	current map[string]*cachedSource
+	entries map[string][]model.Uid // entity UIDs of the current sources, hashed when saving
+}
+
+// newCacheState loads the cache file; a missing or unreadable cache file means all sources are processed
+func newCacheState(options Options, modelInfo *model.ModelInfo) *cacheState {
+	var state = &cacheState{
+		file:      CacheFile(options.ModelInfoFile),
+		options:   cacheOptionsKey(options),
+		modelInfo: modelInfo,
+		current:   make(map[string]*cachedSource),
+		entries:   make(map[string][]model.Uid),
+	}
+
+	if data, err := options.FileSystem().ReadFile(state.file); err == nil {
+		var previous cacheData
+		if json.Unmarshal(data, &previous) == nil && previous.VersionId == VersionId && previous.Options == state.options {
+			state.previous = &previous
+		}
+	}
+	return state
+}
+
+// cacheOptionsKey returns a hash of the options and code generator settings which affect the generated files, including
+// their paths
+func cacheOptionsKey(options Options) string {
+	var generators []string
+	for _, codeGenerator := range options.CodeGenerators {
+		// only exported fields are serialized, i.e. the configuration of the generator, not its internal state
+		config, _ := json.Marshal(codeGenerator)
+		generators = append(generators, fmt.Sprintf("%T%s", codeGenerator, config))
+	}
+
+	key, _ := json.Marshal([]interface{}{options.OutPath, options.OutHeadersPath, options.PreserveDirs, options.InputRoot(), generators})
+	return contentHash(key)
+}
+
+// lookup returns the cache entry of the given source if the source, the files it includes, the sources declaring its
+// relation targets, its entities in the stored model and its generated files are all unchanged since the cache was
+// saved, together with the generated files staged for writing; returns nil otherwise, e.g. if a generated file is
+// missing. Must be called before the stored model is changed by merging the sources.
+func (state *cacheState) lookup(options Options, storedModel *model.ModelInfo, sourceFile string) (*cachedSource, *outputState) {
+	if state.previous == nil {
+		return nil, nil
+	}
+
+	var cached = state.previous.Sources[filepath.Clean(sourceFile)]
+	if cached == nil || len(cached.Inputs) == 0 {
+		return nil, nil
+	}
+
+	for _, files := range []map[string]string{cached.Inputs, cached.Depends} {
+		for file, hash := range files {
+			data, err := options.FileSystem().ReadFile(file)
+			if err != nil || contentHash(data) != hash {
+				return nil, nil
+			}
+		}
+	}
+
+	for uidString, hash := range cached.Entities {
+		uid, err := strconv.ParseUint(uidString, 10, 64)
+		if err != nil {
+			return nil, nil
+		}
+		entity, err := storedModel.FindEntityByUid(uid)
+		if err != nil || entityHash(entity) != hash {
+			return nil, nil
+		}
+	}
+
+	var output = newOutputState(options.FileSystem())
+	for _, codeGenerator := range options.CodeGenerators {
+		for _, file := range codeGenerator.BindingFiles(sourceFile, options) {
+			hash, recorded := cached.Outputs[filepath.Clean(file)]
+			if !recorded {
+				return nil, nil
+			}
+			data, err := options.FileSystem().ReadFile(file)
+			if err != nil || contentHash(data) != hash {
+				return nil, nil
+			}
+			output.add(file, data, sourceFile)
+		}
+	}
+	return cached, output
+}
+
+// restore marks the entities of a cached source as present in the current run
+func (state *cacheState) restore(storedModel *model.ModelInfo, sourceFile string, cached *cachedSource) ([]*model.Entity, error) {
+	var entities []*model.Entity
+	for uidString := range cached.Entities {
+		uid, _ := strconv.ParseUint(uidString, 10, 64)
+		entity, err := storedModel.FindEntityByUid(uid)
+		if err != nil {
+			return nil, err
+		}
+		entity.CurrentlyPresent = true
+		entities = append(entities, entity)
+	}
+
+	state.mutex.Lock()
+	state.current[filepath.Clean(sourceFile)] = cached
+	for _, entity := range entities {
+		uid, _ := entity.Id.GetUid()
+		state.entries[filepath.Clean(sourceFile)] = append(state.entries[filepath.Clean(sourceFile)], uid)
+	}
+	state.mutex.Unlock()
+	return entities, nil
+}
+
+// record stores the cache entry of a source processed in this run
+func (state *cacheState) record(sourceFile string, inputs map[string]string, entities []*model.Entity, output *outputState) {
+	var entry = &cachedSource{Inputs: inputs, Outputs: make(map[string]string)}
+	for _, file := range output.files {
+		entry.Outputs[filepath.Clean(file.Path)] = contentHash(file.Data)
+	}
+
+	var uids []model.Uid
+	for _, entity := range entities {
+		if uid, err := entity.Id.GetUid(); err == nil {
+			uids = append(uids, uid)
+		}
+	}
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.current[filepath.Clean(sourceFile)] = entry
+	state.entries[filepath.Clean(sourceFile)] = uids
+}
+
+// save writes the cache file, with the entity hashes taken from the final model; the file is only written if changed
+func (state *cacheState) save(fsys vfs.FS) error {
+	var data = cacheData{VersionId: VersionId, Options: state.options, Sources: state.current}
+
+	var declaredIn = make(map[model.Uid]string) // the source file by entity UID
+	for sourceFile, uids := range state.entries {
+		for _, uid := range uids {
+			declaredIn[uid] = sourceFile
+		}
+	}
+
+	for sourceFile, entry := range state.current {
+		entry.Entities = make(map[string]string)
+		entry.Depends = nil
+		for _, uid := range state.entries[sourceFile] {
+			entity, err := state.modelInfo.FindEntityByUid(uid)
+			if err != nil {
+				continue // removed from the model, e.g. a duplicate entity
+			}
+			entry.Entities[strconv.FormatUint(uid, 10)] = entityHash(entity)
+
+			// the bindings also depend on the relation targets declared in other sources, e.g. on their namespace
+			for _, target := range relationTargets(state.modelInfo, entity) {
+				targetUid, err := target.Id.GetUid()
+				if err != nil || declaredIn[targetUid] == "" || declaredIn[targetUid] == sourceFile {
+					continue
+				}
+				if entry.Depends == nil {
+					entry.Depends = make(map[string]string)
+				}
+				for file, hash := range state.current[declaredIn[targetUid]].Inputs {
+					entry.Depends[file] = hash
+				}
+			}
+		}
+	}
+
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't serialize cache file %s: %s", state.file, err)
+	}
+	encoded = append(encoded, '\n')
+
+	if existing, err := fsys.ReadFile(state.file); err == nil && bytes.Equal(existing, encoded) {
+		return nil
+	}
+	if err = vfs.WriteFileAtomic(fsys, state.file, encoded, 0644); err != nil {
+		return fmt.Errorf("can't write cache file %s: %s", state.file, err)
+	}
+	return nil
+}
+
+// relationTargets returns the target entities of the to-one and standalone relations of the entity
+func relationTargets(modelInfo *model.ModelInfo, entity *model.Entity) []*model.Entity {
+	var result []*model.Entity
+	for _, property := range entity.Properties {
+		if len(property.RelationTarget) > 0 {
+			if target, err := modelInfo.FindEntityByName(property.RelationTarget); err == nil {
+				result = append(result, target)
+			}
+		}
+	}
+	for _, relation := range entity.Relations {
+		if relation.Target != nil {
+			result = append(result, relation.Target)
+		}
+	}
+	return result
+}
+
+// entityHash returns the hash of the entity as stored in the model JSON file
+func entityHash(entity *model.Entity) string {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return ""
+	}
+	return contentHash(data)
+}
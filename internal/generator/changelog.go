@@ -0,0 +1,97 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// changelogEntry is the JSON representation of a single change in Result.ChangelogJSON()
+type changelogEntry struct {
+	model.Change
+	Description string `json:"description"`
+	Destructive bool   `json:"destructive,omitempty"`
+}
+
+// changelog is the JSON representation of Result.ChangelogJSON()
+type changelog struct {
+	Changes []changelogEntry `json:"changes"`
+}
+
+// ChangelogJSON returns a JSON document listing the model changes of the run, see Result.Changes; each change includes
+// a human-readable description and whether it removes stored data.
+func (result *Result) ChangelogJSON() ([]byte, error) {
+	var c = changelog{Changes: []changelogEntry{}}
+	for _, change := range result.Changes {
+		c.Changes = append(c.Changes, changelogEntry{Change: change, Description: change.String(), Destructive: change.IsDestructive()})
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ChangelogMarkdown returns a Markdown document listing the model changes of the run, see Result.Changes, e.g. to be
+// included in a pull request as a summary of the database schema migration.
+func (result *Result) ChangelogMarkdown() []byte {
+	var buf strings.Builder
+	buf.WriteString("# ObjectBox model changes\n\n")
+	if len(result.Changes) == 0 {
+		buf.WriteString("No changes.\n")
+		return []byte(buf.String())
+	}
+
+	for _, change := range result.Changes {
+		fmt.Fprintf(&buf, "- %s (UID %d)", change, change.Uid)
+		if change.IsDestructive() {
+			buf.WriteString(" - **removes stored data**")
+		}
+		buf.WriteString("\n")
+	}
+	return []byte(buf.String())
+}
+
+// writeChangelogs writes the changelog files requested by the options, the format is chosen by the file extension
+func writeChangelogs(options Options, result *Result) error {
+	for _, file := range options.ChangelogFiles {
+		var data []byte
+		var err error
+		if strings.EqualFold(filepath.Ext(file), ".json") {
+			data, err = result.ChangelogJSON()
+		} else {
+			data = result.ChangelogMarkdown()
+		}
+		if err == nil {
+			err = vfs.WriteFileAtomic(options.FileSystem(), file, data, 0644)
+		}
+		if err != nil {
+			return fmt.Errorf("can't write changelog file %s: %s", file, err)
+		}
+	}
+	return nil
+}
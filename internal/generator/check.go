@@ -0,0 +1,150 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// FileDiff describes how a single file on disk differs from the generator output
+type FileDiff struct {
+	File    string
+	Status  string // one of "missing", "modified", "stale"
+	Summary string
+}
+
+// CheckError is returned by Process when running with Options.Check and some files are not up-to-date
+type CheckError struct {
+	Files []FileDiff
+}
+
+func (err *CheckError) Error() string {
+	var lines = []string{fmt.Sprintf("%d file(s) not up-to-date, run the generator to update them:", len(err.Files))}
+	for _, diff := range err.Files {
+		var line = fmt.Sprintf("  %-8s %s", diff.Status, diff.File)
+		if len(diff.Summary) > 0 {
+			line = line + ": " + diff.Summary
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diagnostics returns a diagnostic for each file which is not up-to-date
+func (err *CheckError) Diagnostics() []*diagnostics.Diagnostic {
+	var result []*diagnostics.Diagnostic
+	for _, diff := range err.Files {
+		var message = fmt.Sprintf("generated file is %s, run the generator to update it", diff.Status)
+		if len(diff.Summary) > 0 {
+			message = message + ": " + diff.Summary
+		}
+		result = append(result, diagnostics.Error(diagnostics.CodeOutdated, message).InFile(diff.File))
+	}
+	return result
+}
+
+// checkState collects the results of a "check" run - outputs are compared to the files on disk instead of writing
+type checkState struct {
+	mutex     sync.Mutex // bindings of multiple sources may be generated concurrently
+	fsys      vfs.FS
+	generated map[string]bool
+	diffs     []FileDiff
+}
+
+func newCheckState(fsys vfs.FS) *checkState {
+	return &checkState{fsys: fsys, generated: make(map[string]bool)}
+}
+
+// compare records whether the given file contents differ from the file on disk
+func (state *checkState) compare(file string, data []byte) error {
+	existing, err := state.fsys.ReadFile(file)
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.generated[filepath.Clean(file)] = true
+	if os.IsNotExist(err) {
+		state.diffs = append(state.diffs, FileDiff{File: file, Status: "missing", Summary: "would be created"})
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't read %s: %s", file, err)
+	}
+
+	if !bytes.Equal(existing, data) {
+		state.diffs = append(state.diffs, FileDiff{File: file, Status: "modified", Summary: diffSummary(existing, data)})
+	}
+	return nil
+}
+
+// stale records a previously generated file that would be removed, unless it was generated during this run
+func (state *checkState) stale(file string) {
+	if !state.generated[filepath.Clean(file)] {
+		state.diffs = append(state.diffs, FileDiff{File: file, Status: "stale", Summary: "would be removed"})
+	}
+}
+
+// result returns a *CheckError if any differences were found, nil otherwise
+func (state *checkState) result() error {
+	if len(state.diffs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(state.diffs, func(i, j int) bool {
+		return state.diffs[i].File < state.diffs[j].File
+	})
+	return &CheckError{Files: state.diffs}
+}
+
+// diffSummary describes the range of lines that differ, by stripping the common leading and trailing lines
+func diffSummary(old, new []byte) string {
+	var oldLines = strings.Split(string(old), "\n")
+	var newLines = strings.Split(string(new), "\n")
+
+	var prefix = 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+
+	var suffix = 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	var removed = len(oldLines) - prefix - suffix
+	var added = len(newLines) - prefix - suffix
+	if removed == 0 {
+		return fmt.Sprintf("%d line(s) added after line %d", added, prefix)
+	}
+	if removed == 1 {
+		return fmt.Sprintf("line %d differs (%d removed, %d added)", prefix+1, removed, added)
+	}
+	return fmt.Sprintf("lines %d-%d differ (%d removed, %d added)", prefix+1, prefix+removed, removed, added)
+}
@@ -0,0 +1,212 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// inputState collects the files read while parsing the sources, i.e. the sources themselves, the included schema files,
+// the other files of a Go package, etc.
+type inputState struct {
+	mutex sync.Mutex
+	files map[string]string // content hash by the cleaned path; empty if not known
+}
+
+func newInputState() *inputState {
+	return &inputState{files: make(map[string]string)}
+}
+
+func (state *inputState) add(file string, hash string) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.files[filepath.Clean(file)] = hash
+}
+
+// addAll adds the inputs of the other state
+func (state *inputState) addAll(other *inputState) {
+	other.mutex.Lock()
+	defer other.mutex.Unlock()
+	for file, hash := range other.files {
+		state.add(file, hash)
+	}
+}
+
+// hashes returns the content hashes of the inputs, except for the generated files, see list()
+func (state *inputState) hashes(options Options) map[string]string {
+	var result = make(map[string]string)
+	for _, file := range state.list(options) {
+		state.mutex.Lock()
+		result[file] = state.files[file]
+		state.mutex.Unlock()
+	}
+	return result
+}
+
+// contentHash returns a hex-encoded SHA-256 of the given data
+func contentHash(data []byte) string {
+	var sum = sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// recorder returns a file system recording all successfully read files as inputs
+func (state *inputState) recorder(fsys vfs.FS) vfs.FS {
+	return inputRecorder{FS: fsys, inputs: state}
+}
+
+// list returns the sorted inputs, except for the generated files (e.g. Go bindings read as part of the package)
+func (state *inputState) list(options Options) []string {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	var result []string
+	for file := range state.files {
+		if options.output != nil {
+			if _, generated := options.output.indexes[file]; generated {
+				continue
+			}
+		}
+		if file != filepath.Clean(options.ModelInfoFile) && isGeneratedFile(options, file) {
+			continue
+		}
+		result = append(result, file)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func isGeneratedFile(options Options, file string) bool {
+	for _, codeGenerator := range options.CodeGenerators {
+		if codeGenerator.IsGeneratedFile(file) {
+			return true
+		}
+	}
+	return false
+}
+
+type inputRecorder struct {
+	vfs.FS
+	inputs *inputState
+}
+
+func (fsys inputRecorder) ReadFile(name string) ([]byte, error) {
+	data, err := fsys.FS.ReadFile(name)
+	if err == nil {
+		fsys.inputs.add(name, contentHash(data))
+	}
+	return data, err
+}
+
+// Outputs returns the paths of all the generated files, including the model JSON file
+func (result *Result) Outputs() []string {
+	var outputs = make([]string, 0, len(result.Files))
+	for _, file := range result.Files {
+		outputs = append(outputs, file.Path)
+	}
+	return outputs
+}
+
+// Depfile returns a Makefile-style dependency file, listing the generated files as targets depending on the inputs.
+// The model JSON file is only listed as an input because it's updated by the generator itself and a rule producing
+// its own prerequisite would be circular.
+func (result *Result) Depfile() []byte {
+	var buf strings.Builder
+	var first = true
+	for _, file := range result.Files {
+		if len(file.permSource) == 0 {
+			continue // the model JSON file
+		}
+		if !first {
+			buf.WriteString(" \\\n ")
+		}
+		first = false
+		buf.WriteString(escapeDepfilePath(file.Path))
+	}
+	buf.WriteString(":")
+	for _, file := range result.Inputs {
+		buf.WriteString(" \\\n  ")
+		buf.WriteString(escapeDepfilePath(file))
+	}
+	buf.WriteString("\n")
+	return []byte(buf.String())
+}
+
+// escapeDepfilePath escapes the characters with a special meaning in a Makefile rule, as understood by Make and Ninja
+func escapeDepfilePath(path string) string {
+	path = filepath.ToSlash(path)
+	path = strings.ReplaceAll(path, "$", "$$")
+	path = strings.ReplaceAll(path, "#", "\\#")
+	path = strings.ReplaceAll(path, " ", "\\ ")
+	return path
+}
+
+// manifest is the JSON representation of Result.Manifest()
+type manifest struct {
+	Outputs []string `json:"outputs"`
+	Inputs  []string `json:"inputs"`
+	Removed []string `json:"removed"`
+}
+
+// Manifest returns a JSON document listing all the generated files ("outputs", including the model JSON file),
+// the files they were generated from ("inputs") and the previously generated files removed by the run ("removed").
+func (result *Result) Manifest() ([]byte, error) {
+	var m = manifest{Outputs: result.Outputs(), Inputs: result.Inputs, Removed: result.Removed}
+	if m.Inputs == nil {
+		m.Inputs = []string{}
+	}
+	if m.Removed == nil {
+		m.Removed = []string{}
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeBuildFiles writes the dependency file, the manifest and the changelogs, if requested by the options
+func writeBuildFiles(options Options, result *Result) error {
+	if len(options.DepFile) != 0 {
+		if err := vfs.WriteFileAtomic(options.FileSystem(), options.DepFile, result.Depfile(), 0644); err != nil {
+			return fmt.Errorf("can't write dependency file %s: %s", options.DepFile, err)
+		}
+	}
+
+	if len(options.ManifestFile) != 0 {
+		data, err := result.Manifest()
+		if err == nil {
+			err = vfs.WriteFileAtomic(options.FileSystem(), options.ManifestFile, data, 0644)
+		}
+		if err != nil {
+			return fmt.Errorf("can't write manifest file %s: %s", options.ManifestFile, err)
+		}
+	}
+	return writeChangelogs(options, result)
+}
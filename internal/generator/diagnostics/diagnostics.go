@@ -0,0 +1,276 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package diagnostics provides reporting of errors, warnings and notices produced by the generator, with a stable code
+// and the entity/property context, in a human-readable or a machine-readable (JSON lines, SARIF) format.
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity of a diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNotice  Severity = "notice"
+	SeverityInfo    Severity = "info" // progress information, e.g. files being removed
+)
+
+// Diagnostic codes; once released, a code must not change its meaning.
+const (
+	CodeError          = "OBX0001" // an error without a more specific code
+	CodeOutdated       = "OBX0002" // "check" mode: a generated file is not up-to-date
+	CodeSource         = "OBX0003" // invalid source definition, e.g. an unsupported type
+	CodeAnnotation     = "OBX0004" // invalid annotation
+	CodeDestructive    = "OBX0005" // a model change would remove stored data, see Options.AllowDestructive
+	CodeProgress       = "OBX0006" // progress information, e.g. a previously generated file is removed
+	CodeTimePrecision  = "OBX1001" // Go: time.Time stored with millisecond precision
+	CodePrivateSkipped = "OBX1002" // Go: unavailable (private) field of an embedded struct skipped
+	CodePropertyReset  = "OBX1003" // a new UID was specified for an existing property, the property is recreated
+	CodeEntityRemoved  = "OBX1004" // an entity not present in the sources anymore is removed from the model
+	CodeNotPinned      = "OBX1005" // Go: the UID of a field of an embedded struct isn't pinned, see Options.PinUids
+)
+
+// descriptions are used to describe the codes (rules) in the SARIF output
+var descriptions = map[string]string{
+	CodeError:          "Generator error",
+	CodeOutdated:       "Generated file is not up-to-date",
+	CodeSource:         "Invalid source definition",
+	CodeAnnotation:     "Invalid annotation",
+	CodeDestructive:    "Model change removes stored data",
+	CodeProgress:       "Progress information",
+	CodeTimePrecision:  "time.Time is stored with millisecond precision",
+	CodePrivateSkipped: "Unavailable (private) field skipped",
+	CodePropertyReset:  "Property data is reset due to a new UID",
+	CodeEntityRemoved:  "Missing entity removed from the model",
+	CodeNotPinned:      "UID of an embedded struct field not pinned",
+}
+
+// Position in a source file; Line and Column are 1-based, zero if unknown.
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// IsValid returns true if the position refers to a file
+func (pos Position) IsValid() bool {
+	return len(pos.File) > 0
+}
+
+// String formats the position as file:line:column, omitting the unknown parts
+func (pos Position) String() string {
+	var result = pos.File
+	if pos.Line > 0 {
+		result = result + fmt.Sprintf(":%d", pos.Line)
+		if pos.Column > 0 {
+			result = result + fmt.Sprintf(":%d", pos.Column)
+		}
+	}
+	return result
+}
+
+// Offset returns the position moved by the given number of columns on the same line
+func (pos Position) Offset(columns int) Position {
+	if pos.Column > 0 {
+		pos.Column = pos.Column + columns
+	}
+	return pos
+}
+
+// Diagnostic is a single message produced by the generator.
+// It implements the error interface so it can be returned (and wrapped) as any other error.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	Position
+	Entity   string `json:"entity,omitempty"`
+	Property string `json:"property,omitempty"`
+}
+
+// Error returns the message, prefixed by the position if known
+func (d *Diagnostic) Error() string {
+	if d.Position.IsValid() {
+		return d.Position.String() + ": " + d.Message
+	}
+	return d.Message
+}
+
+// String formats the diagnostic as human-readable text
+func (d *Diagnostic) String() string {
+	var text = fmt.Sprintf("%s[%s]: %s", d.Severity, d.Code, d.Message)
+	if d.Position.IsValid() {
+		return d.Position.String() + ": " + text
+	}
+	return text
+}
+
+// Notice creates a notice diagnostic
+func Notice(code string, message string) *Diagnostic {
+	return &Diagnostic{Severity: SeverityNotice, Code: code, Message: message}
+}
+
+// Warning creates a warning diagnostic
+func Warning(code string, message string) *Diagnostic {
+	return &Diagnostic{Severity: SeverityWarning, Code: code, Message: message}
+}
+
+// Info creates a progress information diagnostic
+func Info(message string) *Diagnostic {
+	return &Diagnostic{Severity: SeverityInfo, Code: CodeProgress, Message: message}
+}
+
+// Error creates an error diagnostic
+func Error(code string, message string) *Diagnostic {
+	return &Diagnostic{Severity: SeverityError, Code: code, Message: message}
+}
+
+// InEntity sets the entity context and returns the diagnostic, for chaining
+func (d *Diagnostic) InEntity(entity string) *Diagnostic {
+	d.Entity = entity
+	return d
+}
+
+// InProperty sets the property context and returns the diagnostic, for chaining
+func (d *Diagnostic) InProperty(property string) *Diagnostic {
+	d.Property = property
+	return d
+}
+
+// InFile sets the file context and returns the diagnostic, for chaining
+func (d *Diagnostic) InFile(file string) *Diagnostic {
+	d.File = file
+	return d
+}
+
+// At sets the position and returns the diagnostic, for chaining
+func (d *Diagnostic) At(pos Position) *Diagnostic {
+	d.Position = pos
+	return d
+}
+
+// Errorf creates an error diagnostic at the given position (which may be unknown, i.e. zero)
+func Errorf(code string, pos Position, format string, args ...interface{}) *Diagnostic {
+	return Error(code, fmt.Sprintf(format, args...)).At(pos)
+}
+
+// WithPosition returns the error with the position set, unless it already has one
+func WithPosition(err error, pos Position) error {
+	var d *Diagnostic
+	if !errors.As(err, &d) {
+		return Errorf(CodeSource, pos, "%s", err.Error())
+	} else if d.Position.IsValid() {
+		return err
+	}
+	var copied = *d
+	copied.Position = pos
+	return &copied
+}
+
+// HasPosition checks whether the error is a diagnostic with a known position; for a List, all of its errors must be
+func HasPosition(err error) bool {
+	if list, ok := err.(List); ok {
+		for _, err := range list {
+			if !HasPosition(err) {
+				return false
+			}
+		}
+		return len(list) > 0
+	}
+
+	var d *Diagnostic
+	return errors.As(err, &d) && d.Position.IsValid()
+}
+
+// Wrapf adds context to the error message, like fmt.Errorf(format, err, args...), keeping the diagnostic information.
+// The format must start with a verb for the original message, e.g. Wrapf(err, "%s on entity %s", name).
+func Wrapf(err error, format string, args ...interface{}) error {
+	var d *Diagnostic
+	if !errors.As(err, &d) {
+		return fmt.Errorf(format, append([]interface{}{err}, args...)...)
+	}
+	var copied = *d
+	copied.Message = fmt.Sprintf(format, append([]interface{}{d.Message}, args...)...)
+	return &copied
+}
+
+// FromError converts the given error to a diagnostic, keeping the details if it already is (or wraps) one
+func FromError(err error) *Diagnostic {
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return d
+	}
+	return Error(CodeError, err.Error())
+}
+
+// List collects multiple errors, e.g. the errors of all entities in all the processed source files
+type List []error
+
+// Add appends the error to the list; nil errors are ignored and nested lists are flattened
+func (list *List) Add(err error) {
+	if err == nil {
+		return
+	}
+	var nested List
+	if errors.As(err, &nested) {
+		*list = append(*list, nested...)
+	} else {
+		*list = append(*list, err)
+	}
+}
+
+// Err returns nil if the list is empty, the single error if there's just one, or the list itself
+func (list List) Err() error {
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+	return list
+}
+
+// Error returns the messages of all the errors, one per line
+func (list List) Error() string {
+	var lines = make([]string, len(list))
+	for i, err := range list {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diagnostics returns a diagnostic for each error in the list
+func (list List) Diagnostics() []*Diagnostic {
+	var result []*Diagnostic
+	for _, err := range list {
+		if p, ok := err.(provider); ok {
+			result = append(result, p.Diagnostics()...)
+		} else {
+			result = append(result, FromError(err))
+		}
+	}
+	return result
+}
@@ -0,0 +1,298 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Format of the reported diagnostics
+type Format string
+
+const (
+	FormatText  Format = "text"  // human-readable, one diagnostic per line
+	FormatJSON  Format = "json"  // JSON lines, one diagnostic object per line
+	FormatSARIF Format = "sarif" // a single SARIF 2.1.0 document, written by Flush()
+)
+
+// Formats lists the available formats
+var Formats = []Format{FormatText, FormatJSON, FormatSARIF}
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown diagnostics format %s", name)
+}
+
+// Tool describes the generator in the SARIF output
+type Tool struct {
+	Name    string
+	Version string
+	URI     string
+}
+
+// reporter is the package-level diagnostics sink, similar to the standard logger of the "log" package
+var reporter = struct {
+	sync.Mutex
+	format    Format
+	out       io.Writer
+	tool      Tool
+	collected []*Diagnostic // SARIF results, written by Flush()
+}{format: FormatText, out: os.Stderr}
+
+// SetFormat changes the format of the diagnostics reported from now on
+func SetFormat(format Format) {
+	reporter.Lock()
+	defer reporter.Unlock()
+	reporter.format = format
+}
+
+// IsStructured returns true if the diagnostics are reported in a machine-readable format
+func IsStructured() bool {
+	reporter.Lock()
+	defer reporter.Unlock()
+	return reporter.format != FormatText
+}
+
+// SetOutput changes the writer the diagnostics are reported to (os.Stderr by default)
+func SetOutput(out io.Writer) {
+	reporter.Lock()
+	defer reporter.Unlock()
+	reporter.out = out
+}
+
+// SetTool sets the tool information included in the SARIF output
+func SetTool(tool Tool) {
+	reporter.Lock()
+	defer reporter.Unlock()
+	reporter.tool = tool
+}
+
+// Report writes the given diagnostic in the configured format.
+// Progress information (see Info()) isn't part of the structured output, it's always printed to the standard output.
+func Report(d *Diagnostic) {
+	reporter.Lock()
+	defer reporter.Unlock()
+
+	if d.Severity == SeverityInfo {
+		fmt.Println(d.Message)
+		return
+	}
+
+	switch reporter.format {
+	case FormatJSON:
+		if data, err := json.Marshal(d); err == nil {
+			fmt.Fprintln(reporter.out, string(data))
+		} else {
+			fmt.Fprintln(reporter.out, d.String())
+		}
+	case FormatSARIF:
+		reporter.collected = append(reporter.collected, d)
+	default:
+		fmt.Fprintln(reporter.out, d.String())
+	}
+}
+
+// provider is implemented by errors consisting of multiple diagnostics
+type provider interface {
+	Diagnostics() []*Diagnostic
+}
+
+// PrintError reports an error which stops the current generator run.
+// In the text format, the error is printed to the standard output as is, otherwise it's reported as diagnostic(s).
+func PrintError(err error) {
+	if !IsStructured() {
+		fmt.Println(err)
+	} else if p, ok := err.(provider); ok {
+		for _, d := range p.Diagnostics() {
+			Report(d)
+		}
+	} else {
+		Report(FromError(err))
+	}
+}
+
+// Flush writes out the diagnostics collected so far, if the format requires it (i.e. SARIF)
+func Flush() error {
+	reporter.Lock()
+	defer reporter.Unlock()
+
+	if reporter.format != FormatSARIF {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(newSarifLog(reporter.tool, reporter.collected), "", "  ")
+	if err != nil {
+		return err
+	}
+	reporter.collected = nil
+	_, err = fmt.Fprintln(reporter.out, string(data))
+	return err
+}
+
+// SARIF 2.1.0 subset, see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func newSarifLog(tool Tool, diagnostics []*Diagnostic) *sarifLog {
+	var run = sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           tool.Name,
+			Version:        tool.Version,
+			InformationURI: tool.URI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	var rules = make(map[string]bool)
+	for _, d := range diagnostics {
+		rules[d.Code] = true
+
+		var result = sarifResult{
+			RuleId:  d.Code,
+			Level:   sarifLevel(d.Severity),
+			Message: sarifMessage{Text: d.Message},
+		}
+
+		if len(d.File) > 0 {
+			var location = sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepathToURI(d.File)},
+			}}
+			if d.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: d.Line, StartColumn: d.Column}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+
+		if len(d.Entity) > 0 || len(d.Property) > 0 {
+			result.Properties = make(map[string]interface{})
+			if len(d.Entity) > 0 {
+				result.Properties["entity"] = d.Entity
+			}
+			if len(d.Property) > 0 {
+				result.Properties["property"] = d.Property
+			}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	var ruleIds []string
+	for code := range rules {
+		ruleIds = append(ruleIds, code)
+	}
+	sort.Strings(ruleIds)
+	for _, code := range ruleIds {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{Id: code, ShortDescription: sarifMessage{Text: descriptions[code]}})
+	}
+
+	return &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
+
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// filepathToURI converts a file path to a relative URI reference, as expected by SARIF consumers
+func filepathToURI(path string) string {
+	var uri = url.URL{Path: filepath.ToSlash(path)}
+	if filepath.IsAbs(path) {
+		uri.Scheme = "file"
+	}
+	return uri.String()
+}
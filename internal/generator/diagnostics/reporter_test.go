@@ -0,0 +1,111 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package diagnostics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestFormats(t *testing.T) {
+	var out bytes.Buffer
+	SetOutput(&out)
+	defer func() {
+		SetFormat(FormatText)
+		SetOutput(os.Stderr)
+	}()
+
+	var notice = Notice(CodeTimePrecision, "time.Time precision").InEntity("Task").InProperty("Due")
+	notice.File = "task.go"
+	notice.Line = 12
+	notice.Column = 2
+
+	SetFormat(FormatText)
+	Report(notice)
+	assert.Eq(t, "task.go:12:2: notice[OBX1001]: time.Time precision\n", out.String())
+
+	out.Reset()
+	SetFormat(FormatJSON)
+	Report(notice)
+	PrintError(fmt.Errorf("plain error"))
+	assert.Eq(t, `{"severity":"notice","code":"OBX1001","message":"time.Time precision","file":"task.go","line":12,"column":2,"entity":"Task","property":"Due"}`+"\n"+
+		`{"severity":"error","code":"OBX0001","message":"plain error"}`+"\n", out.String())
+
+	out.Reset()
+	SetFormat(FormatSARIF)
+	SetTool(Tool{Name: "test", Version: "1.0"})
+	Report(notice)
+	assert.Eq(t, "", out.String())
+	assert.NoErr(t, Flush())
+
+	var sarif sarifLog
+	assert.NoErr(t, json.Unmarshal(out.Bytes(), &sarif))
+	assert.Eq(t, "2.1.0", sarif.Version)
+	assert.Eq(t, 1, len(sarif.Runs))
+	assert.Eq(t, "test", sarif.Runs[0].Tool.Driver.Name)
+	assert.Eq(t, []sarifRule{{Id: CodeTimePrecision, ShortDescription: sarifMessage{Text: descriptions[CodeTimePrecision]}}}, sarif.Runs[0].Tool.Driver.Rules)
+	assert.Eq(t, 1, len(sarif.Runs[0].Results))
+	assert.Eq(t, "note", sarif.Runs[0].Results[0].Level)
+	assert.Eq(t, "task.go", sarif.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Eq(t, 12, sarif.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+}
+
+func TestFromError(t *testing.T) {
+	var d = Warning(CodePropertyReset, "reset")
+	assert.Eq(t, d, FromError(fmt.Errorf("wrapped: %w", d)))
+	assert.Eq(t, CodeError, FromError(errors.New("other")).Code)
+}
+
+func TestDescriptions(t *testing.T) {
+	// collect all the declared codes from the source so a new code can't be added without a description
+	f, err := parser.ParseFile(token.NewFileSet(), "diagnostics.go", nil, 0)
+	assert.NoErr(t, err)
+
+	var count = 0
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.CONST {
+			for _, spec := range gen.Specs {
+				var value = spec.(*ast.ValueSpec)
+				if !strings.HasPrefix(value.Names[0].Name, "Code") {
+					continue
+				}
+				code, err := strconv.Unquote(value.Values[0].(*ast.BasicLit).Value)
+				assert.NoErr(t, err)
+				if len(descriptions[code]) == 0 {
+					t.Errorf("code %s (%s) has no description", value.Names[0].Name, code)
+				}
+				count++
+			}
+		}
+	}
+	assert.Eq(t, len(descriptions), count)
+}
@@ -0,0 +1,196 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+)
+
+// modelGroup is a model JSON file and the sources it's generated from, see Options.ModelDiscovery
+type modelGroup struct {
+	modelInfoFile string
+	sources       []string
+	modelInfo     *model.ModelInfo
+	created       bool // the model JSON file didn't exist before
+}
+
+// discoverModels assigns each source file in options.InPath to the model JSON file in its directory or in the closest
+// parent directory having one, up to the input root (see Options.InputRoot) so that a model outside of the project is
+// never used. A source without any model JSON file gets a new one in its own directory.
+// The groups are sorted by the model JSON file path, the sources keep the order of pathForEach().
+func discoverModels(options Options) ([]*modelGroup, error) {
+	var fsys = options.FileSystem()
+	var groups = make(map[string]*modelGroup)
+	var found = make(map[string]string) // model JSON file by the source directory
+	var root = filepath.Clean(options.InputRoot())
+
+	var findModel = func(sourceDir string) string {
+		if modelInfoFile, cached := found[sourceDir]; cached {
+			return modelInfoFile
+		}
+		var modelInfoFile = ModelInfoFile(sourceDir)
+		for dir := sourceDir; ; {
+			if _, err := fsys.Stat(ModelInfoFile(dir)); err == nil {
+				modelInfoFile = ModelInfoFile(dir)
+				break
+			}
+			var parent = filepath.Dir(dir)
+			if dir == root || parent == dir {
+				break
+			}
+			dir = parent
+		}
+		found[sourceDir] = modelInfoFile
+		return modelInfoFile
+	}
+
+	err := forEachSource(options, func(filePath string) error {
+		var modelInfoFile = findModel(filepath.Dir(filePath))
+		var group = groups[modelInfoFile]
+		if group == nil {
+			group = &modelGroup{modelInfoFile: modelInfoFile}
+			groups[modelInfoFile] = group
+		}
+		group.sources = append(group.sources, filePath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result = make([]*modelGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].modelInfoFile < result[j].modelInfoFile
+	})
+	return result, nil
+}
+
+// runDiscovered generates the bindings with a separate model for each group of sources, see Options.ModelDiscovery.
+// All the models are locked for the whole run and all the files are written as a unit, i.e. if generating any of the
+// models fails, none of them is changed.
+func runDiscovered(options Options, cleanPath string) (result *Result, err error) {
+	groups, err := discoverModels(options)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no source files found in %s", options.InPath)
+	}
+
+	defer func() {
+		for _, group := range groups {
+			if group.modelInfo == nil {
+				continue
+			}
+			closeModel(options, group.modelInfo)
+
+			// don't leave behind an empty model file if nothing was generated
+			if err != nil && group.created {
+				options.FileSystem().Remove(group.modelInfoFile)
+			}
+		}
+	}()
+
+	// lock all the models first, always in the same order, so that concurrent runs can't deadlock
+	for _, group := range groups {
+		_, statErr := options.FileSystem().Stat(group.modelInfoFile)
+		group.created = os.IsNotExist(statErr) && !options.readOnly()
+
+		var groupOptions = options
+		groupOptions.ModelInfoFile = group.modelInfoFile
+		if group.modelInfo, err = openModel(groupOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	// the sources are pinned once for all the models, see addPinnedSources()
+	if options.PinUids {
+		options.pinned = newOutputState(options.FileSystem())
+	}
+
+	var errs diagnostics.List
+	var inputs = make(map[string]bool)
+	var generatedBy = make(map[string]string) // the model JSON file by the cleaned path of each generated file
+	result = &Result{fsys: options.FileSystem(), report: options.Report}
+	for _, group := range groups {
+		var groupOptions = options
+		groupOptions.ModelInfoFile = group.modelInfoFile
+		groupOptions.sourceFiles = group.sources
+		groupOptions.modelCreated = group.created
+
+		groupResult, err := generateModel(groupOptions, group.modelInfo)
+		if err != nil {
+			if options.FailFast {
+				return nil, err
+			}
+			errs.Add(err)
+			continue
+		}
+
+		for _, file := range groupResult.Files {
+			var key = filepath.Clean(file.Path)
+			if other, exists := generatedBy[key]; exists {
+				err = fmt.Errorf("output path collision: %s would be generated for both %s and %s", file.Path, other, group.modelInfoFile)
+				break
+			}
+			generatedBy[key] = group.modelInfoFile
+		}
+		if err != nil {
+			if options.FailFast {
+				return nil, err
+			}
+			errs.Add(err)
+			continue
+		}
+
+		result.Files = append(result.Files, groupResult.Files...)
+		result.Changes = append(result.Changes, groupResult.Changes...)
+		result.caches = append(result.caches, groupResult.caches...)
+		for _, input := range groupResult.Inputs {
+			inputs[input] = true
+		}
+	}
+	if err = errs.Err(); err != nil {
+		return nil, err
+	}
+	if options.pinned != nil {
+		result.Sources = options.pinned.files
+	}
+
+	for input := range inputs {
+		result.Inputs = append(result.Inputs, input)
+	}
+	sort.Strings(result.Inputs)
+
+	if err = finish(options, result, cleanPath); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,164 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// IgnoreFileName is the file listing the paths to skip when looking for sources in its directory and subdirectories.
+// Each line is a pattern like in Options.Exclude, relative to the directory of the file; empty lines and lines starting
+// with a "#" are skipped.
+const IgnoreFileName = ".objectboxignore"
+
+// isSkippedDir returns true for the directories never recursed into when looking for sources or generated files
+func isSkippedDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "vendor"
+}
+
+// forEachSource executes the given function for each source file in options.InPath, see Options.Include,
+// Options.Exclude and IgnoreFileName. A single source file given as the InPath is never filtered out.
+func forEachSource(options Options, fn func(filePath string) error) error {
+	var parser = options.CodeGenerators[0]
+	var filter *sourceFilter
+	if pathIsDirOrPattern(options.FileSystem(), options.InPath) {
+		filter = newSourceFilter(options)
+	}
+
+	return pathForEach(options.FileSystem(), options.InPath, func(filePath string) error {
+		if !parser.IsSourceFile(filePath) || (filter != nil && !filter.accepts(filePath)) {
+			return nil
+		}
+		return fn(filePath)
+	})
+}
+
+// sourceFilter decides which of the files found in the input path are used as sources
+type sourceFilter struct {
+	fsys    vfs.FS
+	root    string
+	include []string
+	exclude []string
+	ignores map[string][]string // the patterns of the ignore file by its directory, nil if there's none
+}
+
+func newSourceFilter(options Options) *sourceFilter {
+	return &sourceFilter{
+		fsys:    options.FileSystem(),
+		root:    options.InputRoot(),
+		include: options.Include,
+		exclude: options.Exclude,
+		ignores: make(map[string][]string),
+	}
+}
+
+// accepts returns true if the file should be used as a source
+func (filter *sourceFilter) accepts(file string) bool {
+	var rel = relativePath(filter.root, file)
+
+	if len(filter.include) > 0 && !matchAny(filter.include, rel) {
+		return false
+	}
+	if matchAny(filter.exclude, rel) {
+		return false
+	}
+
+	// apply the ignore files from the input root down to the directory of the file
+	var dir = filter.root
+	var elements = strings.Split(path.Dir(rel), "/")
+	for i := 0; ; i++ {
+		if patterns := filter.ignoreFile(dir); len(patterns) > 0 && matchAny(patterns, relativePath(dir, file)) {
+			return false
+		}
+		if i == len(elements) || elements[i] == "." || elements[i] == ".." {
+			return true
+		}
+		dir = filepath.Join(dir, elements[i])
+	}
+}
+
+// ignoreFile returns the patterns of the ignore file in the given directory, if any
+func (filter *sourceFilter) ignoreFile(dir string) []string {
+	if patterns, loaded := filter.ignores[dir]; loaded {
+		return patterns
+	}
+
+	var patterns []string
+	if data, err := filter.fsys.ReadFile(filepath.Join(dir, IgnoreFileName)); err == nil {
+		var scanner = bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			var line = strings.TrimSpace(scanner.Text())
+			if len(line) > 0 && !strings.HasPrefix(line, "#") {
+				patterns = append(patterns, line)
+			}
+		}
+	}
+	filter.ignores[dir] = patterns
+	return patterns
+}
+
+// relativePath returns the slash-separated path of the file relative to the given directory, or the whole path if the
+// file isn't inside of it
+func relativePath(dir, file string) string {
+	if rel, err := filepath.Rel(dir, file); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(file)
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern checks whether the slash-separated relative path matches the glob pattern, see Options.Exclude
+func matchPattern(pattern, rel string) bool {
+	pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+
+	// a pattern without a slash matches the name of the file or any of its parent directories
+	if !strings.Contains(pattern, "/") {
+		for _, element := range strings.Split(rel, "/") {
+			if matched, _ := path.Match(pattern, element); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	// otherwise, it matches the whole relative path of the file or any of its parent directories
+	pattern = strings.TrimPrefix(pattern, "/")
+	for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -35,11 +35,13 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"unsafe"
 
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/flatbuffersc/reflection"
 )
 
+// ParseSchemaFile parses a FlatBuffers schema file, including the files it includes, from the disk
 func ParseSchemaFile(filename string) (*reflection.Schema, error) {
 	var cFilename = C.CString(filename)
 	defer C.free(unsafe.Pointer(cFilename))
@@ -62,6 +64,50 @@ func ParseSchemaFile(filename string) (*reflection.Schema, error) {
 	return reflection.GetRootAsSchema(bytes, 0), nil
 }
 
+// ParseSchema parses a FlatBuffers schema from memory. The files map must contain the schema file itself and all the
+// files it includes, keyed by their paths; includes are resolved relative to the directory of the including file.
+func ParseSchema(filename string, files map[string][]byte) (*reflection.Schema, error) {
+	if _, exists := files[filename]; !exists {
+		return nil, fmt.Errorf("unable to load file: %s", filename)
+	}
+
+	// the schema itself comes first, followed by the others in a stable order
+	var names = []string{filename}
+	for name := range files {
+		if name != filename {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+
+	var contents = make([]string, len(names))
+	for i, name := range names {
+		contents[i] = string(files[name])
+	}
+
+	var cNames = goStringArrayToC(names)
+	defer cNames.free()
+	var cContents = goStringArrayToC(contents)
+	defer cContents.free()
+
+	var cErr *C.char = nil
+	defer func() { C.fbs_error_free(cErr) }()
+
+	var fbsBytes *C.FBS_bytes = C.fbs_schema_parse(cNames.cArray, cContents.cArray, C.size_t(len(names)), &cErr)
+	if fbsBytes == nil {
+		if cErr == nil {
+			return nil, errors.New("unknown error")
+		}
+		return nil, errors.New(C.GoString(cErr))
+	}
+	defer C.fbs_schema_free(fbsBytes)
+
+	// make a copy of the bytes because the source is deallocated by fbs_schema_free
+	var bytes []byte = C.GoBytes(fbsBytes.data, C.int(fbsBytes.size))
+
+	return reflection.GetRootAsSchema(bytes, 0), nil
+}
+
 // ExecuteFlatc runs flatc with the given arguments and returns its exit code and error, if any
 func ExecuteFlatc(args []string) (int, error) {
 	var cErr *C.char = nil
@@ -105,6 +105,26 @@ func TestFbsSchemaParser(t *testing.T) {
 	assert.Eq(t, "All worldly belongings of this being", strings.TrimSpace(string(field.Documentation(0))))
 }
 
+func TestFbsSchemaParserMemory(t *testing.T) {
+	var files = map[string][]byte{
+		"schema/main.fbs":       []byte(`include "types/item.fbs"; table Main { item: Item; }`),
+		"schema/types/item.fbs": []byte(`table Item { name: string; }`),
+	}
+
+	schema, err := ParseSchema("schema/main.fbs", files)
+	assert.NoErr(t, err)
+	assert.Eq(t, 2, schema.ObjectsLength())
+
+	// files not given are not looked up on the disk
+	delete(files, "schema/types/item.fbs")
+	schema, err = ParseSchema("schema/main.fbs", files)
+	assert.True(t, schema == nil)
+	assert.Err(t, err)
+
+	_, err = ParseSchema("missing.fbs", files)
+	assert.Err(t, err)
+}
+
 func TestFbsFlatc(t *testing.T) {
 	code, err := ExecuteFlatc([]string{"invalid", "arguments"})
 	assert.True(t, code != 0)
@@ -22,16 +22,20 @@
 package generator
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
 )
 
 // Version specifies the current generator version.
@@ -68,7 +72,7 @@ type CodeGenerator interface {
 	IsSourceFile(file string) bool
 
 	// ParseSource reads the input file and creates a model representation
-	ParseSource(sourceFile string) (*model.ModelInfo, error)
+	ParseSource(sourceFile string, options Options) (*model.ModelInfo, error)
 
 	// WriteBindingFiles generates and writes binding source code files
 	WriteBindingFiles(sourceFile string, options Options, mergedModel *model.ModelInfo) error
@@ -77,53 +81,113 @@ type CodeGenerator interface {
 	WriteModelBindingFile(options Options, mergedModel *model.ModelInfo) error
 }
 
-// WriteFile writes data to targetFile, while using permissions either from the targetFile or permSource
+// UidPinner can be implemented by a CodeGenerator to support Options.PinUids
+type UidPinner interface {
+	// PinUids returns the new contents of the source file, or of the files it includes, so that each entity, property
+	// and standalone relation of the given model, as declared in the source, carries its UID as an annotation.
+	// Only the files which need to change are returned, by their paths.
+	PinUids(sourceFile string, options Options, mergedModel *model.ModelInfo) (map[string][]byte, error)
+}
+
+// ModelChecker can be implemented by a CodeGenerator to validate the complete model, after all the sources have been
+// merged into it and before any bindings are generated
+type ModelChecker interface {
+	CheckModel(mergedModel *model.ModelInfo) error
+}
+
+// WriteFile writes data to targetFile, while using permissions either from the targetFile or permSource.
+// The data is written to a temporary file first, which then replaces the targetFile.
 func WriteFile(file string, data []byte, permSource string) error {
+	return writeFile(vfs.OS, file, data, permSource)
+}
+
+func writeFile(fsys vfs.FS, file string, data []byte, permSource string) error {
 	var perm os.FileMode
 	// copy permissions either from the existing file or from the source file
-	if info, _ := os.Stat(file); info != nil {
+	if info, _ := fsys.Stat(file); info != nil {
 		perm = info.Mode()
-	} else if info, err := os.Stat(permSource); info != nil {
+	} else if info, err := fsys.Stat(permSource); info != nil {
 		perm = info.Mode()
 	} else {
 		return err
 	}
 
-	return ioutil.WriteFile(file, data, perm)
+	return vfs.WriteFileAtomic(fsys, file, data, perm)
 }
 
 // Process is the main API method of the package
-// it takes source file & model-information file paths and generates bindings (as a sibling file to the source file)
+// it takes source file & model-information file paths and generates bindings (as a sibling file to the source file).
+// Nothing is written unless all the sources are processed successfully.
 func Process(options Options) error {
-	var err error
+	_, err := Run(options)
+	return err
+}
 
-	// Ensure output directory is existing or create
-	if len(options.OutPath) != 0 {
-		err := os.MkdirAll(options.OutPath, 0750)
-		if err != nil {
-			return fmt.Errorf("can't create output path '"+options.OutPath+"': %s", err)
-		}
+// Run works like Process and additionally returns the generated files and the model changes
+func Run(options Options) (*Result, error) {
+	if err := prepare(&options); err != nil {
+		return nil, err
 	}
 
-	// Ensure output header directory is existing or create
-	if len(options.OutHeadersPath) != 0 {
-		err := os.MkdirAll(options.OutHeadersPath, 0750)
-		if err != nil {
-			return fmt.Errorf("can't create output headers path '"+options.OutPath+"': %s", err)
+	cleanPath, err := implicitClean(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.ModelDiscovery {
+		return runDiscovered(options, cleanPath)
+	}
+
+	_, statErr := options.FileSystem().Stat(options.ModelInfoFile)
+	options.modelCreated = os.IsNotExist(statErr) && !options.readOnly()
+
+	modelInfo, err := openModel(options)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := processModel(options, modelInfo, cleanPath)
+	closeModel(options, modelInfo)
+
+	// don't leave behind an empty model file if nothing was generated
+	if err != nil && options.modelCreated {
+		options.FileSystem().Remove(options.ModelInfoFile)
+	}
+	return result, err
+}
+
+// prepare validates the options, fills in the defaults and creates the output directories
+func prepare(options *Options) error {
+	if len(options.CodeGenerators) == 0 {
+		return errors.New("no code generator specified")
+	}
+
+	if options.ModelDiscovery {
+		if len(options.ModelInfoFile) != 0 {
+			return errors.New("model discovery can't be used together with an explicit model-info file")
+		}
+		if (len(options.OutPath) != 0 || len(options.OutHeadersPath) != 0) && !options.PreserveDirs {
+			return errors.New("model discovery can only be used together with an output path if preserving directories")
 		}
 	}
 
-	if PathIsDirOrPattern(options.InPath) {
-		var additional string
-		var cleanPath = options.InPath
+	if options.Check {
+		options.check = newCheckState(options.FileSystem())
+	} else {
+		// Ensure output directory is existing or create
 		if len(options.OutPath) != 0 {
-			additional = "of output path (-out=" + options.OutPath + ") "
-			cleanPath = options.OutPath
+			err := options.FileSystem().MkdirAll(options.OutPath, 0750)
+			if err != nil {
+				return fmt.Errorf("can't create output path '"+options.OutPath+"': %s", err)
+			}
 		}
-		fmt.Printf("Requested to generate for directory/pattern %s, performing an implicit cleanup %sfirst\n", options.InPath, additional)
-		err = Clean(options.CodeGenerator, cleanPath)
-		if err != nil {
-			return err
+
+		// Ensure output header directory is existing or create
+		if len(options.OutHeadersPath) != 0 {
+			err := options.FileSystem().MkdirAll(options.OutHeadersPath, 0750)
+			if err != nil {
+				return fmt.Errorf("can't create output headers path '"+options.OutPath+"': %s", err)
+			}
 		}
 	}
 
@@ -132,82 +196,560 @@ func Process(options Options) error {
 		options.Rand = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
 	}
 
-	if len(options.ModelInfoFile) == 0 {
+	if len(options.ModelInfoFile) == 0 && !options.ModelDiscovery {
 		options.ModelInfoFile = ModelInfoFile(filepath.Dir(options.InPath))
 	}
 
+	return nil
+}
+
+// implicitClean determines the path to clean up when generating for a directory or a pattern, i.e. where to remove
+// previously generated files which are not generated anymore. The files are removed after a successful generation.
+// Returns the cleaned path or an empty string when generating for a single file.
+func implicitClean(options Options) (string, error) {
+	if !pathIsDirOrPattern(options.FileSystem(), options.InPath) {
+		return "", nil
+	}
+
+	var additional string
+	var cleanPath = options.InPath
+	if len(options.OutPath) != 0 {
+		additional = "of output path (-out=" + options.OutPath + ") "
+		cleanPath = options.OutPath
+		if options.PreserveDirs && strings.HasSuffix(options.InPath, recursionSuffix) {
+			cleanPath = options.OutPath + recursionSuffix
+		}
+	}
+
+	// in the "check" mode, previously generated files are only reported as stale after the generation
+	if !options.readOnly() {
+		options.Report(diagnostics.Info(fmt.Sprintf("Requested to generate for directory/pattern %s, performing an implicit cleanup %sof previously generated files", options.InPath, additional)))
+	}
+	return cleanPath, nil
+}
+
+// loadModel opens the model-information file; read-only in the "check" and "in-memory" modes
+func loadModel(options Options) (*model.ModelInfo, error) {
 	var modelInfo *model.ModelInfo
+	var err error
 
-	modelInfo, err = model.LoadOrCreateModel(options.ModelInfoFile)
+	if options.readOnly() {
+		modelInfo, err = model.ReadModelFS(options.FileSystem(), options.ModelInfoFile)
+	} else {
+		modelInfo, err = model.LoadOrCreateModelFS(options.FileSystem(), options.ModelInfoFile, options.lockTimeout())
+	}
 	if err != nil {
-		return fmt.Errorf("can't init ModelInfo: %s", err)
+		return nil, fmt.Errorf("can't init ModelInfo: %s", err)
 	}
 
 	modelInfo.Rand = options.Rand
-	defer modelInfo.Close()
+	return modelInfo, nil
+}
+
+// openModel loads the model, or reloads the one kept open by Watch()
+func openModel(options Options) (*model.ModelInfo, error) {
+	if options.models != nil {
+		return options.models.load(options)
+	}
+	return loadModel(options)
+}
+
+// closeModel closes the model unless it's kept open by Watch()
+func closeModel(options Options, modelInfo *model.ModelInfo) {
+	if options.models == nil {
+		modelInfo.Close()
+	}
+}
+
+// processModel generates the bindings for options.InPath and updates the given model
+func processModel(options Options, modelInfo *model.ModelInfo, cleanPath string) (*Result, error) {
+	result, err := generateModel(options, modelInfo)
+	if err != nil {
+		return nil, err
+	}
+	return result, finish(options, result, cleanPath)
+}
+
+// generateModel parses the sources, updates the given model and generates the bindings, without writing anything
+func generateModel(options Options, modelInfo *model.ModelInfo) (*Result, error) {
+	var err error
 
 	if err = modelInfo.Validate(); err != nil {
-		return fmt.Errorf("invalid ModelInfo loaded: %s", err)
+		return nil, fmt.Errorf("invalid ModelInfo loaded: %s", err)
+	}
+
+	previousModel, err := modelInfo.Clone()
+	if err != nil {
+		return nil, err
 	}
 
 	// if the model is valid, upgrade it to the latest version
 	modelInfo.MinimumParserVersion = model.ModelVersion
 	modelInfo.ModelVersion = model.ModelVersion
 
+	options.output = newOutputState(options.FileSystem())
+	options.inputs = newInputState()
+	options.inputs.add(options.ModelInfoFile, "")
+	if options.PinUids {
+		// with ModelDiscovery, the pinned sources are shared by all the models, see runDiscovered()
+		if options.pinned == nil {
+			options.pinned = newOutputState(options.FileSystem())
+		}
+	} else if options.Cache && !options.readOnly() {
+		options.cache = newCacheState(options, modelInfo)
+	}
+
 	if err = createBinding(options, modelInfo); err != nil {
-		return err
+		return nil, err
 	}
 
 	if err = createModel(options, modelInfo); err != nil {
+		return nil, err
+	}
+
+	if err = options.canceled(); err != nil {
+		return nil, err
+	}
+
+	var changes = model.Diff(previousModel, modelInfo)
+	if !options.AllowDestructive {
+		if err = checkDestructiveChanges(modelInfo, changes); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := options.output.result(options, modelInfo)
+	if err != nil {
+		return nil, err
+	}
+	result.Changes = changes
+	if options.pinned != nil {
+		result.Sources = options.pinned.files
+	}
+	result.Inputs = options.inputs.list(options)
+	if options.cache != nil {
+		result.caches = append(result.caches, options.cache)
+	}
+	return result, nil
+}
+
+// finish lists the previously generated files in cleanPath (if not empty) which were not generated during this run.
+// Then, it either reports the differences in the "check" mode, or writes the result, unless running InMemory.
+func finish(options Options, result *Result, cleanPath string) error {
+	if len(cleanPath) != 0 {
+		if err := result.listRemoved(options, cleanPath); err != nil {
+			return err
+		}
+	}
+
+	if options.check != nil {
+		if len(cleanPath) != 0 {
+			if err := checkStaleFiles(options, cleanPath); err != nil {
+				return err
+			}
+		}
+		return options.check.result()
+	}
+
+	if options.InMemory {
+		return nil
+	}
+
+	if err := result.Write(); err != nil {
 		return err
 	}
+	for _, source := range result.Sources {
+		options.Report(diagnostics.Info(fmt.Sprintf("Pinned the UIDs in %s", source.Path)))
+	}
+	for _, cache := range result.caches {
+		if err := cache.save(options.FileSystem()); err != nil {
+			return err
+		}
+	}
+	return writeBuildFiles(options, result)
+}
 
-	return nil
+// checkStaleFiles reports previously generated files in the given path which wouldn't be generated anymore
+func checkStaleFiles(options Options, path string) error {
+	return pathForEach(options.FileSystem(), path, func(filePath string) error {
+		for _, codeGenerator := range options.CodeGenerators {
+			if codeGenerator.IsGeneratedFile(filePath) {
+				options.check.stale(filePath)
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// sourceState holds the intermediate results of processing a single source file
+type sourceState struct {
+	file     string
+	cached   *cachedSource     // set if the source doesn't need to be processed again, see Options.Cache
+	parsed   *model.ModelInfo  // the model read from the source
+	inputs   *inputState       // the files read while parsing the source
+	entities []*model.Entity   // the entities of the source in the stored model
+	output   *outputState      // the files generated for the source
+	pinned   map[string][]byte // the sources rewritten with the UIDs pinned, see Options.PinUids
+	err      error
 }
 
+// createBinding parses all the sources and merges them into the stored model, then generates their bindings.
+// Parsing and generating run concurrently (see Options.Workers), while merging is done one source after another, in the
+// order of pathForEach(), so the IDs and UIDs are assigned exactly like when processing the sources sequentially.
+// Unless running with Options.FailFast, errors are collected and the remaining sources are still processed.
 func createBinding(options Options, storedModel *model.ModelInfo) error {
-	return pathForEach(options.InPath, func(filePath string) error {
-		if !options.CodeGenerator.IsSourceFile(filePath) {
+	var sources []*sourceState
+	if options.sourceFiles != nil {
+		for _, filePath := range options.sourceFiles {
+			sources = append(sources, &sourceState{file: filePath})
+		}
+	} else {
+		err := forEachSource(options, func(filePath string) error {
+			sources = append(sources, &sourceState{file: filePath})
 			return nil
+		})
+		if err != nil {
+			return err
 		}
+	}
 
-		// clear meta information from the previous createBinding() call (when processing multiple files at once)
-		for _, entity := range storedModel.EntitiesWithMeta() {
-			entity.Meta = nil
+	forEachConcurrently(options.workerCount(), len(sources), func(i int) bool {
+		var source = sources[i]
+		if source.err = options.canceled(); source.err == nil {
+			if options.cache != nil {
+				source.cached, source.output = options.cache.lookup(options, storedModel, source.file)
+			}
+			if source.cached == nil {
+				source.parsed, source.inputs, source.err = parseSource(options, source.file)
+			}
 		}
+		return source.err == nil || !options.FailFast
+	})
 
-		currentModel, err := options.CodeGenerator.ParseSource(filePath)
-		if err != nil {
+	// with FailFast, only the sources before the first error (in the order given) are processed
+	var count = len(sources)
+	for i := 0; i < count; i++ {
+		if err := options.canceled(); err != nil {
 			return err
 		}
 
-		if err = mergeBindingWithModelInfo(currentModel, storedModel); err != nil {
-			return fmt.Errorf("can't merge model information: %s", err)
+		var source = sources[i]
+		if source.err == nil {
+			if source.cached != nil {
+				source.entities, source.err = options.cache.restore(storedModel, source.file, source.cached)
+			} else {
+				if options.PinUids {
+					acceptUidRequests(source.parsed)
+				}
+				source.entities, source.err = mergeSource(options, source.parsed, storedModel)
+			}
+		}
+		if source.err != nil && options.FailFast {
+			count = i + 1
+		}
+	}
+
+	count, err := linkSources(options, storedModel, sources[0:count])
+	if err != nil {
+		return err
+	}
+
+	forEachConcurrently(options.workerCount(), count, func(i int) bool {
+		var source = sources[i]
+		if source.err == nil && source.cached == nil {
+			source.output, source.err = writeSourceBindings(options, storedModel, source.file, source.entities)
+		}
+		if source.err == nil && options.pinned != nil {
+			source.pinned, source.err = pinSourceUids(options, storedModel, source.file, source.entities)
 		}
+		return source.err == nil || !options.FailFast
+	})
 
-		if err = storedModel.Finalize(); err != nil {
-			return fmt.Errorf("model finalization failed: %s", err)
+	var errs diagnostics.List
+	var generatedBy = make(map[string]string) // the source file by the cleaned path of each generated file
+	for _, source := range sources[0:count] {
+		if source.err == nil {
+			source.err = checkCollisions(generatedBy, source.file, source.output)
+		}
+		if source.err != nil {
+			if options.FailFast {
+				return source.err
+			}
+			errs.Add(source.err)
+			continue
 		}
 
-		if err = options.CodeGenerator.WriteBindingFiles(filePath, options, storedModel); err != nil {
+		if options.output != nil {
+			options.output.addAll(source.output)
+		}
+		if err := addPinnedSources(options, source.pinned); err != nil {
 			return err
 		}
+		if options.inputs != nil {
+			if source.cached != nil {
+				for file, hash := range source.cached.Inputs {
+					options.inputs.add(file, hash)
+				}
+			} else {
+				options.inputs.addAll(source.inputs)
+			}
+		}
+		if options.cache != nil && source.cached == nil {
+			options.cache.record(source.file, source.inputs.hashes(options), source.entities, source.output)
+		}
+	}
+	return errs.Err()
+}
+
+// checkCollisions fails if any of the files generated for the given source have already been generated for another one,
+// e.g. for sources with the same name in different directories when generating into a single output directory
+func checkCollisions(generatedBy map[string]string, sourceFile string, output *outputState) error {
+	for _, file := range output.files {
+		var key = filepath.Clean(file.Path)
+		if other, exists := generatedBy[key]; exists && other != sourceFile {
+			return fmt.Errorf("output path collision: %s would be generated for both %s and %s", file.Path, other, sourceFile)
+		}
+		generatedBy[key] = sourceFile
+	}
+	return nil
+}
+
+// parseSource reads a single source file, recording the files read as the inputs of the generated files
+func parseSource(options Options, filePath string) (*model.ModelInfo, *inputState, error) {
+	for _, codeGenerator := range options.CodeGenerators[1:] {
+		if !codeGenerator.IsSourceFile(filePath) {
+			return nil, nil, fmt.Errorf("%s is not a source file for all the selected code generators", filePath)
+		}
+	}
+
+	var inputs = newInputState()
+	options.FS = inputs.recorder(options.FileSystem())
+
+	// the first generator parses the sources, the model is then shared by all of them
+	parsed, err := options.CodeGenerators[0].ParseSource(filePath, options)
+	return parsed, inputs, err
+}
+
+// mergeSource merges the model of a single source file into the stored model and returns the stored entities.
+// The standalone relation targets are set later by linkSources(), after all the sources have been merged.
+func mergeSource(options Options, currentModel *model.ModelInfo, storedModel *model.ModelInfo) ([]*model.Entity, error) {
+	if err := mergeBindingWithModelInfo(currentModel, storedModel, options.Report); err != nil {
+		return nil, fmt.Errorf("can't merge model information: %s", err)
+	}
+
+	if err := storedModel.Finalize(); err != nil {
+		return nil, fmt.Errorf("model finalization failed: %s", err)
+	}
+
+	// the merge has updated the IDs of the current model to the stored ones
+	var entities = make([]*model.Entity, 0, len(currentModel.Entities))
+	for _, currentEntity := range currentModel.Entities {
+		entity, err := findStoredEntity(currentEntity, storedModel)
+		if err != nil {
+			return nil, err
+		}
+		entity.CurrentlyPresent = true
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
 
-		for _, entity := range storedModel.EntitiesWithMeta() {
-			entity.CurrentlyPresent = true
+// linkSources completes the stored model after all the sources have been merged into it, so that relations can point
+// to entities declared in any of the sources: it sets the standalone relation targets and lets the code generators
+// check the complete model, see ModelChecker. Returns the number of sources to continue with, see createBinding().
+func linkSources(options Options, storedModel *model.ModelInfo, sources []*sourceState) (int, error) {
+	var failed bool
+	for i, source := range sources {
+		if source.err == nil && source.parsed != nil {
+			if err := mergeRelationTargets(source.parsed, storedModel); err != nil {
+				source.err = fmt.Errorf("can't merge model information: %s", err)
+			}
+		}
+		if source.err != nil {
+			if options.FailFast {
+				return i + 1, nil
+			}
+			failed = true
 		}
+	}
+
+	// the model is incomplete, the errors of the sources are reported by createBinding()
+	if failed {
+		return len(sources), nil
+	}
 
+	if err := storedModel.Finalize(); err != nil {
+		return 0, fmt.Errorf("model finalization failed: %s", err)
+	}
+
+	if err := parseRelationTargets(options, storedModel, sources); err != nil {
+		return 0, err
+	}
+
+	for _, codeGenerator := range options.CodeGenerators {
+		if checker, isChecker := codeGenerator.(ModelChecker); isChecker {
+			if err := checker.CheckModel(storedModel); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(sources), nil
+}
+
+// parseRelationTargets parses the sources restored from the cache which declare relation targets of the sources being
+// generated: the code generators need the information only available after parsing (e.g. the namespace of the target).
+// Their own bindings are still restored from the cache.
+func parseRelationTargets(options Options, storedModel *model.ModelInfo, sources []*sourceState) error {
+	var cachedBy = make(map[*model.Entity]*sourceState)
+	for _, source := range sources {
+		if source.err == nil && source.cached != nil {
+			for _, entity := range source.entities {
+				cachedBy[entity] = source
+			}
+		}
+	}
+	if len(cachedBy) == 0 {
 		return nil
-	})
+	}
+
+	var needed = make(map[*sourceState]bool)
+	for _, source := range sources {
+		if source.err != nil || source.cached != nil {
+			continue
+		}
+		for _, entity := range source.entities {
+			for _, relation := range entity.Relations {
+				if target := cachedBy[relation.Target]; target != nil {
+					needed[target] = true
+				}
+			}
+			for _, property := range entity.Properties {
+				if len(property.RelationTarget) == 0 {
+					continue
+				}
+				if targetEntity, err := storedModel.FindEntityByName(property.RelationTarget); err == nil && cachedBy[targetEntity] != nil {
+					needed[cachedBy[targetEntity]] = true
+				}
+			}
+		}
+	}
+
+	// in the order of the sources, so the result doesn't depend on the map iteration order
+	for _, source := range sources {
+		if !needed[source] {
+			continue
+		}
+		parsed, _, err := parseSource(options, source.file)
+		if err == nil {
+			_, err = mergeSource(options, parsed, storedModel)
+		}
+		if err == nil {
+			err = mergeRelationTargets(parsed, storedModel)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSourceBindings generates the bindings of a single source file, consisting of the given entities.
+// The generated files are staged separately for each source, in order to be added to options.output deterministically.
+func writeSourceBindings(options Options, storedModel *model.ModelInfo, filePath string, entities []*model.Entity) (*outputState, error) {
+	options.output = newOutputState(options.FileSystem())
+
+	var fileModel = sourceModel(storedModel, entities)
+	for _, codeGenerator := range options.CodeGenerators {
+		if err := codeGenerator.WriteBindingFiles(filePath, options, fileModel); err != nil {
+			return nil, err
+		}
+	}
+	return options.output, nil
+}
+
+// pinSourceUids lets the parsing code generator rewrite the source so that its entities carry their UIDs
+func pinSourceUids(options Options, storedModel *model.ModelInfo, filePath string, entities []*model.Entity) (map[string][]byte, error) {
+	var codeGenerator = options.CodeGenerators[0]
+	pinner, isPinner := codeGenerator.(UidPinner)
+	if !isPinner {
+		return nil, fmt.Errorf("pinning UIDs is not supported for the source file %s", filePath)
+	}
+	return pinner.PinUids(filePath, options, sourceModel(storedModel, entities))
+}
+
+// acceptUidRequests makes the merge treat empty uid annotations like missing ones: instead of failing with the UID to
+// paste into the source, the existing element is kept (found by its name) and its UID is pinned, see Options.PinUids
+func acceptUidRequests(parsed *model.ModelInfo) {
+	for _, entity := range parsed.Entities {
+		entity.UidRequest = false
+		for _, property := range entity.Properties {
+			property.UidRequest = false
+		}
+		for _, relation := range entity.Relations {
+			relation.UidRequest = false
+		}
+	}
+}
+
+// addPinnedSources stages the rewritten sources to be written with the generated files, in the order of their paths.
+// In the "check" mode, the sources are compared instead, i.e. reported as not up-to-date if any UID is not pinned yet.
+// Each file is pinned only once per run, e.g. a schema file included by multiple sources.
+func addPinnedSources(options Options, pinned map[string][]byte) error {
+	var paths = make([]string, 0, len(pinned))
+	for path := range pinned {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if previous, exists := options.pinned.data(path); exists {
+			if !bytes.Equal(previous, pinned[path]) {
+				return fmt.Errorf("can't pin the UIDs in %s: it's used by sources with different UIDs for its declarations, e.g. of different models", path)
+			}
+			continue
+		}
+		options.pinned.add(path, pinned[path], path)
+		if options.check != nil {
+			if err := options.check.compare(path, pinned[path]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sourceModel returns a view of the stored model for generating the bindings of a single source file: the code
+// generators use model.EntitiesWithMeta() to find the entities to generate, thus the other entities are replaced by
+// their copies without Meta. The stored model itself is not changed, so multiple sources can be generated concurrently.
+func sourceModel(storedModel *model.ModelInfo, entities []*model.Entity) *model.ModelInfo {
+	var own = make(map[*model.Entity]bool, len(entities))
+	for _, entity := range entities {
+		own[entity] = true
+	}
+
+	var view = *storedModel
+	view.Entities = make([]*model.Entity, len(storedModel.Entities))
+	for i, entity := range storedModel.Entities {
+		if own[entity] {
+			view.Entities[i] = entity
+		} else {
+			var other = *entity
+			other.Meta = nil
+			view.Entities[i] = &other
+		}
+	}
+	return &view
 }
 
 func createModel(options Options, modelInfo *model.ModelInfo) error {
 	// clean entities not present in the current run - ONLY if running for a path
-	if PathIsDirOrPattern(options.InPath) {
+	if pathIsDirOrPattern(options.FileSystem(), options.InPath) {
 		removedEntities := make([]*model.Entity, 0)
 		for _, entity := range modelInfo.Entities {
 			if !entity.CurrentlyPresent {
-				fmt.Printf("Removing missing entity %s %s from the model\n", entity.Name, entity.Id)
+				options.Report(diagnostics.Notice(diagnostics.CodeEntityRemoved,
+					fmt.Sprintf("removing missing entity %s %s from the model", entity.Name, entity.Id)).InEntity(entity.Name))
 				removedEntities = append(removedEntities, entity)
 			}
 		}
@@ -223,22 +765,45 @@ func createModel(options Options, modelInfo *model.ModelInfo) error {
 		}
 	}
 
-	if err := modelInfo.Write(); err != nil {
-		return fmt.Errorf("can't write model-info file %s: %s", options.ModelInfoFile, err)
+	if options.check != nil {
+		if data, err := modelInfo.Marshal(); err != nil {
+			return fmt.Errorf("can't serialize model-info file %s: %s", options.ModelInfoFile, err)
+		} else if err = options.check.compare(options.ModelInfoFile, data); err != nil {
+			return err
+		}
+	}
+
+	var modelFiles = make(map[string]bool)
+	for _, codeGenerator := range options.CodeGenerators {
+		// some generators share the model file, e.g. C and C++ both use objectbox-model.h
+		var modelFile = codeGenerator.ModelFile(options.ModelInfoFile, options)
+		if modelFiles[modelFile] {
+			continue
+		}
+		modelFiles[modelFile] = true
+
+		if err := codeGenerator.WriteModelBindingFile(options, modelInfo); err != nil {
+			return err
+		}
 	}
 
-	return options.CodeGenerator.WriteModelBindingFile(options, modelInfo)
+	return nil
 }
 
 // Clean removes generated files in the given path.
 // Removes *.obx.* and objectbox-model.[go|h|...] but keeps objectbox-model.json
 func Clean(codeGenerator CodeGenerator, path string) error {
-	return pathForEach(path, func(filePath string) error {
+	return CleanFS(vfs.OS, codeGenerator, path)
+}
+
+// CleanFS works like Clean, on the given file system
+func CleanFS(fsys vfs.FS, codeGenerator CodeGenerator, path string) error {
+	return pathForEach(fsys, path, func(filePath string) error {
 		if !codeGenerator.IsGeneratedFile(filePath) {
 			return nil
 		}
-		fmt.Printf("Removing %s\n", filePath)
-		return os.Remove(filePath)
+		diagnostics.Report(diagnostics.Info(fmt.Sprintf("Removing %s", filePath)))
+		return fsys.Remove(filePath)
 	})
 }
 
@@ -246,6 +811,10 @@ const recursionSuffix = "/..."
 
 // PathIsDirOrPattern checks whether the given path is a path pattern, a directory or a single file.
 func PathIsDirOrPattern(path string) bool {
+	return pathIsDirOrPattern(vfs.OS, path)
+}
+
+func pathIsDirOrPattern(fsys vfs.FS, path string) bool {
 	// if it's a recursion pattern
 	if strings.HasSuffix(path, recursionSuffix) {
 		return true
@@ -257,7 +826,7 @@ func PathIsDirOrPattern(path string) bool {
 	}
 
 	// if it's a directory
-	if finfo, err := os.Stat(path); err == nil && finfo.IsDir() {
+	if finfo, err := fsys.Stat(path); err == nil && finfo.IsDir() {
 		return true
 	}
 
@@ -265,7 +834,7 @@ func PathIsDirOrPattern(path string) bool {
 }
 
 // pathForEach executes the given function for each file in the given directory/path pattern
-func pathForEach(path string, fn func(filePath string) error) error {
+func pathForEach(fsys vfs.FS, path string, fn func(filePath string) error) error {
 	var recursive bool
 
 	// if it's a pattern
@@ -274,24 +843,27 @@ func pathForEach(path string, fn func(filePath string) error) error {
 		path = path[0:len(path)-len(recursionSuffix)] + "/*"
 	} else {
 		// if it's a directory
-		if finfo, err := os.Stat(path); err == nil && finfo.IsDir() {
+		if finfo, err := fsys.Stat(path); err == nil && finfo.IsDir() {
 			path = path + "/*"
 		}
 	}
 
-	matches, err := filepath.Glob(path)
+	matches, err := vfs.Glob(fsys, path)
 	if err != nil {
 		return err
 	}
 
 	for _, subpath := range matches {
-		finfo, err := os.Stat(subpath)
+		finfo, err := fsys.Stat(subpath)
 		if err != nil {
 			return err
 		}
 
 		if recursive && finfo.Mode().IsDir() {
-			err = pathForEach(subpath+recursionSuffix, fn)
+			if isSkippedDir(finfo.Name()) {
+				continue
+			}
+			err = pathForEach(fsys, subpath+recursionSuffix, fn)
 		} else if finfo.Mode().IsRegular() {
 			err = fn(subpath)
 		}
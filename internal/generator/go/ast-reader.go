@@ -25,13 +25,13 @@ import (
 	"fmt"
 	"go/ast"
 	"go/types"
-	"log"
 	"path"
 	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/binding"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
 )
 
@@ -40,6 +40,7 @@ type id = uint32
 
 var supportedEntityAnnotations = map[string]bool{
 	"name":      false, // TODO
+	"removed":   true,
 	"sync":      true,
 	"transient": true,
 	"uid":       true,
@@ -70,8 +71,9 @@ type astReader struct {
 	// model produced by reading the schema
 	model *model.ModelInfo
 
-	err    error
+	errs   diagnostics.List // errors of all the processed structs
 	source *file
+	report func(*diagnostics.Diagnostic) // receives the warnings and notices, see generator.Options.Report()
 }
 
 // Entity holds the model information necessary to generate the binding code
@@ -126,12 +128,13 @@ type Field struct {
 	IsLazyLoaded       bool                      // only standalone (to-many) relations currently support lazy loading
 	Meta               *Field                    // self reference for recursive ".Meta.Fields" access in the template
 
-	path   string // relative addressing path for embedded structs
-	parent *Field // when included in parent.Fields[], nil for top-level fields (directly in the entity)
+	path   string               // relative addressing path for embedded structs
+	parent *Field               // when included in parent.Fields[], nil for top-level fields (directly in the entity)
+	pos    diagnostics.Position // declaration in the source code, used in errors
 }
 
 func NewBinding() (*astReader, error) {
-	return &astReader{model: &model.ModelInfo{}}, nil
+	return &astReader{model: &model.ModelInfo{}, report: diagnostics.Report}, nil
 }
 
 func (r *astReader) CreateFromAst(f *file) (err error) {
@@ -147,19 +150,12 @@ func (r *astReader) CreateFromAst(f *file) (err error) {
 		return r.entityLoader(node, &prevDecl)
 	})
 
-	if r.err != nil {
-		return r.err
-	}
-
-	return nil
+	return r.errs.Err()
 }
 
-// this function only processes structs and cuts-off on types that can't contain a struct
+// this function only processes structs and cuts-off on types that can't contain a struct.
+// Errors are collected so that all invalid structs are reported at once.
 func (r *astReader) entityLoader(node ast.Node, prevDecl **ast.GenDecl) bool {
-	if r.err != nil {
-		return false
-	}
-
 	switch v := node.(type) {
 	case *ast.TypeSpec:
 		if strct, isStruct := v.Type.(*ast.StructType); isStruct {
@@ -167,7 +163,7 @@ func (r *astReader) entityLoader(node ast.Node, prevDecl **ast.GenDecl) bool {
 
 			if name == "" {
 				// NOTE this should probably not happen
-				r.err = fmt.Errorf("encountered a struct without a name")
+				r.errs.Add(fmt.Errorf("encountered a struct without a name"))
 				return false
 			}
 
@@ -182,7 +178,7 @@ func (r *astReader) entityLoader(node ast.Node, prevDecl **ast.GenDecl) bool {
 				comments = (**prevDecl).Doc.List
 			}
 
-			r.err = r.createEntityFromAst(strct, name, comments)
+			r.errs.Add(r.createEntityFromAst(strct, name, comments, r.source.position(v.Name.Pos())))
 
 			// no need to go any deeper in the AST
 			return false
@@ -201,15 +197,19 @@ func (r *astReader) entityLoader(node ast.Node, prevDecl **ast.GenDecl) bool {
 	return false
 }
 
-func (r *astReader) createEntityFromAst(strct *ast.StructType, name string, comments []*ast.Comment) error {
+func (r *astReader) createEntityFromAst(strct *ast.StructType, name string, comments []*ast.Comment, pos diagnostics.Position) error {
 	var modelEntity = model.CreateEntity(r.model, 0, 0)
 	var entity = &Entity{Object: binding.CreateObject(modelEntity), binding: r}
 	modelEntity.Meta = entity
 	entity.SetName(name)
 
+	var entityError = func(err error) error {
+		return diagnostics.Wrapf(diagnostics.WithPosition(err, pos), "%s on entity %s", entity.Name)
+	}
+
 	if comments != nil {
 		if err := entity.setAnnotations(comments); err != nil {
-			return fmt.Errorf("%s on entity %s", err, entity.Name)
+			return entityError(err)
 		}
 	}
 
@@ -230,12 +230,12 @@ func (r *astReader) createEntityFromAst(strct *ast.StructType, name string, comm
 	// }
 
 	if err := modelEntity.AutosetIdProperty([]model.PropertyType{model.PropertyTypeLong, model.PropertyTypeString}); err != nil {
-		return fmt.Errorf("%s on entity %s", err, entity.Name)
+		return entityError(err)
 	}
 
 	// special handling for string IDs = they are transformed to uint64 in the binding
 	if idProp, err := modelEntity.IdProperty(); err != nil {
-		return fmt.Errorf("%s on entity %s", err, entity.Name)
+		return entityError(err)
 	} else if idProp.Type == model.PropertyTypeString {
 		var idPropMeta = idProp.Meta.(*Property)
 		idProp.Type = model.PropertyTypeLong
@@ -247,7 +247,7 @@ func (r *astReader) createEntityFromAst(strct *ast.StructType, name string, comm
 			idPropMeta.Converter = &converter
 		}
 	} else if !idProp.Meta.(*Property).hasValidTypeAsId() {
-		return fmt.Errorf("id field '%s' has unsupported type '%s' on entity %s - must be one of [int64, uint64, string]",
+		return diagnostics.Errorf(diagnostics.CodeSource, idProp.Meta.(*Property).GoField.pos, "id field '%s' has unsupported type '%s' on entity %s - must be one of [int64, uint64, string]",
 			idProp.Meta.(*Property).Name, idProp.Meta.(*Property).GoType, entity.Name)
 	} else {
 		idProp.Meta.(*Property).FbType = "Uint64" // always stored as Uint64
@@ -259,11 +259,9 @@ func (r *astReader) createEntityFromAst(strct *ast.StructType, name string, comm
 }
 
 func (entity *Entity) addFields(parent *Field, fields fieldList, fieldPath, prefix string, recursionStack *map[string]bool) ([]*Field, error) {
-	var propertyLog = func(text string, property *Property) {
-		log.Printf("%s property %s found in %s", text, property.Name, fieldPath)
-	}
-	var propertyError = func(err error, property *Property) error {
-		return fmt.Errorf("%s on property %s found in %s", err, property.Name, fieldPath)
+	var propertyNotice = func(code string, text string, property *Property, hint string) {
+		entity.binding.report(diagnostics.Notice(code, fmt.Sprintf("%s property %s found in %s%s", text, property.Name, fieldPath, hint)).
+			At(property.GoField.pos).InEntity(entity.Name).InProperty(property.Name))
 	}
 
 	var children []*Field
@@ -271,6 +269,15 @@ func (entity *Entity) addFields(parent *Field, fields fieldList, fieldPath, pref
 	for i := 0; i < fields.Length(); i++ {
 		f := fields.Field(i)
 
+		// fields of structs from other packages don't have a position, use the one of the embedding field instead
+		var pos = entity.binding.source.position(f.Pos())
+		if !pos.IsValid() && parent != nil {
+			pos = parent.pos
+		}
+		var propertyError = func(err error, property *Property) error {
+			return diagnostics.Wrapf(diagnostics.WithPosition(err, pos), "%s on property %s found in %s", property.Name, fieldPath)
+		}
+
 		var modelProperty = model.CreateProperty(entity.ModelEntity, 0, 0)
 		var property = &Property{
 			Field: binding.CreateField(modelProperty),
@@ -293,11 +300,16 @@ func (entity *Entity) addFields(parent *Field, fields fieldList, fieldPath, pref
 			Property: property,
 			path:     fieldPath,
 			parent:   parent,
+			pos:      pos,
 		}
 		field.Meta = field
 		property.GoField = field
 
-		if err := property.setAnnotations(f.Tag()); err != nil {
+		var tagPos = entity.binding.source.position(f.TagPos())
+		if !tagPos.IsValid() {
+			tagPos = pos
+		}
+		if err := property.setAnnotations(f.Tag(), tagPos); err != nil {
 			return nil, propertyError(err, property)
 		}
 
@@ -314,7 +326,7 @@ func (entity *Entity) addFields(parent *Field, fields fieldList, fieldPath, pref
 		if pkg.Path() != entity.binding.Package.Path() {
 			// check if it's available (starts with an uppercase letter)
 			if len(field.Name) == 0 || field.Name[0] < 65 || field.Name[0] > 90 {
-				propertyLog("Notice: skipping unavailable (private)", property)
+				propertyNotice(diagnostics.CodePrivateSkipped, "skipping unavailable (private)", property, "")
 				continue
 			}
 
@@ -348,9 +360,8 @@ func (entity *Entity) addFields(parent *Field, fields fieldList, fieldPath, pref
 			// first, try to handle time.Time struct - automatically set a converter if it's declared a date by the user
 			if property.annotations["date"] == nil && property.annotations["date-nano"] == nil {
 				property.annotations["date"] = &binding.Annotation{}
-				propertyLog("Notice: time.Time is stored and read using millisecond precision in UTC by default on", property)
-				log.Printf("To silence this notice either define your own converter using `converter` and " +
-					"`type` annotations or add a `date` annotation explicitly")
+				propertyNotice(diagnostics.CodeTimePrecision, "time.Time is stored and read using millisecond precision in UTC by default on", property,
+					"; to silence this notice either define your own converter using `converter` and `type` annotations or add a `date` annotation explicitly")
 			}
 
 			// store the field as an int64
@@ -493,7 +504,7 @@ func (field *Field) processType(f field) (fields fieldList, err error) {
 		}
 
 		// otherwise inline all fields
-		return structFieldList{strct}, nil
+		return structFieldList{strct, field.Entity.binding.source}, nil
 	}
 
 	// check if it's a slice of a non-base type
@@ -567,14 +578,15 @@ func (field *Field) fillInfo(f field, typ typeErrorful) {
 }
 
 func (entity *Entity) setAnnotations(comments []*ast.Comment) error {
-	lines := parseCommentsLines(comments)
+	lines := parseCommentsLines(comments, entity.binding.source)
 
 	var annotations = make(map[string]*binding.Annotation)
 
-	for _, tags := range lines {
+	for _, line := range lines {
 		// only handle comments in the form of:   // `tags`
+		var tags = line.text
 		if len(tags) > 1 && tags[0] == tags[len(tags)-1] && tags[0] == '`' {
-			if err := parseAnnotations(tags, &annotations, supportedEntityAnnotations); err != nil {
+			if err := parseAnnotations(tags, line.pos, &annotations, supportedEntityAnnotations); err != nil {
 				return err
 			}
 		}
@@ -583,8 +595,35 @@ func (entity *Entity) setAnnotations(comments []*ast.Comment) error {
 	return entity.ProcessAnnotations(annotations)
 }
 
-func parseCommentsLines(comments []*ast.Comment) []string {
-	var lines []string
+// commentLine is a single trimmed line of a comment and its position in the source file
+type commentLine struct {
+	text string
+	pos  diagnostics.Position
+}
+
+func parseCommentsLines(comments []*ast.Comment, source *file) []commentLine {
+	var lines []commentLine
+
+	// positions of the trimmed lines: the text is searched for in the raw comment text which starts at comment.Slash
+	var appendLine = func(comment *ast.Comment, text string, searchFrom int) int {
+		var pos = source.position(comment.Slash)
+		var index = strings.Index(comment.Text[searchFrom:], text)
+		if index >= 0 && pos.IsValid() {
+			index = index + searchFrom
+			var lineStart = strings.LastIndex(comment.Text[:index], "\n")
+			pos.Line = pos.Line + strings.Count(comment.Text[:index], "\n")
+			if lineStart >= 0 {
+				pos.Column = index - lineStart
+			} else {
+				pos = pos.Offset(index)
+			}
+			searchFrom = index + len(text)
+		} else {
+			pos = diagnostics.Position{}
+		}
+		lines = append(lines, commentLine{text: text, pos: pos})
+		return searchFrom
+	}
 
 	for _, comment := range comments {
 		text := comment.Text
@@ -593,7 +632,7 @@ func parseCommentsLines(comments []*ast.Comment) []string {
 		// text is a single/multi line comment
 		if strings.HasPrefix(text, "//") {
 			text = strings.TrimPrefix(text, "//")
-			lines = append(lines, strings.TrimSpace(text))
+			appendLine(comment, strings.TrimSpace(text), 0)
 
 		} else if strings.HasPrefix(text, "/*") {
 			text = strings.TrimPrefix(text, "/*")
@@ -601,8 +640,9 @@ func parseCommentsLines(comments []*ast.Comment) []string {
 			text = strings.TrimSuffix(text, "*/")
 			text = strings.TrimSuffix(text, "*")
 			text = strings.TrimSpace(text)
+			var searchFrom = 0
 			for _, line := range strings.Split(text, "\n") {
-				lines = append(lines, strings.TrimSpace(line))
+				searchFrom = appendLine(comment, strings.TrimSpace(line), searchFrom)
 			}
 		} else {
 			// unknown format, ignore
@@ -617,9 +657,9 @@ func (property *Property) hasValidTypeAsId() bool {
 	return goType == "int64" || goType == "uint64" || goType == "string"
 }
 
-func (property *Property) setAnnotations(tags string) error {
+func (property *Property) setAnnotations(tags string, pos diagnostics.Position) error {
 	var annotations = make(map[string]*binding.Annotation)
-	if err := parseAnnotations(tags, &annotations, supportedPropertyAnnotations); err != nil {
+	if err := parseAnnotations(tags, pos, &annotations, supportedPropertyAnnotations); err != nil {
 		return err
 	}
 
@@ -659,9 +699,11 @@ func (property *Property) setRelationAnnotation(target string, manyToMany bool)
 	return nil
 }
 
-func parseAnnotations(tags string, annotations *map[string]*binding.Annotation, supportedAnnotations map[string]bool) error {
+// parseAnnotations parses the objectbox struct tag; pos is the position of the tags string in the source file
+func parseAnnotations(tags string, pos diagnostics.Position, annotations *map[string]*binding.Annotation, supportedAnnotations map[string]bool) error {
 	if len(tags) > 1 && tags[0] == tags[len(tags)-1] && (tags[0] == '`' || tags[0] == '"') {
 		tags = tags[1 : len(tags)-1]
+		pos = pos.Offset(1)
 	}
 
 	if tags == "" {
@@ -670,15 +712,16 @@ func parseAnnotations(tags string, annotations *map[string]*binding.Annotation,
 
 	// if it's a top-level call, i.e. tags is something like `objectbox:"tag1 tag2:value2" irrelevant:"value"`
 	var tag = reflect.StructTag(tags)
-	if contents, found := tag.Lookup("objectbox"); found {
-		tags = contents
-	} else if contents, found := tag.Lookup("ObjectBox"); found {
-		tags = contents
-	} else {
-		return nil
+	for _, key := range []string{"objectbox", "ObjectBox"} {
+		if contents, found := tag.Lookup(key); found {
+			if index := strings.Index(tags, key+`:"`); index >= 0 {
+				pos = pos.Offset(index + len(key) + 2)
+			}
+			return binding.ParseAnnotations(contents, pos, annotations, supportedAnnotations)
+		}
 	}
 
-	return binding.ParseAnnotations(tags, annotations, supportedAnnotations)
+	return nil
 }
 
 func (property *Property) setBasicType(baseType string) error {
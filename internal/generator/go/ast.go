@@ -21,6 +21,7 @@
 package gogenerator
 
 import (
+	"bytes"
 	"fmt"
 	"go/ast"
 	"go/importer"
@@ -31,62 +32,170 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
 )
 
-type file struct {
-	ast            *ast.File
+// goPackage holds the files of a package directory, parsed (and type-checked on demand) only once for all of its
+// sources, see packageCache
+type goPackage struct {
+	dir     string
+	pkgName string
+	fileset *token.FileSet
+	files   []*ast.File
+	sources []packageSource      // the files the package was parsed from, in the order of the directory listing
+	asts    map[string]*ast.File // the parsed files of the package, by path
+
+	analyzed       sync.Once
 	info           *types.Info
-	fileset        *token.FileSet
-	files          []*ast.File
-	dir            string
-	pkgName        string
 	typeCheckError error
+
+	fieldsIndexed sync.Once
+	fieldsByPos   map[token.Pos]*ast.Field // see astField()
 }
 
-func parseFile(sourceFile string) (f *file, err error) {
-	f = &file{
-		dir:     filepath.Dir(sourceFile),
-		fileset: token.NewFileSet(),
+// packageSource is the content of a single file of a package directory
+type packageSource struct {
+	path string
+	data []byte
+}
+
+// file is a source file along with the package it belongs to
+type file struct {
+	*goPackage
+	ast *ast.File
+}
+
+// packageCache keeps the parsed packages by directory and package name, so that the sources of a package share a single
+// parsed and type-checked package instead of processing the whole directory for each of them. The package files are
+// still read for each source (the reads are recorded as the inputs of the source), a package is only parsed again if
+// any of them has changed, e.g. between the runs in the watch mode.
+type packageCache struct {
+	mutex    sync.Mutex
+	packages map[string]*cachedPackage
+}
+
+// cachedPackage is locked while the package is being parsed, so that other sources of the package wait for it
+type cachedPackage struct {
+	mutex sync.Mutex
+	pkg   *goPackage
+}
+
+func (cache *packageCache) entry(dir, pkgName string) *cachedPackage {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.packages == nil {
+		cache.packages = make(map[string]*cachedPackage)
+	}
+	var key = filepath.Clean(dir) + string(filepath.Separator) + pkgName
+	if cache.packages[key] == nil {
+		cache.packages[key] = &cachedPackage{}
+	}
+	return cache.packages[key]
+}
+
+// parseFile returns the given source file, parsing the package directory it's in unless already parsed
+func (cache *packageCache) parseFile(fsys vfs.FS, sourceFile string) (*file, error) {
+	var dir = filepath.Dir(sourceFile)
+
+	// get the main file's package name
+	src, err := fsys.ReadFile(sourceFile)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parser.ParseFile(token.NewFileSet(), sourceFile, src, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, err
 	}
+	var pkgName = parsed.Name.Name
 
-	{ // get the main file's package name
-		parsed, err := parser.ParseFile(f.fileset, sourceFile, nil, 0)
+	// read the whole directory to read & understand the used types
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sourcePath = filepath.Join(dir, filepath.Base(sourceFile))
+	var sources []packageSource
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+
+		// never skip the sourceFile
+		var path = filepath.Join(dir, entry.Name())
+		if path != sourcePath && !parserFilter(entry) {
+			continue
+		}
+
+		data, err := fsys.ReadFile(path)
 		if err != nil {
 			return nil, err
 		}
-		f.pkgName = parsed.Name.Name
+		sources = append(sources, packageSource{path, data})
 	}
 
-	// parse the whole directory to read & understand the used types
-	var filter = func(file os.FileInfo) bool {
-		// never skip the sourceFile
-		if file.Name() == filepath.Base(sourceFile) {
-			return true
+	var cached = cache.entry(dir, pkgName)
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+	if cached.pkg == nil || !sameSources(cached.pkg.sources, sources) {
+		cached.pkg = nil
+		pkg, err := parsePackage(dir, pkgName, sources)
+		if err != nil {
+			return nil, err
 		}
-		return parserFilter(file)
+		cached.pkg = pkg
 	}
-	var pkgs map[string]*ast.Package
-	if pkgs, err = parser.ParseDir(f.fileset, f.dir, filter, parser.ParseComments); err != nil {
-		return nil, err
+
+	var f = &file{goPackage: cached.pkg, ast: cached.pkg.asts[sourcePath]}
+	if f.ast == nil {
+		return nil, fmt.Errorf("the source file %s not found among the files processed in the directory", sourceFile)
 	}
+	return f, nil
+}
 
-	if pkgs[f.pkgName] == nil {
-		return nil, fmt.Errorf("couldn't find package %s in directory %s", f.pkgName, f.dir)
+// parsePackage parses the files of the given package, the files of other packages in the same directory are skipped
+func parsePackage(dir, pkgName string, sources []packageSource) (*goPackage, error) {
+	var pkg = &goPackage{
+		dir:     dir,
+		pkgName: pkgName,
+		fileset: token.NewFileSet(),
+		sources: sources,
+		asts:    make(map[string]*ast.File),
 	}
 
-	// create a list of types in the package the original file belongs to and
-	for name, file := range pkgs[f.pkgName].Files {
-		if name == sourceFile {
-			f.ast = file
+	for _, source := range sources {
+		parsed, err := parser.ParseFile(pkg.fileset, source.path, source.data, parser.ParseComments)
+		if err != nil {
+			return nil, err
 		}
-		f.files = append(f.files, file)
+
+		// create a list of types in the package the original file belongs to
+		if parsed.Name.Name != pkgName {
+			continue
+		}
+		pkg.asts[source.path] = parsed
+		pkg.files = append(pkg.files, parsed)
 	}
 
-	if f.ast == nil {
-		return nil, fmt.Errorf("the source file %s not found among the files processed in the directory", sourceFile)
+	if len(pkg.files) == 0 {
+		return nil, fmt.Errorf("couldn't find package %s in directory %s", pkgName, dir)
 	}
+	return pkg, nil
+}
 
-	return f, nil
+func sameSources(a, b []packageSource) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].path != b[i].path || !bytes.Equal(a[i].data, b[i].data) {
+			return false
+		}
+	}
+	return true
 }
 
 func parserFilter(file os.FileInfo) bool {
@@ -121,11 +230,11 @@ func (f *file) importedPackage(name string) (*types.Package, error) {
 	return nil, fmt.Errorf("package %s not imported in the source file", name)
 }
 
-func (f *file) analyze() {
-	// load file info (resolved types) JiT if necessary
-	if f.info == nil {
+func (pkg *goPackage) analyze() {
+	// load file info (resolved types) JiT if necessary, only once for all the sources of the package
+	pkg.analyzed.Do(func() {
 		// call types.Config.Check() to fill types.Info
-		f.info = &types.Info{
+		pkg.info = &types.Info{
 			Types: make(map[ast.Expr]types.TypeAndValue),
 			Defs:  make(map[*ast.Ident]types.Object),
 			Uses:  make(map[*ast.Ident]types.Object),
@@ -145,12 +254,12 @@ func (f *file) analyze() {
 			},
 		}
 
-		if _, err := conf.Check(f.dir, f.fileset, f.files, f.info); err != nil {
+		if _, err := conf.Check(pkg.dir, pkg.fileset, pkg.files, pkg.info); err != nil {
 			// The type checker tries to go on even in case of an error to find out as much as it can.
 			// Therefore, this may be an error on an unrelated field and we may still be able to get all the info we
 			// need. If the type still can't be determined, we well fail bellow, printing this error as well.
 			if firstHardErr != nil {
-				f.typeCheckError = firstHardErr // give preference to first hard error over any soft error
+				pkg.typeCheckError = firstHardErr // give preference to first hard error over any soft error
 			}
 		}
 
@@ -158,7 +267,7 @@ func (f *file) analyze() {
 		// this can be used to verify converters exist and have correct signatures, however it only shows functions
 		// imported in the package, e.g. it won't show `objectbox.StringIdConvertToEntityProperty`
 		// TODO finish verification
-		//for _, v := range f.info.Defs {
+		//for _, v := range pkg.info.Defs {
 		//	if def, isFn := v.(*types.Func); isFn {
 		//		if signature, isSig := def.Type().(*types.Signature); isSig {
 		//			if signature.Recv() == nil {
@@ -167,7 +276,7 @@ func (f *file) analyze() {
 		//		}
 		//	}
 		//}
-	}
+	})
 }
 
 func (f *file) getType(expr ast.Expr) (types.Type, error) {
@@ -189,6 +298,60 @@ func (f *file) getType(expr ast.Expr) (types.Type, error) {
 //	return nil, nil
 //}
 
+// position converts the given token position to a diagnostics position, zero if not valid
+func (f *file) position(pos token.Pos) diagnostics.Position {
+	if !pos.IsValid() {
+		return diagnostics.Position{}
+	}
+	var position = f.fileset.Position(pos)
+	return diagnostics.Position{File: position.Filename, Line: position.Line, Column: position.Column}
+}
+
+// offset returns the byte offset of the given position in its source file
+func (f *file) offset(pos token.Pos) int {
+	return f.fileset.Position(pos).Offset
+}
+
+// lineOffset returns the byte offset of the given line and column in the source file; only for positions returned by
+// position()
+func (f *file) lineOffset(pos diagnostics.Position) int {
+	var tokenFile = f.fileset.File(f.ast.Pos())
+	return tokenFile.Offset(tokenFile.LineStart(pos.Line)) + pos.Column - 1
+}
+
+// astField finds a struct field declaration in the package by the position of its name (or its type if embedded)
+func (f *file) astField(pos token.Pos) *ast.Field {
+	if f == nil || !pos.IsValid() {
+		return nil
+	}
+
+	f.fieldsIndexed.Do(func() {
+		f.fieldsByPos = make(map[token.Pos]*ast.Field)
+		for _, file := range f.files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				if field, isField := node.(*ast.Field); isField {
+					for _, name := range field.Names {
+						f.fieldsByPos[name.Pos()] = field
+					}
+					if len(field.Names) == 0 {
+						// embedded field, e.g. `Base`, `*Base` or `pkg.Base` - the position of the type name is used
+						var typ = field.Type
+						if star, isStar := typ.(*ast.StarExpr); isStar {
+							typ = star.X
+						}
+						if selector, isSelector := typ.(*ast.SelectorExpr); isSelector {
+							typ = selector.Sel
+						}
+						f.fieldsByPos[typ.Pos()] = field
+					}
+				}
+				return true
+			})
+		}
+	})
+	return f.fieldsByPos[pos]
+}
+
 func (f *file) walk(fn func(ast.Node) bool) {
 	ast.Walk(fnAsVisitor(fn), f.ast)
 }
@@ -0,0 +1,72 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gogenerator
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestPackageCache(t *testing.T) {
+	var fsys = vfs.NewMemory()
+	var write = func(name, content string) {
+		assert.NoErr(t, fsys.WriteFile(filepath.Join("model", name), []byte(content), 0644))
+	}
+	assert.NoErr(t, fsys.MkdirAll("model", 0755))
+	write("a.go", "package model\n\ntype A struct {\n\tId uint64\n\tB  *B\n}\n")
+	write("b.go", "package model\n\ntype B struct {\n\tId uint64\n}\n")
+	write("other.go", "package other\n")
+
+	var cache packageCache
+	var files = make([]*file, 2)
+	var wg sync.WaitGroup
+	for i, name := range []string{"a.go", "b.go"} {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			f, err := cache.parseFile(fsys, filepath.Join("model", name))
+			assert.NoErr(t, err)
+			f.analyze()
+			files[i] = f
+		}(i, name)
+	}
+	wg.Wait()
+
+	// both sources share the package, parsed only once and without the files of other packages
+	assert.True(t, files[0].goPackage == files[1].goPackage)
+	assert.Eq(t, 2, len(files[0].files))
+	assert.NoErr(t, files[0].typeCheckError)
+	assert.True(t, files[0].ast != files[1].ast)
+
+	// unchanged files reuse the package, a change parses it again
+	f, err := cache.parseFile(fsys, filepath.Join("model", "a.go"))
+	assert.NoErr(t, err)
+	assert.True(t, f.goPackage == files[0].goPackage)
+
+	write("b.go", "package model\n\ntype B struct {\n\tId   uint64\n\tName string\n}\n")
+	f, err = cache.parseFile(fsys, filepath.Join("model", "a.go"))
+	assert.NoErr(t, err)
+	assert.True(t, f.goPackage != files[0].goPackage)
+}
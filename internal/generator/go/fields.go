@@ -23,6 +23,7 @@ package gogenerator
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
 	"go/types"
 	"path"
 	"strings"
@@ -40,6 +41,8 @@ type field interface {
 	Type() typeErrorful
 	TypeInternal() types.Type
 	Package() (*types.Package, error)
+	Pos() token.Pos    // token.NoPos if not available in the source file set
+	TagPos() token.Pos // token.NoPos if not available in the source file set
 }
 
 type typeErrorful interface {
@@ -98,6 +101,17 @@ func (field astStructField) Tag() string {
 	return ""
 }
 
+func (field astStructField) Pos() token.Pos {
+	return field.Field.Pos()
+}
+
+func (field astStructField) TagPos() token.Pos {
+	if field.Field.Tag != nil {
+		return field.Field.Tag.Pos()
+	}
+	return token.NoPos
+}
+
 func (field astStructField) Type() typeErrorful {
 	return astTypeExpr{Expr: field.Field.Type, source: field.source}
 }
@@ -160,6 +174,7 @@ func (expr astTypeExpr) UnderlyingOrError() (types.Type, error) {
 
 type structFieldList struct {
 	*types.Struct
+	source *file
 }
 
 func (fields structFieldList) Length() int {
@@ -171,12 +186,13 @@ func (fields structFieldList) Length() int {
 }
 
 func (fields structFieldList) Field(i int) field {
-	return structField{fields.Struct.Field(i), fields.Tag(i)}
+	return structField{fields.Struct.Field(i), fields.Tag(i), fields.source}
 }
 
 type structField struct {
 	*types.Var
-	tag string
+	tag    string
+	source *file
 }
 
 func (field structField) Name() (string, error) {
@@ -187,6 +203,21 @@ func (field structField) Tag() string {
 	return field.tag
 }
 
+func (field structField) Pos() token.Pos {
+	// types from other packages are loaded by the importer which uses a different file set
+	if field.source == nil || field.Var.Pkg() == nil || field.Var.Pkg().Path() != field.source.dir {
+		return token.NoPos
+	}
+	return field.Var.Pos()
+}
+
+func (field structField) TagPos() token.Pos {
+	if astField := field.source.astField(field.Pos()); astField != nil && astField.Tag != nil {
+		return astField.Tag.Pos()
+	}
+	return token.NoPos
+}
+
 func (field structField) Type() typeErrorful {
 	return typesTypeErrorful{field.Var.Type()}
 }
@@ -23,25 +23,40 @@ package gogenerator
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"go/format"
+	"go/parser"
+	"go/token"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/go/templates"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
 )
 
 type GoGenerator struct {
-	binding *astReader
 	ByValue bool
+
+	// bindings are set by ParseSource() for WriteBindingFiles(), by the source file; sources may be parsed concurrently
+	bindings      map[string]*astReader
+	bindingsMutex sync.Mutex
+
+	// packages are the package names of the parsed sources, used for the model file if there are no entities at all
+	packages map[string]string
+
+	// parsed are the Go packages of the sources, each parsed and type-checked once for all the sources in the package
+	parsed packageCache
 }
 
 // BindingFiles returns names of binding files for the given entity file.
 func (gen *GoGenerator) BindingFiles(forFile string, options generator.Options) []string {
 	if len(options.OutPath) > 0 {
-		forFile = filepath.Join(options.OutPath, filepath.Base(forFile))
+		forFile = options.OutputFile(options.OutPath, forFile)
 	}
 	var extension = filepath.Ext(forFile)
 	return []string{forFile[0:len(forFile)-len(extension)] + ".obx" + extension}
@@ -50,53 +65,84 @@ func (gen *GoGenerator) BindingFiles(forFile string, options generator.Options)
 // ModelFile returns the model GO file for the given JSON info file path
 func (gen *GoGenerator) ModelFile(forFile string, options generator.Options) string {
 	if len(options.OutPath) > 0 {
-		forFile = filepath.Join(options.OutPath, filepath.Base(forFile))
+		forFile = options.OutputFile(options.OutPath, forFile)
 	}
 	var extension = filepath.Ext(forFile)
 	return forFile[0:len(forFile)-len(extension)] + ".go"
 }
 
-func (GoGenerator) IsGeneratedFile(file string) bool {
+func (*GoGenerator) IsGeneratedFile(file string) bool {
 	var name = filepath.Base(file)
 	return name == "objectbox-model.go" || strings.HasSuffix(name, ".obx.go")
 }
 
-func (GoGenerator) IsSourceFile(file string) bool {
+func (*GoGenerator) IsSourceFile(file string) bool {
 	// TODO: maybe we should look for the appropriate `//go:generate ....` comment in the file?
 	//  E.g. when the generator is launched for a whole directory/pattern...
 	return strings.HasSuffix(file, ".go")
 }
 
-func (goGen *GoGenerator) ParseSource(sourceFile string) (*model.ModelInfo, error) {
+func (goGen *GoGenerator) ParseSource(sourceFile string, options generator.Options) (*model.ModelInfo, error) {
 	var f *file
 	var err error
 
-	if f, err = parseFile(sourceFile); err != nil {
+	if f, err = goGen.parsed.parseFile(options.FileSystem(), sourceFile); err != nil {
 		return nil, fmt.Errorf("can't parse file %s: %s", sourceFile, err)
 	}
 
-	if goGen.binding, err = NewBinding(); err != nil {
+	binding, err := NewBinding()
+	if err != nil {
 		return nil, fmt.Errorf("can't init Go AST reader: %s", err)
 	}
+	binding.report = options.Report
 
-	if err = goGen.binding.CreateFromAst(f); err != nil {
+	if err = binding.CreateFromAst(f); err != nil {
+		// positioned errors already name the source file
+		if diagnostics.HasPosition(err) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("can't prepare bindings for %s: %s", sourceFile, err)
 	}
 
-	return goGen.binding.model, nil
+	goGen.bindingsMutex.Lock()
+	defer goGen.bindingsMutex.Unlock()
+	if goGen.bindings == nil {
+		goGen.bindings = make(map[string]*astReader)
+	}
+	goGen.bindings[sourceFile] = binding
+
+	if goGen.packages == nil {
+		goGen.packages = make(map[string]string)
+	}
+	goGen.packages[filepath.Clean(sourceFile)] = binding.Package.Name()
+
+	return binding.model, nil
+}
+
+// takeBinding returns the binding of the given source file, as set by ParseSource(), and forgets it
+func (goGen *GoGenerator) takeBinding(sourceFile string) *astReader {
+	goGen.bindingsMutex.Lock()
+	defer goGen.bindingsMutex.Unlock()
+	var binding = goGen.bindings[sourceFile]
+	delete(goGen.bindings, sourceFile)
+	return binding
+}
+
+// CheckModel implements generator.ModelChecker: relation cycles are only checked for Go, across all the source files
+func (goGen *GoGenerator) CheckModel(mergedModel *model.ModelInfo) error {
+	return mergedModel.CheckRelationCycles()
 }
 
 func (goGen *GoGenerator) WriteBindingFiles(sourceFile string, options generator.Options, mergedModel *model.ModelInfo) error {
-	// NOTE: find a better place for this check - we only want to do it for some languages
-	// should be called after generator calls storedMode.Finalize()
-	if err := mergedModel.CheckRelationCycles(); err != nil {
-		return err
+	var binding = goGen.takeBinding(sourceFile)
+	if binding == nil {
+		return fmt.Errorf("can't generate binding file %s: the source file has not been parsed", sourceFile)
 	}
 
 	var err, err2 error
 
 	var bindingSource []byte
-	if bindingSource, err = goGen.generateBindingFile(options, mergedModel); err != nil {
+	if bindingSource, err = goGen.generateBindingFile(options, binding, mergedModel); err != nil {
 		return fmt.Errorf("can't generate binding file %s: %s", sourceFile, err)
 	}
 
@@ -111,7 +157,7 @@ func (goGen *GoGenerator) WriteBindingFiles(sourceFile string, options generator
 		bindingSource = formattedSource
 	}
 
-	if err = generator.WriteFile(bindingFiles[0], bindingSource, sourceFile); err != nil {
+	if err = options.WriteFile(bindingFiles[0], bindingSource, sourceFile); err != nil {
 		return fmt.Errorf("can't write binding file %s: %s", sourceFile, err)
 	} else if err2 != nil {
 		// now when the binding has been written (for debugging purposes), we can return the error
@@ -121,7 +167,7 @@ func (goGen *GoGenerator) WriteBindingFiles(sourceFile string, options generator
 	return nil
 }
 
-func (goGen *GoGenerator) generateBindingFile(options generator.Options, m *model.ModelInfo) (data []byte, err error) {
+func (goGen *GoGenerator) generateBindingFile(options generator.Options, binding *astReader, m *model.ModelInfo) (data []byte, err error) {
 	var b bytes.Buffer
 	writer := bufio.NewWriter(&b)
 
@@ -131,7 +177,7 @@ func (goGen *GoGenerator) generateBindingFile(options generator.Options, m *mode
 		ByValue          bool
 		GeneratorVersion int
 		Options          generator.Options
-	}{m, goGen.binding, goGen.ByValue, generator.VersionId, options}
+	}{m, binding, goGen.ByValue, generator.VersionId, options}
 
 	if err = templates.BindingTemplate.Execute(writer, tplArguments); err != nil {
 		return nil, fmt.Errorf("template execution failed: %s", err)
@@ -150,7 +196,7 @@ func (goGen *GoGenerator) WriteModelBindingFile(options generator.Options, model
 	var modelFile = goGen.ModelFile(options.ModelInfoFile, options)
 	var modelSource []byte
 
-	if modelSource, err = goGen.generateModelFile(modelInfo); err != nil {
+	if modelSource, err = goGen.generateModelFile(options, modelInfo); err != nil {
 		return fmt.Errorf("can't generate model file %s: %s", modelFile, err)
 	}
 
@@ -161,7 +207,7 @@ func (goGen *GoGenerator) WriteModelBindingFile(options generator.Options, model
 		modelSource = formattedSource
 	}
 
-	if err = generator.WriteFile(modelFile, modelSource, options.ModelInfoFile); err != nil {
+	if err = options.WriteFile(modelFile, modelSource, options.ModelInfoFile); err != nil {
 		return fmt.Errorf("can't write model file %s: %s", modelFile, err)
 	} else if err2 != nil {
 		// now when the model has been written (for debugging purposes), we can return the error
@@ -171,7 +217,64 @@ func (goGen *GoGenerator) WriteModelBindingFile(options generator.Options, model
 	return nil
 }
 
-func (goGen *GoGenerator) generateModelFile(m *model.ModelInfo) (data []byte, err error) {
+// modelPackage returns the package of the entities processed during this run, i.e. the package of the model file
+func (goGen *GoGenerator) modelPackage(options generator.Options, m *model.ModelInfo) (string, error) {
+	for i := len(m.Entities) - 1; i >= 0; i-- {
+		if entity, isGoEntity := m.Entities[i].Meta.(*Entity); isGoEntity {
+			return entity.binding.Package.Name(), nil
+		}
+	}
+
+	var modelFile = goGen.ModelFile(options.ModelInfoFile, options)
+	if packageName := goGen.sourcePackage(filepath.Dir(modelFile)); len(packageName) > 0 {
+		return packageName, nil
+	}
+
+	// no source has been parsed, e.g. all of them were up-to-date in the cache; keep the package of the existing file
+	if src, err := options.FileSystem().ReadFile(modelFile); err == nil {
+		if parsed, err := parser.ParseFile(token.NewFileSet(), modelFile, src, parser.PackageClauseOnly); err == nil {
+			return parsed.Name.Name, nil
+		}
+	}
+	return "", errors.New("no source file has been parsed, can't determine the package name")
+}
+
+// sourcePackage returns the package of the last parsed source (by path, so that the result doesn't depend on the order
+// the sources are parsed in), preferring the sources in the given directory and then the ones in its subdirectories
+func (goGen *GoGenerator) sourcePackage(dir string) string {
+	goGen.bindingsMutex.Lock()
+	defer goGen.bindingsMutex.Unlock()
+
+	var sources = make([]string, 0, len(goGen.packages))
+	for sourceFile := range goGen.packages {
+		sources = append(sources, sourceFile)
+	}
+	sort.Strings(sources)
+
+	var inDir, inSubdir, last string
+	for _, sourceFile := range sources {
+		if filepath.Dir(sourceFile) == dir {
+			inDir = sourceFile
+		} else if dir == "." && !filepath.IsAbs(sourceFile) || strings.HasPrefix(sourceFile, dir+string(filepath.Separator)) {
+			inSubdir = sourceFile
+		}
+		last = sourceFile
+	}
+
+	for _, sourceFile := range []string{inDir, inSubdir, last} {
+		if len(sourceFile) > 0 {
+			return goGen.packages[sourceFile]
+		}
+	}
+	return ""
+}
+
+func (goGen *GoGenerator) generateModelFile(options generator.Options, m *model.ModelInfo) (data []byte, err error) {
+	packageName, err := goGen.modelPackage(options, m)
+	if err != nil {
+		return nil, err
+	}
+
 	var b bytes.Buffer
 	writer := bufio.NewWriter(&b)
 
@@ -179,7 +282,7 @@ func (goGen *GoGenerator) generateModelFile(m *model.ModelInfo) (data []byte, er
 		Package          string
 		Model            *model.ModelInfo
 		GeneratorVersion int
-	}{goGen.binding.Package.Name(), m, generator.VersionId}
+	}{packageName, m, generator.VersionId}
 
 	if err = templates.ModelTemplate.Execute(writer, tplArguments); err != nil {
 		return nil, fmt.Errorf("template execution failed: %s", err)
@@ -0,0 +1,370 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package gogenerator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/binding"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+)
+
+// sourceEdit replaces a part of the source file, see PinUids()
+type sourceEdit struct {
+	offset int
+	length int
+	text   string
+}
+
+// uidValueRegexp matches an empty uid annotation, i.e. the part replaced by "uid:123"
+var uidValueRegexp = regexp.MustCompile(`^uid(\s*[=:])?`)
+
+// PinUids implements generator.UidPinner: it adds the uid annotation to the struct tags of the fields and to the
+// `objectbox:"..."` comment of the entity structs, or fills in empty ones. The source is edited in place, keeping its
+// formatting. Fields of embedded structs are not pinned because the struct may be embedded in multiple entities, they
+// are reported as warnings instead, see reportEmbeddedFields().
+func (goGen *GoGenerator) PinUids(sourceFile string, options generator.Options, mergedModel *model.ModelInfo) (map[string][]byte, error) {
+	var entities = mergedModel.EntitiesWithMeta()
+	if len(entities) == 0 {
+		return nil, nil
+	}
+
+	source, err := options.FileSystem().ReadFile(sourceFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []sourceEdit
+	for _, entity := range entities {
+		var meta = entity.Meta.(*Entity)
+		entityEdits, err := pinEntityUids(meta.binding.source, source, entity, meta, options.Report)
+		if err != nil {
+			return nil, fmt.Errorf("can't pin the UIDs of entity %s: %s", entity.Name, err)
+		}
+		edits = append(edits, entityEdits...)
+	}
+
+	if len(edits) == 0 {
+		return nil, nil
+	}
+
+	// apply from the end so that the offsets of the remaining edits stay valid
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].offset > edits[j].offset })
+	var result = append([]byte{}, source...)
+	for _, edit := range edits {
+		result = append(result[:edit.offset], append([]byte(edit.text), result[edit.offset+edit.length:]...)...)
+	}
+	// keep a gofmt-formatted source formatted, e.g. the alignment of the tags
+	if formatted, err := format.Source(source); err == nil && bytes.Equal(formatted, source) {
+		if formatted, err = format.Source(result); err == nil {
+			result = formatted
+		}
+	}
+
+	if bytes.Equal(source, result) {
+		return nil, nil
+	}
+	return map[string][]byte{sourceFile: result}, nil
+}
+
+func pinEntityUids(f *file, source []byte, entity *model.Entity, meta *Entity, report func(*diagnostics.Diagnostic)) ([]sourceEdit, error) {
+	decl, spec := findStructDecl(f.ast, meta.Name)
+	if spec == nil {
+		return nil, fmt.Errorf("declaration of struct %s not found", meta.Name)
+	}
+
+	var edits []sourceEdit
+	if edit, err := pinEntityComment(f, source, decl, spec, entity.Id); err != nil {
+		return nil, err
+	} else if edit != nil {
+		edits = append(edits, *edit)
+	}
+
+	var strct = spec.Type.(*ast.StructType)
+	for _, field := range meta.Fields {
+		var id model.IdUid
+		if field.StandaloneRelation != nil {
+			relation, err := entity.FindRelationByName(field.StandaloneRelation.Name)
+			if err != nil {
+				return nil, err
+			}
+			id = relation.Id
+		} else if field.Property != nil {
+			id = field.Property.ModelProperty.Id
+		} else {
+			reportEmbeddedFields(report, entity, field)
+			continue
+		}
+
+		var astField = findStructField(strct, field.Name)
+		if astField == nil {
+			return nil, fmt.Errorf("declaration of field %s not found", field.Name)
+		} else if len(astField.Names) > 1 {
+			return nil, fmt.Errorf("field %s shares its declaration (and the tag) with other fields, declare it separately", field.Name)
+		}
+
+		if edit, err := pinFieldTag(f, astField, id); err != nil {
+			return nil, fmt.Errorf("field %s: %s", field.Name, err)
+		} else if edit != nil {
+			edits = append(edits, *edit)
+		}
+	}
+	return edits, nil
+}
+
+// reportEmbeddedFields reports a warning for each property and standalone relation of the embedded struct (recursively)
+// without a uid annotation, as it can't be pinned: the annotation would apply to all the entities embedding the struct
+func reportEmbeddedFields(report func(*diagnostics.Diagnostic), entity *model.Entity, embedded *Field) {
+	for _, field := range embedded.Fields {
+		if field.Property != nil {
+			if annotation := field.Property.annotations["uid"]; annotation != nil && len(annotation.Value) > 0 {
+				continue
+			}
+		}
+
+		var id model.IdUid
+		var name string
+		if field.StandaloneRelation != nil {
+			relation, err := entity.FindRelationByName(field.StandaloneRelation.Name)
+			if err != nil {
+				continue
+			}
+			id, name = relation.Id, relation.Name
+		} else if field.Property != nil {
+			id, name = field.Property.ModelProperty.Id, field.Property.ModelProperty.Name
+		} else {
+			reportEmbeddedFields(report, entity, field)
+			continue
+		}
+
+		uid, err := id.GetUid()
+		if err != nil {
+			continue
+		}
+		report(diagnostics.Warning(diagnostics.CodeNotPinned, fmt.Sprintf("UID of %s.%s isn't pinned because it's declared "+
+			"in the embedded struct %s; unless the struct is embedded in other entities too, add uid:%d to its tag manually",
+			entity.Name, name, embedded.Type, uid)).At(field.pos).InEntity(entity.Name).InProperty(name))
+	}
+}
+
+// findStructDecl finds the top-level declaration of the given struct, as processed by astReader.entityLoader()
+func findStructDecl(file *ast.File, name string) (*ast.GenDecl, *ast.TypeSpec) {
+	for _, decl := range file.Decls {
+		if genDecl, isGenDecl := decl.(*ast.GenDecl); isGenDecl && genDecl.Tok == token.TYPE {
+			for _, spec := range genDecl.Specs {
+				if typeSpec := spec.(*ast.TypeSpec); typeSpec.Name.Name == name {
+					if _, isStruct := typeSpec.Type.(*ast.StructType); isStruct {
+						return genDecl, typeSpec
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// findStructField finds the field by its name, or by its type name if embedded
+func findStructField(strct *ast.StructType, name string) *ast.Field {
+	for _, field := range strct.Fields.List {
+		for _, ident := range field.Names {
+			if ident.Name == name {
+				return field
+			}
+		}
+		if len(field.Names) == 0 {
+			var typ = field.Type
+			if star, isStar := typ.(*ast.StarExpr); isStar {
+				typ = star.X
+			}
+			if selector, isSelector := typ.(*ast.SelectorExpr); isSelector {
+				typ = selector.Sel
+			}
+			if ident, isIdent := typ.(*ast.Ident); isIdent && ident.Name == name {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// pinFieldTag adds the uid annotation to the struct tag of the field, creating the tag if necessary
+func pinFieldTag(f *file, field *ast.Field, id model.IdUid) (*sourceEdit, error) {
+	if field.Tag == nil {
+		tag, err := pinTag("", id, supportedPropertyAnnotations)
+		if err != nil {
+			return nil, err
+		}
+		return &sourceEdit{offset: f.offset(field.Type.End()), text: " `" + tag + "`"}, nil
+	}
+
+	value, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := pinTag(value, id, supportedPropertyAnnotations)
+	if err != nil || tag == value {
+		return nil, err
+	}
+
+	var literal = strconv.Quote(tag)
+	if !strings.Contains(tag, "`") {
+		literal = "`" + tag + "`"
+	}
+	var offset = f.offset(field.Tag.Pos())
+	return &sourceEdit{offset: offset, length: f.offset(field.Tag.End()) - offset, text: literal}, nil
+}
+
+// pinEntityComment adds the uid annotation to the `objectbox:"..."` line of the entity doc comment (see
+// Entity.setAnnotations()), or adds a new comment line before the declaration if there's none
+func pinEntityComment(f *file, source []byte, decl *ast.GenDecl, spec *ast.TypeSpec, id model.IdUid) (*sourceEdit, error) {
+	// the same doc comment as used by astReader.entityLoader()
+	var doc = spec.Doc
+	var declarationPos = spec.Pos()
+	if doc == nil {
+		doc = decl.Doc
+		if !decl.Lparen.IsValid() {
+			declarationPos = decl.Pos()
+		}
+	}
+
+	var tags []commentTag
+	if doc != nil {
+		tags = commentTags(f, doc.List)
+	}
+	for _, tag := range tags {
+		if tag.annotations["uid"] != nil {
+			return pinCommentTag(tag, id)
+		}
+	}
+	for _, tag := range tags {
+		if tag.annotations != nil {
+			if doc != spec.Doc && decl.Lparen.IsValid() {
+				return nil, fmt.Errorf("the annotations are in the doc comment of the type group, move them to the struct")
+			}
+			return pinCommentTag(tag, id)
+		}
+	}
+
+	tag, err := pinTag("", id, supportedEntityAnnotations)
+	if err != nil {
+		return nil, err
+	}
+	// a new line before the declaration, with the same indentation
+	var offset = f.offset(declarationPos)
+	var lineStart = offset - f.fileset.Position(declarationPos).Column + 1
+	var indent = string(source[lineStart:offset])
+	return &sourceEdit{offset: lineStart, text: indent + "// `" + tag + "`\n"}, nil
+}
+
+// commentTag is a struct tag in a comment line, e.g. // `objectbox:"sync"`
+type commentTag struct {
+	offset      int    // of the tag contents (without the backquotes) in the source file
+	value       string // the tag contents
+	annotations map[string]*binding.Annotation
+}
+
+// commentTags lists the comment lines consisting of a tag, like parseCommentsLines() does
+func commentTags(f *file, comments []*ast.Comment) []commentTag {
+	var result []commentTag
+	for _, line := range parseCommentsLines(comments, f) {
+		var text = line.text
+		if len(text) < 2 || text[0] != '`' || text[len(text)-1] != '`' || !line.pos.IsValid() {
+			continue
+		}
+
+		var tag = commentTag{offset: f.lineOffset(line.pos) + 1, value: text[1 : len(text)-1]}
+		var annotations = make(map[string]*binding.Annotation)
+		if _, found := reflect.StructTag(tag.value).Lookup("objectbox"); found {
+			if err := parseAnnotations(tag.value, diagnostics.Position{}, &annotations, supportedEntityAnnotations); err == nil {
+				tag.annotations = annotations
+			}
+		}
+		result = append(result, tag)
+	}
+	return result
+}
+
+func pinCommentTag(tag commentTag, id model.IdUid) (*sourceEdit, error) {
+	value, err := pinTag(tag.value, id, supportedEntityAnnotations)
+	if err != nil || value == tag.value {
+		return nil, err
+	}
+	return &sourceEdit{offset: tag.offset, length: len(tag.value), text: value}, nil
+}
+
+// pinTag returns the struct tag (without the quotes) with the uid annotation added to its "objectbox" key, or filled in
+// if empty. The tag is returned unchanged if the annotation is already set.
+func pinTag(tag string, id model.IdUid, supportedAnnotations map[string]bool) (string, error) {
+	uid, err := id.GetUid()
+	if err != nil {
+		return "", err
+	}
+	var annotation = "uid:" + strconv.FormatUint(uid, 10)
+
+	var key = "objectbox"
+	contents, found := reflect.StructTag(tag).Lookup(key)
+	if !found {
+		key = "ObjectBox"
+		contents, found = reflect.StructTag(tag).Lookup(key)
+	}
+	if !found {
+		if len(strings.TrimSpace(tag)) == 0 {
+			return `objectbox:"` + annotation + `"`, nil
+		}
+		return tag + ` objectbox:"` + annotation + `"`, nil
+	}
+
+	// the contents must be found in the tag as-is, i.e. without escaped characters
+	var start = strings.Index(tag, key+`:"`) + len(key) + 2
+	if start < len(key)+2 || !strings.HasPrefix(tag[start:], contents+`"`) {
+		return "", fmt.Errorf("can't edit the tag %s, please add the %s annotation manually", tag, annotation)
+	}
+
+	var annotations = make(map[string]*binding.Annotation)
+	if err := binding.ParseAnnotations(contents, diagnostics.Position{Line: 1, Column: 1}, &annotations, supportedAnnotations); err != nil {
+		return "", err
+	}
+
+	var uidAnnotation = annotations["uid"]
+	if uidAnnotation == nil {
+		var separator = " "
+		if len(strings.TrimSpace(contents)) == 0 {
+			separator = ""
+		}
+		return tag[:start+len(contents)] + separator + annotation + tag[start+len(contents):], nil
+	} else if len(uidAnnotation.Value) == 0 {
+		var column = start + uidAnnotation.Pos.Column - 1
+		var length = len(uidValueRegexp.FindString(tag[column:]))
+		return tag[:column] + annotation + tag[column+length:], nil
+	}
+	return tag, nil
+}
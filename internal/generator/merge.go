@@ -23,12 +23,85 @@ package generator
 import (
 	"errors"
 	"fmt"
-	"log"
+	"strconv"
+	"strings"
 
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
 	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
 )
 
-func mergeBindingWithModelInfo(currentModel *model.ModelInfo, storedModel *model.ModelInfo) error {
+// Merge merges a model read from a source file (see CodeGenerator.ParseSource) into the stored model, assigning IDs
+// and UIDs to the new model elements, and finalizes the stored model. Warnings are reported using options.Report().
+func Merge(options Options, currentModel *model.ModelInfo, storedModel *model.ModelInfo) error {
+	if err := mergeBindingWithModelInfo(currentModel, storedModel, options.Report); err != nil {
+		return fmt.Errorf("can't merge model information: %s", err)
+	}
+
+	if err := mergeRelationTargets(currentModel, storedModel); err != nil {
+		return fmt.Errorf("can't merge model information: %s", err)
+	}
+
+	if err := storedModel.Finalize(); err != nil {
+		return fmt.Errorf("model finalization failed: %s", err)
+	}
+	return nil
+}
+
+// mergeRelationTargets sets the target entities of the standalone relations of an already merged model. When merging
+// multiple source files, it's only called after all of them have been merged, because a target entity may be declared
+// in any of them.
+func mergeRelationTargets(currentModel *model.ModelInfo, storedModel *model.ModelInfo) error {
+	for _, currentEntity := range currentModel.Entities {
+		if len(currentEntity.Relations) == 0 {
+			continue
+		}
+
+		storedEntity, err := findStoredEntity(currentEntity, storedModel)
+		if err != nil {
+			return fmt.Errorf("merging entity %s: %s", currentEntity.Name, err)
+		}
+
+		for _, currentRelation := range currentEntity.Relations {
+			if err := mergeRelationTarget(currentRelation, storedEntity, storedModel); err != nil {
+				return fmt.Errorf("merging entity %s: merging relation %s: %s", currentEntity.Name, currentRelation.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// findStoredEntity returns the stored entity the given entity has been merged into
+func findStoredEntity(currentEntity *model.Entity, storedModel *model.ModelInfo) (*model.Entity, error) {
+	uid, err := currentEntity.Id.GetUid()
+	if err != nil {
+		return nil, err
+	}
+	return storedModel.FindEntityByUid(uid)
+}
+
+func mergeRelationTarget(currentRelation *model.StandaloneRelation, storedEntity *model.Entity, storedModel *model.ModelInfo) error {
+	uid, err := currentRelation.Id.GetUid()
+	if err != nil {
+		return err
+	}
+	storedRelation, err := storedEntity.FindRelationByUid(uid)
+	if err != nil {
+		return err
+	}
+
+	// find the target entity & read it's ID/UID for the binding code
+	if targetEntity, err := storedModel.FindEntityByName(currentRelation.Target.Name); err != nil {
+		return err
+	} else if _, _, err = targetEntity.Id.Get(); err != nil {
+		return err
+	} else {
+		currentRelation.Target.Id = targetEntity.Id
+		storedRelation.SetTarget(targetEntity)
+	}
+	return nil
+}
+
+func mergeBindingWithModelInfo(currentModel *model.ModelInfo, storedModel *model.ModelInfo, report func(*diagnostics.Diagnostic)) error {
 	// we need to first prepare all entities - otherwise relations wouldn't be able to find them in the model
 	var models = make([]*model.Entity, len(currentModel.Entities))
 	var err error
@@ -40,7 +113,7 @@ func mergeBindingWithModelInfo(currentModel *model.ModelInfo, storedModel *model
 	}
 
 	for k, entity := range currentModel.Entities {
-		if err := mergeModelEntity(entity, models[k], storedModel); err != nil {
+		if err := mergeModelEntity(entity, models[k], storedModel, report); err != nil {
 			return fmt.Errorf("merging entity %s: %s", entity.Name, err)
 		}
 	}
@@ -84,10 +157,11 @@ func getModelEntity(currentEntity *model.Entity, storedModel *model.ModelInfo) (
 	return entity, nil
 }
 
-func mergeModelEntity(currentEntity *model.Entity, storedEntity *model.Entity, storedModel *model.ModelInfo) (err error) {
+func mergeModelEntity(currentEntity *model.Entity, storedEntity *model.Entity, storedModel *model.ModelInfo, report func(*diagnostics.Diagnostic)) (err error) {
 	storedEntity.Name = currentEntity.Name
 	storedEntity.Flags = currentEntity.Flags
 	storedEntity.Comments = currentEntity.Comments
+	storedEntity.RemovedUids = append([]model.Uid(nil), currentEntity.RemovedUids...)
 
 	if currentEntity.Meta != nil {
 		storedEntity.Meta = currentEntity.Meta.Merge(storedEntity)
@@ -106,7 +180,7 @@ func mergeModelEntity(currentEntity *model.Entity, storedEntity *model.Entity, s
 
 		// add all properties from the bindings to the model and update/rename the changed ones
 		for _, currentProperty := range currentEntity.Properties {
-			if modelProperty, err := getModelProperty(currentProperty, storedEntity, storedModel); err != nil {
+			if modelProperty, err := getModelProperty(currentProperty, storedEntity, storedModel, report); err != nil {
 				return fmt.Errorf("property %s: %s", currentProperty.Name, err)
 			} else if err := mergeModelProperty(currentProperty, modelProperty); err != nil {
 				return fmt.Errorf("merging property %s: %s", currentProperty.Name, err)
@@ -136,7 +210,7 @@ func mergeModelEntity(currentEntity *model.Entity, storedEntity *model.Entity, s
 		for _, currentRelation := range currentEntity.Relations {
 			if modelRelation, err := getModelRelation(currentRelation, storedEntity); err != nil {
 				return fmt.Errorf("relation %s: %s", currentRelation.Name, err)
-			} else if err := mergeModelRelation(currentRelation, modelRelation, storedModel); err != nil {
+			} else if err := mergeModelRelation(currentRelation, modelRelation); err != nil {
 				return fmt.Errorf("merging relation %s: %s", currentRelation.Name, err)
 			}
 		}
@@ -159,7 +233,7 @@ func mergeModelEntity(currentEntity *model.Entity, storedEntity *model.Entity, s
 	return nil
 }
 
-func getModelProperty(currentProperty *model.Property, storedEntity *model.Entity, storedModel *model.ModelInfo) (*model.Property, error) {
+func getModelProperty(currentProperty *model.Property, storedEntity *model.Entity, storedModel *model.ModelInfo, report func(*diagnostics.Diagnostic)) (*model.Property, error) {
 	if uid, err := currentProperty.Id.GetUidAllowZero(); err != nil {
 		return nil, err
 	} else if uid != 0 {
@@ -174,7 +248,14 @@ func getModelProperty(currentProperty *model.Property, storedEntity *model.Entit
 			return nil, fmt.Errorf("%v; %v", err, err2)
 		}
 
-		log.Printf("Notice - new UID was specified for the same property name '%s' - resetting value (recreating the property)", currentProperty.Name)
+		report(diagnostics.Warning(diagnostics.CodePropertyReset,
+			fmt.Sprintf("new UID was specified for the same property name '%s' - resetting value (recreating the property)", currentProperty.Name)).
+			InEntity(storedEntity.Name).InProperty(currentProperty.Name))
+
+		// the reset has been requested explicitly, there's no need to acknowledge the removal of the previous UID
+		if oldUid, err := property.Id.GetUid(); err == nil {
+			storedEntity.RemovedUids = append(storedEntity.RemovedUids, oldUid)
+		}
 		return property, nil
 	}
 
@@ -238,6 +319,12 @@ func mergeModelProperty(currentProperty *model.Property, storedProperty *model.P
 
 		storedProperty.Id = model.CreateIdUid(highestId+1, curUid)
 		storedProperty.Entity.LastPropertyId = storedProperty.Id
+
+		// the recreated property must never get the previous UID again, same as a removed one
+		var storedModel = storedProperty.Entity.Model
+		storedModel.RetiredPropertyUids = append(storedModel.RetiredPropertyUids, oldUid)
+	} else if err := checkPropertyTypeChange(currentProperty, storedProperty); err != nil {
+		return err
 	}
 
 	// TODO not sure we need this check
@@ -279,6 +366,29 @@ func mergeModelProperty(currentProperty *model.Property, storedProperty *model.P
 	return nil
 }
 
+// checkPropertyTypeChange fails if the stored data of an existing property (not being reset) can't be read as the new type
+func checkPropertyTypeChange(currentProperty *model.Property, storedProperty *model.Property) error {
+	// a new property, or an ID, which is always stored as Long regardless of its type in the source
+	if storedProperty.Type == 0 || currentProperty.IsIdProperty() || currentProperty.IsCompatibleType(storedProperty.Type) {
+		return nil
+	}
+
+	uid, err := storedProperty.Id.GetUid()
+	if err != nil {
+		return err
+	}
+	newUid, err := storedProperty.Entity.Model.GenerateUid()
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf(`incompatible type change from %s to %s, the stored data can't be converted:
+    [change/reset] apply a new UID %d to drop the stored data of the property (previous UID %d)
+    [revert] keep the %s type`,
+		model.PropertyTypeNames[storedProperty.Type], model.PropertyTypeNames[currentProperty.Type], newUid, uid,
+		model.PropertyTypeNames[storedProperty.Type])
+}
+
 func bindingPropertyExists(modelProperty *model.Property, bindingEntity *model.Entity) bool {
 	for _, bindingProperty := range bindingEntity.Properties {
 		if bindingProperty.Name == modelProperty.Name {
@@ -321,7 +431,7 @@ func getModelRelation(currentRelation *model.StandaloneRelation, storedEntity *m
 	return relation, nil
 }
 
-func mergeModelRelation(currentRelation *model.StandaloneRelation, storedRelation *model.StandaloneRelation, storedModel *model.ModelInfo) (err error) {
+func mergeModelRelation(currentRelation *model.StandaloneRelation, storedRelation *model.StandaloneRelation) (err error) {
 	storedRelation.Name = currentRelation.Name
 
 	if currentRelation.Meta != nil {
@@ -336,16 +446,7 @@ func mergeModelRelation(currentRelation *model.StandaloneRelation, storedRelatio
 		currentRelation.Id = storedRelation.Id
 	}
 
-	// find the target entity & read it's ID/UID for the binding code
-	if targetEntity, err := storedModel.FindEntityByName(currentRelation.Target.Name); err != nil {
-		return err
-	} else if _, _, err = targetEntity.Id.Get(); err != nil {
-		return err
-	} else {
-		currentRelation.Target.Id = targetEntity.Id
-		storedRelation.SetTarget(targetEntity)
-	}
-
+	// the target entity is set by mergeRelationTargets()
 	return nil
 }
 
@@ -358,3 +459,36 @@ func bindingRelationExists(modelRelation *model.StandaloneRelation, bindingEntit
 
 	return false
 }
+
+// checkDestructiveChanges fails if any of the changes removes stored data, unless acknowledged by a "removed" annotation
+// listing its UID, see Options.AllowDestructive
+func checkDestructiveChanges(modelInfo *model.ModelInfo, changes []model.Change) error {
+	var acknowledged = make(map[model.Uid]bool)
+	for _, entity := range modelInfo.Entities {
+		for _, uid := range entity.RemovedUids {
+			acknowledged[uid] = true
+		}
+	}
+
+	var report []string
+	var uids []string
+	for _, change := range changes {
+		// for a reset, it's the data of the previous UID which is removed
+		var uid = change.Uid
+		if change.Kind == model.PropertyReset {
+			uid = change.OldUid
+		}
+		if change.IsDestructive() && !acknowledged[uid] {
+			report = append(report, fmt.Sprintf("  - %s (UID %d)", change, uid))
+			uids = append(uids, strconv.FormatUint(uid, 10))
+		}
+	}
+	if len(report) == 0 {
+		return nil
+	}
+
+	return diagnostics.Error(diagnostics.CodeDestructive, fmt.Sprintf("refusing to apply model changes which would remove stored data on all devices after an upgrade:\n%s\n"+
+		"If this is intended, acknowledge the removals by annotating any of the entities with `objectbox:removed=%s` "+
+		"(or `objectbox:\"removed:%s\"` in Go), or run with -allow-destructive",
+		strings.Join(report, "\n"), strings.Join(uids, ";"), strings.Join(uids, ";")))
+}
@@ -0,0 +1,234 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package model
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// compatElement is an entity, property, index or relation, as seen by CheckCompatibility()
+type compatElement struct {
+	kind     string // "entity", "property", "index" or "relation"
+	name     string // qualified by the entity name, e.g. "Entity.property"
+	id       IdUid
+	owner    Uid       // UID of the entity of a property or a relation, UID of the property of an index
+	entity   *Entity   // only for entities
+	property *Property // only for properties
+}
+
+func (element *compatElement) String() string {
+	return fmt.Sprintf("%s %s %s", element.kind, element.name, element.id)
+}
+
+// compatElements lists the elements of a model in the model order, identified by UIDs
+type compatElements struct {
+	list  []*compatElement
+	byUid map[Uid]*compatElement
+}
+
+// CheckCompatibility verifies that the current model is a safe evolution of the base model, i.e. that a database created
+// with the base model can be opened with the current one: no UID is reused with a different meaning, no retired UID is
+// used again, no ID of the base model is reused for a new element and no property type is changed incompatibly.
+// Removals (see Change.IsDestructive()) are reported too, unless allowDestructive is true.
+// Returns the list of problems found, empty if the current model is compatible.
+func CheckCompatibility(base, current *ModelInfo, allowDestructive bool) []string {
+	var problems []string
+	var report = func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if err := base.Validate(); err != nil {
+		report("the base model is invalid: %s", err)
+		return problems
+	}
+	if err := current.Validate(); err != nil {
+		report("the current model is invalid: %s", err)
+		return problems
+	}
+
+	var baseElements = collectCompatElements(base, report)
+	var currentElements = collectCompatElements(current, report)
+	var baseRetired = retiredUidKinds(base)
+	var currentRetired = retiredUidKinds(current)
+
+	for _, element := range currentElements.list {
+		var uid = element.id.getUidSafe()
+		var baseElement = baseElements.byUid[uid]
+
+		if kind, retired := baseRetired[uid]; retired {
+			report("%s: UID %d has been retired in the base model (%s), it must not be used again", element, uid, kind)
+		} else if baseElement == nil && base.containsUid(uid) {
+			report("%s: UID %d is used by the base model (as a last ID), it must not be used again", element, uid)
+		} else if baseElement == nil {
+			if last := baseLastId(base, baseElements, element); element.id.getIdSafe() <= last.getIdSafe() {
+				report("%s: ID %d is not higher than the last %s ID %s of the base model, it may have been used before",
+					element, element.id.getIdSafe(), element.kind, last)
+			}
+		} else if baseElement.kind != element.kind || baseElement.owner != element.owner {
+			report("%s: UID %d is used by %s in the base model", element, uid, baseElement)
+		} else if baseElement.id.getIdSafe() != element.id.getIdSafe() {
+			report("%s: ID changed from %s in the base model", element, baseElement.id)
+		} else if element.property != nil && !element.property.IsCompatibleType(baseElement.property.Type) {
+			report("%s: incompatible type change from %s to %s, the stored data can't be converted",
+				element, PropertyTypeNames[baseElement.property.Type], PropertyTypeNames[element.property.Type])
+		}
+	}
+
+	for _, element := range baseElements.list {
+		var uid = element.id.getUidSafe()
+		if currentElements.byUid[uid] == nil {
+			if _, retired := currentRetired[uid]; !retired {
+				report("%s has been removed without retiring its UID %d", element, uid)
+			}
+		}
+	}
+
+	for _, uid := range sortedUids(baseRetired) {
+		if _, retired := currentRetired[uid]; !retired && currentElements.byUid[uid] == nil {
+			report("UID %d has been retired in the base model (%s) but isn't retired anymore", uid, baseRetired[uid])
+		}
+	}
+
+	var checkLastId = func(what string, baseLast, currentLast IdUid) {
+		if currentLast.getIdSafe() < baseLast.getIdSafe() {
+			report("%s has been lowered from %s to %s, IDs of the base model could be reused", what, baseLast, currentLast)
+		}
+	}
+	checkLastId("lastEntityId", base.LastEntityId, current.LastEntityId)
+	checkLastId("lastIndexId", base.LastIndexId, current.LastIndexId)
+	checkLastId("lastRelationId", base.LastRelationId, current.LastRelationId)
+	for _, element := range currentElements.list {
+		if baseElement := baseElements.byUid[element.id.getUidSafe()]; element.entity != nil && baseElement != nil && baseElement.entity != nil {
+			checkLastId("entity "+element.name+" lastPropertyId", baseElement.entity.LastPropertyId, element.entity.LastPropertyId)
+		}
+	}
+
+	if !allowDestructive {
+		for _, change := range Diff(base, current) {
+			if !change.IsDestructive() {
+				continue
+			}
+			if change.Kind == PropertyReset {
+				report("%s removes stored data", change) // the description includes the previous UID
+			} else {
+				report("%s (UID %d) removes stored data", change, change.Uid)
+			}
+		}
+	}
+
+	return problems
+}
+
+// CheckCompatibilityFiles reads the given model JSON files and checks their compatibility, see CheckCompatibility().
+func CheckCompatibilityFiles(fsys vfs.FS, basePath, currentPath string, allowDestructive bool) ([]string, error) {
+	base, err := readExistingModel(fsys, basePath)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readExistingModel(fsys, currentPath)
+	if err != nil {
+		return nil, err
+	}
+	return CheckCompatibility(base, current, allowDestructive), nil
+}
+
+// readExistingModel works like ReadModelFS() but fails if the file doesn't exist
+func readExistingModel(fsys vfs.FS, path string) (*ModelInfo, error) {
+	if !fileExists(fsys, path) {
+		return nil, fmt.Errorf("model file %s doesn't exist: %s", path, os.ErrNotExist)
+	}
+	return ReadModelFS(fsys, path)
+}
+
+func collectCompatElements(model *ModelInfo, report func(format string, args ...interface{})) compatElements {
+	var elements = compatElements{byUid: make(map[Uid]*compatElement)}
+	var add = func(element *compatElement) {
+		var uid = element.id.getUidSafe()
+		if other := elements.byUid[uid]; other != nil {
+			report("%s has the same UID as %s", element, other)
+			return
+		}
+		elements.list = append(elements.list, element)
+		elements.byUid[uid] = element
+	}
+
+	for _, entity := range model.Entities {
+		var entityUid = entity.Id.getUidSafe()
+		add(&compatElement{kind: "entity", name: entity.Name, id: entity.Id, entity: entity})
+		for _, property := range entity.Properties {
+			var name = entity.Name + "." + property.Name
+			add(&compatElement{kind: "property", name: name, id: property.Id, owner: entityUid, property: property})
+			if property.IndexId != nil {
+				add(&compatElement{kind: "index", name: name, id: *property.IndexId, owner: property.Id.getUidSafe()})
+			}
+		}
+		for _, relation := range entity.Relations {
+			add(&compatElement{kind: "relation", name: entity.Name + "." + relation.Name, id: relation.Id, owner: entityUid})
+		}
+	}
+	return elements
+}
+
+// baseLastId returns the last ID assigned by the base model to the elements of the same kind as the given new element
+func baseLastId(base *ModelInfo, baseElements compatElements, element *compatElement) IdUid {
+	switch element.kind {
+	case "entity":
+		return base.LastEntityId
+	case "index":
+		return base.LastIndexId
+	case "relation":
+		return base.LastRelationId
+	case "property":
+		// properties of a new entity can't collide with anything
+		if owner := baseElements.byUid[element.owner]; owner != nil && owner.entity != nil {
+			return owner.entity.LastPropertyId
+		}
+	}
+	return ""
+}
+
+// retiredUidKinds maps the retired UIDs of the model to the kind of the retired element
+func retiredUidKinds(model *ModelInfo) map[Uid]string {
+	var result = make(map[Uid]string)
+	var add = func(kind string, uids []Uid) {
+		for _, uid := range uids {
+			result[uid] = kind
+		}
+	}
+	add("entity", model.RetiredEntityUids)
+	add("property", model.RetiredPropertyUids)
+	add("index", model.RetiredIndexUids)
+	add("relation", model.RetiredRelationUids)
+	return result
+}
+
+func sortedUids(uids map[Uid]string) []Uid {
+	var result = make([]Uid, 0, len(uids))
+	for uid := range uids {
+		result = append(result, uid)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
@@ -0,0 +1,305 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeKind describes what changed in the model
+type ChangeKind string
+
+const (
+	EntityAdded          ChangeKind = "entity-added"
+	EntityRemoved        ChangeKind = "entity-removed"
+	EntityRenamed        ChangeKind = "entity-renamed"
+	EntityFlagsChanged   ChangeKind = "entity-flags-changed"
+	PropertyAdded        ChangeKind = "property-added"
+	PropertyRemoved      ChangeKind = "property-removed"
+	PropertyRenamed      ChangeKind = "property-renamed"
+	PropertyReset        ChangeKind = "property-reset"
+	PropertyTypeChanged  ChangeKind = "property-type-changed"
+	PropertyFlagsChanged ChangeKind = "property-flags-changed"
+	IndexAdded           ChangeKind = "index-added"
+	IndexRemoved         ChangeKind = "index-removed"
+	RelationAdded        ChangeKind = "relation-added"
+	RelationRemoved      ChangeKind = "relation-removed"
+	RelationRenamed      ChangeKind = "relation-renamed"
+)
+
+// Change describes a single difference between two versions of a model, see Diff().
+// Entity, Property and Relation hold the current names, or the last known ones for removed elements.
+type Change struct {
+	Kind     ChangeKind `json:"kind"`
+	Entity   string     `json:"entity"`
+	Property string     `json:"property,omitempty"`
+	Relation string     `json:"relation,omitempty"`
+	Uid      Uid        `json:"uid"`
+	OldUid   Uid        `json:"oldUid,omitempty"`   // only for resets
+	OldName  string     `json:"oldName,omitempty"`  // only for renames
+	OldType  string     `json:"oldType,omitempty"`  // only for type changes and resets
+	NewType  string     `json:"newType,omitempty"`  // only for type changes, resets and added properties
+	OldFlags string     `json:"oldFlags,omitempty"` // only for flag changes
+	NewFlags string     `json:"newFlags,omitempty"` // only for flag changes
+}
+
+// String describes the change in a human-readable form
+func (change Change) String() string {
+	var subject = "entity " + change.Entity
+	if len(change.Property) > 0 {
+		subject = "property " + change.Entity + "." + change.Property
+	} else if len(change.Relation) > 0 {
+		subject = "relation " + change.Entity + "." + change.Relation
+	}
+
+	switch change.Kind {
+	case EntityAdded, PropertyAdded, RelationAdded:
+		return subject + " added"
+	case EntityRemoved, PropertyRemoved, RelationRemoved:
+		return subject + " removed"
+	case EntityRenamed, PropertyRenamed, RelationRenamed:
+		return fmt.Sprintf("%s renamed from %s", subject, change.OldName)
+	case PropertyTypeChanged:
+		return fmt.Sprintf("%s type changed from %s to %s", subject, change.OldType, change.NewType)
+	case PropertyReset:
+		if change.OldType != change.NewType {
+			return fmt.Sprintf("%s reset (previous UID %d), type changed from %s to %s", subject, change.OldUid, change.OldType, change.NewType)
+		}
+		return fmt.Sprintf("%s reset (previous UID %d)", subject, change.OldUid)
+	case EntityFlagsChanged, PropertyFlagsChanged:
+		return fmt.Sprintf("%s flags changed from %s to %s", subject, change.OldFlags, change.NewFlags)
+	case IndexAdded:
+		return "index added to " + subject
+	case IndexRemoved:
+		return "index removed from " + subject
+	}
+	return subject + " changed"
+}
+
+// IsDestructive returns true if the change removes data stored in the database
+func (change Change) IsDestructive() bool {
+	switch change.Kind {
+	case EntityRemoved, PropertyRemoved, PropertyReset, RelationRemoved, IndexRemoved:
+		return true
+	}
+	return false
+}
+
+// Clone creates a deep copy of the model data, as stored in the model JSON file
+func (model *ModelInfo) Clone() (*ModelInfo, error) {
+	data, err := model.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	var clone = &ModelInfo{Rand: model.Rand}
+	if err = clone.unmarshal(data); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// Diff lists the changes from the old to the new model; elements are matched by their UIDs.
+// Changes of the current entities come first, in the order of the new model, followed by the removed entities.
+func Diff(old, new *ModelInfo) []Change {
+	var changes []Change
+
+	var oldEntities = make(map[Uid]*Entity)
+	for _, entity := range old.Entities {
+		oldEntities[entity.Id.getUidSafe()] = entity
+	}
+
+	var newEntities = make(map[Uid]bool)
+	for _, entity := range new.Entities {
+		var uid = entity.Id.getUidSafe()
+		newEntities[uid] = true
+
+		var oldEntity = oldEntities[uid]
+		if oldEntity == nil {
+			changes = append(changes, Change{Kind: EntityAdded, Entity: entity.Name, Uid: uid})
+			continue
+		}
+
+		if oldEntity.Name != entity.Name {
+			changes = append(changes, Change{Kind: EntityRenamed, Entity: entity.Name, Uid: uid, OldName: oldEntity.Name})
+		}
+		if oldEntity.Flags != entity.Flags {
+			changes = append(changes, Change{Kind: EntityFlagsChanged, Entity: entity.Name, Uid: uid,
+				OldFlags: entityFlagsString(oldEntity.Flags), NewFlags: entityFlagsString(entity.Flags)})
+		}
+		changes = append(changes, diffProperties(entity.Name, oldEntity.Properties, entity.Properties)...)
+		changes = append(changes, diffRelations(entity.Name, oldEntity.Relations, entity.Relations)...)
+	}
+
+	for _, entity := range old.Entities {
+		if uid := entity.Id.getUidSafe(); !newEntities[uid] {
+			changes = append(changes, Change{Kind: EntityRemoved, Entity: entity.Name, Uid: uid})
+		}
+	}
+
+	return changes
+}
+
+func diffProperties(entityName string, old, new []*Property) []Change {
+	var changes []Change
+
+	var oldProperties = make(map[Uid]*Property)
+	for _, property := range old {
+		oldProperties[property.Id.getUidSafe()] = property
+	}
+
+	var newProperties = make(map[Uid]bool)
+	var addedProperties = make(map[string]int) // index in changes by the lowercase name, to recognize resets
+	for _, property := range new {
+		var uid = property.Id.getUidSafe()
+		newProperties[uid] = true
+
+		var change = Change{Entity: entityName, Property: property.Name, Uid: uid}
+		var oldProperty = oldProperties[uid]
+		if oldProperty == nil {
+			change.Kind = PropertyAdded
+			change.NewType = PropertyTypeNames[property.Type]
+			addedProperties[strings.ToLower(property.Name)] = len(changes)
+			changes = append(changes, change)
+			continue
+		}
+
+		if oldProperty.Name != property.Name {
+			var rename = change
+			rename.Kind = PropertyRenamed
+			rename.OldName = oldProperty.Name
+			changes = append(changes, rename)
+		}
+
+		if oldProperty.Type != property.Type {
+			var typeChange = change
+			typeChange.Kind = PropertyTypeChanged
+			typeChange.OldType = PropertyTypeNames[oldProperty.Type]
+			typeChange.NewType = PropertyTypeNames[property.Type]
+			changes = append(changes, typeChange)
+		}
+
+		// the index flags change together with the index, there's no need to list them separately
+		var oldFlags, newFlags = oldProperty.Flags, property.Flags
+		if (oldProperty.IndexId == nil) != (property.IndexId == nil) {
+			const indexFlags = PropertyFlagIndexed | PropertyFlagIndexHash | PropertyFlagIndexHash64
+			oldFlags, newFlags = oldFlags&^indexFlags, newFlags&^indexFlags
+		}
+		if oldFlags != newFlags {
+			var flagsChange = change
+			flagsChange.Kind = PropertyFlagsChanged
+			flagsChange.OldFlags = propertyFlagsString(oldProperty.Flags)
+			flagsChange.NewFlags = propertyFlagsString(property.Flags)
+			changes = append(changes, flagsChange)
+		}
+
+		if oldProperty.IndexId == nil && property.IndexId != nil {
+			change.Kind = IndexAdded
+			changes = append(changes, change)
+		} else if oldProperty.IndexId != nil && property.IndexId == nil {
+			change.Kind = IndexRemoved
+			changes = append(changes, change)
+		}
+	}
+
+	for _, property := range old {
+		var uid = property.Id.getUidSafe()
+		if newProperties[uid] {
+			continue
+		}
+
+		// a property with the same name and a new UID has been reset, i.e. recreated
+		if index, found := addedProperties[strings.ToLower(property.Name)]; found {
+			changes[index].Kind = PropertyReset
+			changes[index].OldUid = uid
+			changes[index].OldType = PropertyTypeNames[property.Type]
+			delete(addedProperties, strings.ToLower(property.Name))
+			continue
+		}
+		changes = append(changes, Change{Kind: PropertyRemoved, Entity: entityName, Property: property.Name, Uid: uid})
+	}
+
+	return changes
+}
+
+func diffRelations(entityName string, old, new []*StandaloneRelation) []Change {
+	var changes []Change
+
+	var oldRelations = make(map[Uid]*StandaloneRelation)
+	for _, relation := range old {
+		oldRelations[relation.Id.getUidSafe()] = relation
+	}
+
+	var newRelations = make(map[Uid]bool)
+	for _, relation := range new {
+		var uid = relation.Id.getUidSafe()
+		newRelations[uid] = true
+
+		if oldRelation := oldRelations[uid]; oldRelation == nil {
+			changes = append(changes, Change{Kind: RelationAdded, Entity: entityName, Relation: relation.Name, Uid: uid})
+		} else if oldRelation.Name != relation.Name {
+			changes = append(changes, Change{Kind: RelationRenamed, Entity: entityName, Relation: relation.Name, Uid: uid, OldName: oldRelation.Name})
+		}
+	}
+
+	for _, relation := range old {
+		if uid := relation.Id.getUidSafe(); !newRelations[uid] {
+			changes = append(changes, Change{Kind: RelationRemoved, Entity: entityName, Relation: relation.Name, Uid: uid})
+		}
+	}
+
+	return changes
+}
+
+func entityFlagsString(flags EntityFlags) string {
+	var names []string
+	for flag := EntityFlags(1); flag > 0 && flag <= flags; flag <<= 1 {
+		if flags&flag != 0 {
+			names = append(names, flagName(EntityFlagNames[flag], int64(flag)))
+		}
+	}
+	return joinFlagNames(names)
+}
+
+func propertyFlagsString(flags PropertyFlags) string {
+	var names []string
+	for flag := PropertyFlags(1); flag > 0 && flag <= flags; flag <<= 1 {
+		if flags&flag != 0 {
+			names = append(names, flagName(PropertyFlagNames[flag], int64(flag)))
+		}
+	}
+	return joinFlagNames(names)
+}
+
+func flagName(name string, value int64) string {
+	if len(name) == 0 {
+		return fmt.Sprintf("%d", value)
+	}
+	return name
+}
+
+func joinFlagNames(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
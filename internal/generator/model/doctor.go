@@ -0,0 +1,384 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// Problem is an inconsistency of the model data, see Diagnose() and Repair()
+type Problem struct {
+	Message string
+	Fixable bool // Repair() fixes it without changing the IDs and UIDs of the existing elements
+}
+
+// Diagnose checks the model data for inconsistencies; unlike Validate(), it lists all of them instead of failing on the
+// first one. The model isn't changed, see Repair().
+func (model *ModelInfo) Diagnose() ([]Problem, error) {
+	clone, err := model.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return clone.Repair(), nil
+}
+
+// Repair fixes the problems which can be fixed safely, e.g. recomputes the last IDs or adds missing retired UID lists,
+// and returns all the problems found, including the ones it couldn't fix.
+func (model *ModelInfo) Repair() []Problem {
+	var d = &doctor{model: model}
+	d.check()
+
+	// make sure nothing is left undetected: once the fixable problems are fixed, the model must be valid
+	if !d.hasUnfixable() {
+		if err := model.Validate(); err != nil {
+			d.report(false, "%s", err)
+		}
+	}
+	return d.problems
+}
+
+// DoctorFile checks the given model JSON file (see Repair()) and returns the problems found, with the current file
+// content and the content after repairing it. If fix is true and anything was fixed, the file is overwritten.
+// On the OS file system, the model is locked against other processes meanwhile, see LockFile().
+func DoctorFile(fsys vfs.FS, path string, lockTimeout time.Duration, fix bool) (problems []Problem, before, after []byte, err error) {
+	if fsys == vfs.OS {
+		lock, err := lockFile(LockFile(path), lockTimeout)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		defer lock.release()
+	}
+
+	if before, err = fsys.ReadFile(path); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var model = &ModelInfo{fsys: fsys, path: path}
+	if err = model.unmarshal(before); err != nil {
+		return nil, nil, nil, fmt.Errorf("can't read file %s: %s", path, err)
+	}
+	defer func() { model.fsys = nil }()
+
+	problems = model.Repair()
+	var fixable = false
+	for _, problem := range problems {
+		fixable = fixable || problem.Fixable
+	}
+	if !fixable {
+		return problems, before, before, nil
+	}
+
+	if after, err = model.Marshal(); err != nil {
+		return nil, nil, nil, err
+	}
+	if fix && !bytes.Equal(before, after) {
+		if err = model.Write(); err != nil {
+			return nil, nil, nil, fmt.Errorf("can't write file %s: %s", path, err)
+		}
+	}
+	return problems, before, after, nil
+}
+
+type doctor struct {
+	model    *ModelInfo
+	problems []Problem
+}
+
+func (d *doctor) report(fixable bool, format string, args ...interface{}) {
+	d.problems = append(d.problems, Problem{Message: fmt.Sprintf(format, args...), Fixable: fixable})
+}
+
+func (d *doctor) hasUnfixable() bool {
+	for _, problem := range d.problems {
+		if !problem.Fixable {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *doctor) check() {
+	var model = d.model
+
+	if model.Entities == nil {
+		d.report(false, "entities are not defined or not an array")
+		return
+	}
+
+	d.checkRetired("retiredEntityUids", &model.RetiredEntityUids)
+	d.checkRetired("retiredIndexUids", &model.RetiredIndexUids)
+	d.checkRetired("retiredPropertyUids", &model.RetiredPropertyUids)
+	d.checkRetired("retiredRelationUids", &model.RetiredRelationUids)
+
+	var uids = make(map[Uid]string) // description of the element by UID, to find duplicates
+	var checkUid = func(what string, id IdUid) {
+		var uid = id.getUidSafe()
+		if other, found := uids[uid]; found {
+			d.report(false, "%s has the same UID as %s", what, other)
+		}
+		uids[uid] = what
+	}
+
+	var entityIds, indexIds, relationIds []IdUid
+	var entityNames = make(map[string]bool)
+	for _, entity := range model.Entities {
+		entity.Model = model
+		var what = fmt.Sprintf("entity %s %s", entity.Name, entity.Id)
+		if len(entity.Name) == 0 {
+			d.report(false, "%s: name is undefined", what)
+		} else if entityNames[strings.ToLower(entity.Name)] {
+			d.report(false, "%s: duplicate entity name", what)
+		}
+		entityNames[strings.ToLower(entity.Name)] = true
+
+		if err := entity.Id.Validate(); err != nil {
+			d.report(false, "%s: invalid ID: %s", what, err)
+			continue
+		}
+		checkUid(what, entity.Id)
+		entityIds = append(entityIds, entity.Id)
+
+		if entity.Properties == nil {
+			d.report(true, "%s: properties are not defined or not an array", what)
+			entity.Properties = make([]*Property, 0)
+		}
+
+		var propertyIds []IdUid
+		var propertyNames = make(map[string]bool)
+		var idProperty *Property
+		for _, property := range entity.Properties {
+			property.Entity = entity
+			var what = fmt.Sprintf("property %s.%s %s", entity.Name, property.Name, property.Id)
+			if len(property.Name) == 0 {
+				d.report(false, "%s: name is undefined", what)
+			} else if propertyNames[strings.ToLower(property.Name)] {
+				d.report(false, "%s: duplicate property name (note that property names are case insensitive)", what)
+			}
+			propertyNames[strings.ToLower(property.Name)] = true
+
+			if property.IsIdProperty() {
+				if idProperty != nil {
+					d.report(false, "%s: multiple properties marked as ID, %s and %s", what, idProperty.Name, property.Name)
+				}
+				idProperty = property
+			}
+
+			if len(property.RelationTarget) > 0 {
+				if target, _ := model.FindEntityByName(property.RelationTarget); target == nil {
+					d.report(false, "%s: relation target entity %s not found", what, property.RelationTarget)
+				}
+			}
+
+			if err := property.Id.Validate(); err != nil {
+				d.report(false, "%s: invalid ID: %s", what, err)
+			} else {
+				checkUid(what, property.Id)
+				propertyIds = append(propertyIds, property.Id)
+			}
+
+			if property.IndexId != nil {
+				if err := property.IndexId.Validate(); err != nil {
+					d.report(false, "%s: invalid index ID: %s", what, err)
+				} else {
+					checkUid(fmt.Sprintf("index %s of %s", *property.IndexId, what), *property.IndexId)
+					indexIds = append(indexIds, *property.IndexId)
+				}
+			}
+		}
+		d.checkDuplicateIds(what+" properties", propertyIds)
+		entity.LastPropertyId = d.checkLastId(what+": lastPropertyId", entity.LastPropertyId, propertyIds, &model.RetiredPropertyUids)
+
+		var relationNames = make(map[string]bool)
+		for _, relation := range entity.Relations {
+			relation.entity = entity
+			var what = fmt.Sprintf("relation %s.%s %s", entity.Name, relation.Name, relation.Id)
+			if len(relation.Name) == 0 {
+				d.report(false, "%s: name is undefined", what)
+			} else if relationNames[strings.ToLower(relation.Name)] {
+				d.report(false, "%s: duplicate relation name", what)
+			}
+			relationNames[strings.ToLower(relation.Name)] = true
+
+			if err := relation.Id.Validate(); err != nil {
+				d.report(false, "%s: invalid ID: %s", what, err)
+			} else {
+				checkUid(what, relation.Id)
+				relationIds = append(relationIds, relation.Id)
+			}
+		}
+	}
+
+	// relation targets are checked once all entity IDs are known
+	for _, entity := range model.Entities {
+		for _, relation := range entity.Relations {
+			d.checkRelationTarget(fmt.Sprintf("relation %s.%s %s", entity.Name, relation.Name, relation.Id), relation)
+		}
+	}
+
+	d.checkDuplicateIds("entities", entityIds)
+	d.checkDuplicateIds("indexes", indexIds)
+	d.checkDuplicateIds("relations", relationIds)
+
+	model.LastEntityId = d.checkLastId("lastEntityId", model.LastEntityId, entityIds, &model.RetiredEntityUids)
+	model.LastIndexId = d.checkLastId("lastIndexId", model.LastIndexId, indexIds, &model.RetiredIndexUids)
+	model.LastRelationId = d.checkLastId("lastRelationId", model.LastRelationId, relationIds, &model.RetiredRelationUids)
+
+	for _, retired := range [][]Uid{model.RetiredEntityUids, model.RetiredIndexUids, model.RetiredPropertyUids, model.RetiredRelationUids} {
+		for _, uid := range retired {
+			if what, found := uids[uid]; found {
+				d.report(false, "%s: the UID is listed as retired", what)
+			}
+		}
+	}
+}
+
+// checkRetired adds a missing retired UIDs list and removes duplicates from it
+func (d *doctor) checkRetired(what string, retired *[]Uid) {
+	if *retired == nil {
+		d.report(true, "%s are not defined or not an array", what)
+		*retired = make([]Uid, 0)
+		return
+	}
+
+	var unique = make([]Uid, 0, len(*retired))
+	var seen = make(map[Uid]bool)
+	for _, uid := range *retired {
+		if seen[uid] {
+			d.report(true, "%s: UID %d is listed multiple times", what, uid)
+			continue
+		}
+		seen[uid] = true
+		unique = append(unique, uid)
+	}
+	*retired = unique
+}
+
+func (d *doctor) checkDuplicateIds(what string, ids []IdUid) {
+	var seen = make(map[Id]IdUid)
+	for _, id := range ids {
+		if other, found := seen[id.getIdSafe()]; found {
+			d.report(false, "%s: %s and %s have the same ID", what, other, id)
+		}
+		seen[id.getIdSafe()] = id
+	}
+}
+
+// checkLastId checks that the last ID is the highest one of the current elements, or belongs to a retired element;
+// returns the repaired value. A missing or invalid last ID is only repaired if no element of the kind has been retired:
+// the retired UIDs don't record their IDs, which may be higher than the highest current one.
+func (d *doctor) checkLastId(what string, last IdUid, current []IdUid, retired *[]Uid) IdUid {
+	var highest = lastIdUid(current)
+	var unknownIds = func(problem string) IdUid {
+		d.report(false, "%s %s and can't be repaired: the IDs of the retired UIDs aren't known, they may be higher than the current ones",
+			what, problem)
+		return last
+	}
+
+	var known = func(uid Uid) bool {
+		for _, id := range current {
+			if id.getUidSafe() == uid {
+				return true
+			}
+		}
+		return searchSliceUid(*retired, uid)
+	}
+
+	if len(last) == 0 {
+		if len(*retired) > 0 {
+			return unknownIds("is missing")
+		} else if len(highest) > 0 {
+			d.report(true, "%s is missing, should be %s", what, highest)
+		}
+		return highest
+	}
+
+	if err := last.Validate(); err != nil {
+		if len(*retired) > 0 {
+			return unknownIds(fmt.Sprintf("is invalid (%s)", err))
+		} else if len(highest) > 0 {
+			d.report(true, "%s %s is invalid (%s), should be %s", what, last, err, highest)
+			return highest
+		}
+		d.report(false, "%s %s is invalid: %s", what, last, err)
+		return last
+	}
+
+	var id, uid = last.getIdSafe(), last.getUidSafe()
+	if len(highest) > 0 && id <= highest.getIdSafe() {
+		if last == highest {
+			return last
+		}
+
+		var problem = "is lower than the highest ID"
+		if id == highest.getIdSafe() {
+			problem = "doesn't match the element with the highest ID"
+		}
+		// the UID may have been assigned to an element already, make sure it's never reused
+		if !known(uid) {
+			d.report(true, "%s %s %s, should be %s (retiring UID %d)", what, last, problem, highest, uid)
+			*retired = append(*retired, uid)
+		} else {
+			d.report(true, "%s %s %s, should be %s", what, last, problem, highest)
+		}
+		return highest
+	}
+
+	// higher than all current elements: it must be a retired one
+	for _, other := range current {
+		if other.getUidSafe() == uid {
+			d.report(false, "%s %s has the UID of %s, but a different ID", what, last, other)
+			return last
+		}
+	}
+	if !searchSliceUid(*retired, uid) {
+		d.report(true, "%s %s doesn't match any current or retired element (retiring UID %d)", what, last, uid)
+		*retired = append(*retired, uid)
+	}
+	return last
+}
+
+// checkRelationTarget re-links a standalone relation to its target entity by the UID, if the target's ID doesn't match
+func (d *doctor) checkRelationTarget(what string, relation *StandaloneRelation) {
+	if len(relation.TargetId) == 0 {
+		return
+	} else if err := relation.TargetId.Validate(); err != nil {
+		d.report(false, "%s: invalid target ID: %s", what, err)
+		return
+	}
+
+	for _, entity := range d.model.Entities {
+		if entity.Id == relation.TargetId {
+			return
+		}
+	}
+
+	if target, _ := d.model.FindEntityByUid(relation.TargetId.getUidSafe()); target != nil {
+		d.report(true, "%s: target ID %s doesn't match entity %s %s", what, relation.TargetId, target.Name, target.Id)
+		relation.SetTarget(target)
+		return
+	}
+	d.report(false, "%s: target entity %s not found", what, relation.TargetId)
+}
@@ -35,6 +35,7 @@ type Entity struct {
 	Properties       []*Property           `json:"properties"`
 	Relations        []*StandaloneRelation `json:"relations,omitempty"`
 	UidRequest       bool                  `json:"-"` // used when the user gives an empty uid annotation
+	RemovedUids      []Uid                 `json:"-"` // removals acknowledged by the "removed" annotation
 	Meta             EntityMeta            `json:"-"`
 	CurrentlyPresent bool                  `json:"-"`
 	Comments         []string              `json:"-"`
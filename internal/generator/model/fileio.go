@@ -21,69 +21,156 @@
 package model
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"os"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
 )
 
-// LoadOrCreateModel reads a model file or creates a new one if it doesn't exist
+// LoadOrCreateModel reads a model file or creates a new one if it doesn't exist.
+// The model file is locked until Close(), see LoadOrCreateModelFS().
 func LoadOrCreateModel(path string) (model *ModelInfo, err error) {
-	if fileExists(path) {
-		return LoadModelFromJSONFile(path)
+	return LoadOrCreateModelFS(vfs.OS, path, DefaultLockTimeout)
+}
+
+// LoadOrCreateModelFS reads a model file from the given file system or creates a new one if it doesn't exist.
+// On the OS file system, the model is locked against other processes (see LockFile()) until Close(); if another process
+// holds the lock, it waits up to lockTimeout for the lock to be released.
+func LoadOrCreateModelFS(fsys vfs.FS, path string, lockTimeout time.Duration) (model *ModelInfo, err error) {
+	var lock *fileLock
+	if fsys == vfs.OS {
+		if lock, err = lockFile(LockFile(path), lockTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	if fileExists(fsys, path) {
+		model, err = loadModelFile(fsys, path)
+	} else {
+		model, err = createModelJSONFile(fsys, path)
 	}
-	return createModelJSONFile(path)
+
+	if err != nil {
+		lock.release()
+		return nil, err
+	}
+	model.lock = lock
+	return model, nil
+}
+
+// ReadModel reads a model file without keeping it open, or creates a new in-memory model if the file doesn't exist.
+// The returned model can't be written, use LoadOrCreateModel() if you need to persist changes.
+func ReadModel(path string) (model *ModelInfo, err error) {
+	return ReadModelFS(vfs.OS, path)
 }
 
-// Close and unlock model
+// ReadModelFS works like ReadModel, reading from the given file system
+func ReadModelFS(fsys vfs.FS, path string) (model *ModelInfo, err error) {
+	if !fileExists(fsys, path) {
+		return createModelInfo(), nil
+	}
+
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	model = &ModelInfo{}
+	if err = model.unmarshal(data); err != nil {
+		return nil, fmt.Errorf("can't read file %s: %s", path, err)
+	}
+	return model, nil
+}
+
+// Close the model and release its lock; it can't be written anymore afterwards
 func (model *ModelInfo) Close() error {
-	return model.file.Close()
+	model.fsys = nil
+	var err = model.lock.release()
+	model.lock = nil
+	return err
 }
 
-// Write current model data to file
-func (model *ModelInfo) Write() error {
-	data, err := json.MarshalIndent(model, "", "  ")
+// Reload reads the model file again, discarding the changes made in memory since it was loaded; the model stays open,
+// i.e. the lock isn't released in the meantime
+func (model *ModelInfo) Reload() error {
+	if model.fsys == nil {
+		return errors.New("the model was opened read-only")
+	}
+
+	data, err := model.fsys.ReadFile(model.path)
 	if err != nil {
 		return err
 	}
 
-	if err = model.file.Truncate(0); err != nil {
-		return err
+	var reloaded = ModelInfo{fsys: model.fsys, path: model.path, lock: model.lock, Rand: model.Rand}
+	if err = reloaded.unmarshal(data); err != nil {
+		return fmt.Errorf("can't read file %s: %s", model.path, err)
 	}
+	*model = reloaded
+	return nil
+}
 
-	if _, err := model.file.WriteAt(data, 0); err != nil {
-		return err
+// Marshal returns the model data as written to the model JSON file
+func (model *ModelInfo) Marshal() ([]byte, error) {
+	return json.MarshalIndent(model, "", "  ")
+}
+
+// Write current model data to file
+func (model *ModelInfo) Write() error {
+	if model.fsys == nil {
+		return errors.New("the model was opened read-only")
 	}
 
-	if err = model.file.Sync(); err != nil {
+	data, err := model.Marshal()
+	if err != nil {
 		return err
 	}
 
-	return nil
+	// don't touch an up-to-date file, keeping its modification time
+	if existing, err := model.fsys.ReadFile(model.path); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+
+	// keep the permissions of an existing file
+	var perm os.FileMode = 0600
+	if info, err := model.fsys.Stat(model.path); err == nil {
+		perm = info.Mode()
+	}
+
+	// never leave a partially written file behind, the UIDs would be lost
+	return vfs.WriteFileAtomic(model.fsys, model.path, data, perm)
 }
 
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
+func fileExists(fsys vfs.FS, path string) bool {
+	_, err := fsys.Stat(path)
 	return !os.IsNotExist(err)
 }
 
 func LoadModelFromJSONFile(path string) (model *ModelInfo, err error) {
-	model = &ModelInfo{}
+	return loadModelFile(vfs.OS, path)
+}
 
-	if model.file, err = os.OpenFile(path, os.O_RDWR, 0); err != nil {
+func loadModelFile(fsys vfs.FS, path string) (model *ModelInfo, err error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
 		return nil, err
 	}
 
-	data, err := ioutil.ReadAll(io.Reader(model.file))
-
-	if err == nil {
-		err = json.Unmarshal(data, model)
+	model = &ModelInfo{fsys: fsys, path: path}
+	if err = model.unmarshal(data); err != nil {
+		return nil, fmt.Errorf("can't read file %s: %s", path, err)
 	}
 
-	if err != nil {
-		defer model.Close()
-		return nil, fmt.Errorf("can't read file %s: %s", path, err)
+	return model, nil
+}
+
+func (model *ModelInfo) unmarshal(data []byte) error {
+	if err := json.Unmarshal(data, model); err != nil {
+		return err
 	}
 
 	// until objectbox-go 0.9 we didn't have model version in the file but it was basically version 4; recognize this
@@ -94,20 +181,16 @@ func LoadModelFromJSONFile(path string) (model *ModelInfo, err error) {
 
 	model.fillMissing()
 
-	return model, nil
+	return nil
 }
 
-func createModelJSONFile(path string) (model *ModelInfo, err error) {
+func createModelJSONFile(fsys vfs.FS, path string) (model *ModelInfo, err error) {
 	model = createModelInfo()
-
-	// create a file handle so to have an exclusive access
-	if model.file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600); err != nil {
-		return nil, err
-	}
+	model.fsys = fsys
+	model.path = path
 
 	// write it with initial content (so that we know it's writable & it would have correct contents on next tool run)
 	if err = model.Write(); err != nil {
-		defer model.Close()
 		return nil, fmt.Errorf("can't write file %s: %s", path, err)
 	}
 
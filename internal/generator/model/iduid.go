@@ -98,7 +98,12 @@ func (str IdUid) getComponent(n, bitsize int, allowZero bool) (uint64, error) {
 		return 0, errors.New(componentNamesErr[n] + " is undefined")
 	}
 
-	idStr := strings.Split(string(str), ":")[n]
+	var parts = strings.Split(string(str), ":")
+	if len(parts) <= n {
+		return 0, errors.New("invalid id format - " + componentNamesErr[n] + " is missing")
+	}
+
+	idStr := parts[n]
 	if component, err := strconv.ParseUint(idStr, 10, bitsize); err != nil {
 		return 0, fmt.Errorf("can't parse '%s' as unsigned int: %s", idStr, err)
 	} else if component == 0 && !allowZero {
@@ -0,0 +1,135 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package model
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultLockTimeout is how long LoadOrCreateModel() waits for another process to release the model file lock
+const DefaultLockTimeout = 30 * time.Second
+
+// lockRetryInterval is how often the lock is attempted while waiting for another process to release it
+const lockRetryInterval = 50 * time.Millisecond
+
+// LockFile returns the path of the lock file guarding the given model JSON file.
+// A separate file is locked because the model JSON file itself is replaced on each write.
+func LockFile(modelInfoFile string) string {
+	return modelInfoFile + ".lock"
+}
+
+// fileLock is an advisory inter-process lock, e.g. flock() on Linux. It only guards against other processes taking
+// the same lock, not against plain reads and writes. The lock file contains the holder description while locked.
+type fileLock struct {
+	path string
+	file *os.File
+}
+
+// lockFile takes an exclusive lock of the given file, creating the file if it doesn't exist. If the lock is held by
+// another process, it's retried until the timeout (a negative timeout means trying only once).
+func lockFile(path string, timeout time.Duration) (*fileLock, error) {
+	var deadline = time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("can't open lock file %s: %s", path, err)
+		}
+
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("can't lock %s: %s", path, err)
+		}
+
+		if locked {
+			// the previous holder removes the file when releasing the lock, make sure we've locked the current one
+			if current, err := os.Stat(path); err == nil {
+				if opened, err := file.Stat(); err == nil && os.SameFile(current, opened) {
+					return newFileLock(path, file)
+				}
+			}
+			unlock(file)
+			file.Close()
+			continue
+		}
+		file.Close()
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("can't lock %s within %v: the model is in use by %s", path, timeout, readHolder(path))
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// newFileLock writes the holder description to the locked file, for the processes waiting for the lock
+func newFileLock(path string, file *os.File) (*fileLock, error) {
+	var err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteAt([]byte(holderDescription()), 0)
+	}
+	if err != nil {
+		unlock(file)
+		file.Close()
+		return nil, fmt.Errorf("can't write lock file %s: %s", path, err)
+	}
+	return &fileLock{path: path, file: file}, nil
+}
+
+// release removes the lock file and releases the lock
+func (lock *fileLock) release() error {
+	if lock == nil || lock.file == nil {
+		return nil
+	}
+
+	// removing an open file may fail on some platforms (Windows); the file is then only cleared and reused next time
+	if os.Remove(lock.path) != nil {
+		lock.file.Truncate(0)
+	}
+
+	var err = unlock(lock.file)
+	if closeErr := lock.file.Close(); err == nil {
+		err = closeErr
+	}
+	lock.file = nil
+	return err
+}
+
+// holderDescription describes the current process, e.g. "process 1234 (objectbox-generator -cpp) on host, since ..."
+func holderDescription() string {
+	var host, _ = os.Hostname()
+	if len(host) == 0 {
+		host = "unknown host"
+	}
+	return fmt.Sprintf("process %d (%s) on %s, since %s", os.Getpid(), strings.Join(os.Args, " "), host, time.Now().Format(time.RFC3339))
+}
+
+// readHolder returns the holder description from the lock file
+func readHolder(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil || len(strings.TrimSpace(string(data))) == 0 {
+		return "another process"
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -24,8 +24,9 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"os"
 	"strings"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
 )
 
 // Id identifies a model element locally (e.g. property inside an entity)
@@ -61,7 +62,9 @@ type ModelInfo struct {
 	RetiredRelationUids  []Uid     `json:"retiredRelationUids"`
 	Version              int       `json:"version"` // user specified version
 
-	file *os.File   // file handle, locked while the model is open
+	fsys vfs.FS     // file system to write the model to, nil if read-only or closed
+	path string     // model file path in fsys
+	lock *fileLock  // held while the model is open for writing, see LoadOrCreateModelFS()
 	Rand *rand.Rand `json:"-"` // seeded random number generator
 }
 
@@ -149,6 +149,23 @@ func (property *Property) hasValidTypeAsId(acceptedTypes []PropertyType) bool {
 	}
 }
 
+// IsCompatibleType checks whether the data stored with the given (previous) type can be read as the property's type,
+// i.e. whether the type can be changed without resetting the property data (assigning a new UID)
+func (property *Property) IsCompatibleType(storedType PropertyType) bool {
+	if property.Type == storedType {
+		return true
+	}
+
+	// all of these are stored as 64-bit integers; only the meaning of the dates differs (milliseconds vs nanoseconds)
+	var isLong = func(t PropertyType) bool {
+		return t == PropertyTypeLong || t == PropertyTypeRelation || t == PropertyTypeDate || t == PropertyTypeDateNano
+	}
+	var isDate = func(t PropertyType) bool {
+		return t == PropertyTypeDate || t == PropertyTypeDateNano
+	}
+	return isLong(property.Type) && isLong(storedType) && !(isDate(property.Type) && isDate(storedType))
+}
+
 // CreateIndex creates an index
 func (property *Property) CreateIndex() error {
 	if property.IndexId != nil {
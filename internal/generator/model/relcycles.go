@@ -63,7 +63,7 @@ func (entity *Entity) checkRelationCycles(recursionStack *map[*Entity]bool, path
 }
 
 func checkRelationCycle(recursionStack *map[*Entity]bool, path string, relTarget *Entity) error {
-	// this happens if the entity containing this relation haven't been defined in this file
+	// this happens if the target entity isn't in the model
 	if relTarget == nil {
 		return nil
 	}
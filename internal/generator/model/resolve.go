@@ -0,0 +1,529 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// git conflict markers; the base section is only present with the "diff3" (or "zdiff3") conflict style
+const (
+	conflictOursMarker   = "<<<<<<<"
+	conflictBaseMarker   = "|||||||"
+	conflictSplitMarker  = "======="
+	conflictTheirsMarker = ">>>>>>>"
+)
+
+// splitConflicts reconstructs both sides of a file with git conflict markers, and their common base if known
+func splitConflicts(data []byte) (ours, base, theirs []byte, err error) {
+	const (
+		common = iota
+		inOurs
+		inBase
+		inTheirs
+	)
+
+	var state = common
+	var conflicts, bases int
+	for i, line := range bytes.SplitAfter(data, []byte("\n")) {
+		var marker = func(prefix string) bool {
+			return bytes.HasPrefix(line, []byte(prefix))
+		}
+		var unexpected = func() error {
+			return fmt.Errorf("unexpected conflict marker on line %d", i+1)
+		}
+
+		switch {
+		case marker(conflictOursMarker):
+			if state != common {
+				return nil, nil, nil, unexpected()
+			}
+			state = inOurs
+			conflicts++
+		case marker(conflictBaseMarker):
+			if state != inOurs {
+				return nil, nil, nil, unexpected()
+			}
+			state = inBase
+			bases++
+		case marker(conflictSplitMarker) && len(bytes.TrimSpace(line)) == len(conflictSplitMarker):
+			if state != inOurs && state != inBase {
+				return nil, nil, nil, unexpected()
+			}
+			state = inTheirs
+		case marker(conflictTheirsMarker):
+			if state != inTheirs {
+				return nil, nil, nil, unexpected()
+			}
+			state = common
+		case state == inOurs:
+			ours = append(ours, line...)
+		case state == inBase:
+			base = append(base, line...)
+		case state == inTheirs:
+			theirs = append(theirs, line...)
+		default:
+			ours = append(ours, line...)
+			base = append(base, line...)
+			theirs = append(theirs, line...)
+		}
+	}
+
+	if state != common {
+		return nil, nil, nil, errors.New("unterminated conflict at the end of the file")
+	} else if conflicts == 0 {
+		return nil, nil, nil, errors.New("no conflict markers found")
+	} else if bases != conflicts {
+		base = nil
+	}
+	return ours, base, theirs, nil
+}
+
+// ResolveConflicts reconciles both sides of a model JSON file with git conflict markers, matching the model elements
+// by their UIDs: the elements added on either side are kept, the removed ones stay removed (retired UIDs are merged)
+// and the last IDs are the highest of both sides. Elements added on "their" side (the branch being merged) get new IDs
+// if they collide with the ones added on "our" side. If an element was changed on both sides, e.g. renamed, the
+// changed side can only be recognized with the "diff3" conflict style (i.e. the base is included), otherwise it fails.
+func ResolveConflicts(data []byte) (*ModelInfo, error) {
+	oursData, baseData, theirsData, err := splitConflicts(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sides aren't validated: they include the parts merged without conflicts, i.e. also the other side's changes,
+	// so e.g. the lastPropertyId of one side may be lower than a property added on the other one
+	var parse = func(side string, data []byte) (*ModelInfo, error) {
+		var model = &ModelInfo{}
+		if err := model.unmarshal(data); err != nil {
+			return nil, fmt.Errorf("can't parse %s side of the conflict: %s", side, err)
+		} else if model.Entities == nil {
+			return nil, fmt.Errorf("%s side of the conflict is invalid: entities are not defined or not an array", side)
+		}
+		return model, nil
+	}
+
+	var r = conflictResolver{}
+	if r.ours, err = parse("our", oursData); err != nil {
+		return nil, err
+	} else if r.theirs, err = parse("their", theirsData); err != nil {
+		return nil, err
+	} else if baseData != nil {
+		if r.base, err = parse("the base", baseData); err != nil {
+			return nil, err
+		}
+	}
+	return r.resolve()
+}
+
+// ResolveConflictsFile resolves the conflicts in the given model JSON file (see ResolveConflicts()) and overwrites it
+// with the result. On the OS file system, the model is locked against other processes meanwhile, see LockFile().
+func ResolveConflictsFile(fsys vfs.FS, path string, lockTimeout time.Duration) (*ModelInfo, error) {
+	if fsys == vfs.OS {
+		lock, err := lockFile(LockFile(path), lockTimeout)
+		if err != nil {
+			return nil, err
+		}
+		defer lock.release()
+	}
+
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := ResolveConflicts(data)
+	if err != nil {
+		return nil, fmt.Errorf("can't resolve conflicts in %s: %s", path, err)
+	}
+
+	model.fsys = fsys
+	model.path = path
+	defer func() { model.fsys = nil }()
+	if err = model.Write(); err != nil {
+		return nil, fmt.Errorf("can't write file %s: %s", path, err)
+	}
+	return model, nil
+}
+
+type conflictResolver struct {
+	ours   *ModelInfo // the result is built in place of our side
+	theirs *ModelInfo
+	base   *ModelInfo // nil if not known
+
+	// UIDs of the indexes and relations known to our side, including the retired ones
+	ourIndexes   map[Uid]bool
+	ourRelations map[Uid]bool
+
+	// entities added on their side which got a new ID, by UID
+	renumberedEntities map[Uid]IdUid
+}
+
+func (r *conflictResolver) resolve() (*ModelInfo, error) {
+	var merged = r.ours
+	var theirs = r.theirs
+
+	r.ourIndexes = uidSet(merged.RetiredIndexUids)
+	r.ourRelations = uidSet(merged.RetiredRelationUids)
+	for _, entity := range merged.Entities {
+		for _, property := range entity.Properties {
+			if property.IndexId != nil {
+				r.ourIndexes[property.IndexId.getUidSafe()] = true
+			}
+		}
+		for _, relation := range entity.Relations {
+			r.ourRelations[relation.Id.getUidSafe()] = true
+		}
+	}
+	r.renumberedEntities = make(map[Uid]IdUid)
+
+	// entities removed on their side
+	var entities = make([]*Entity, 0, len(merged.Entities))
+	for _, entity := range merged.Entities {
+		if !searchSliceUid(theirs.RetiredEntityUids, entity.Id.getUidSafe()) {
+			entities = append(entities, entity)
+		}
+	}
+	merged.Entities = entities
+
+	var ourLastId = merged.LastEntityId.getIdSafe()
+	var nextId = maxId(ourLastId, theirs.LastEntityId.getIdSafe())
+	for _, theirEntity := range theirs.Entities {
+		var uid = theirEntity.Id.getUidSafe()
+		if entity, _ := merged.FindEntityByUid(uid); entity != nil {
+			if err := r.mergeEntity(entity, theirEntity); err != nil {
+				return nil, err
+			}
+		} else if !searchSliceUid(merged.RetiredEntityUids, uid) {
+			// added on their side
+			if other, _ := merged.FindEntityByName(theirEntity.Name); other != nil {
+				return nil, fmt.Errorf("entity %s was added on both sides with different UIDs (%s and %s), remove one of them on its branch first",
+					theirEntity.Name, other.Id, theirEntity.Id)
+			}
+			if theirEntity.Id.getIdSafe() <= ourLastId {
+				nextId++
+				theirEntity.Id = CreateIdUid(nextId, uid)
+				r.renumberedEntities[uid] = theirEntity.Id
+			}
+			merged.Entities = append(merged.Entities, theirEntity)
+		}
+	}
+
+	r.renumberIndexesAndRelations()
+	r.renumberDuplicates()
+
+	var entityIds, indexIds, relationIds []IdUid
+	for _, entity := range merged.Entities {
+		entity.Model = merged
+		entityIds = append(entityIds, entity.Id)
+		var propertyIds []IdUid
+		for _, property := range entity.Properties {
+			property.Entity = entity
+			propertyIds = append(propertyIds, property.Id)
+			if property.IndexId != nil {
+				indexIds = append(indexIds, *property.IndexId)
+			}
+		}
+		entity.LastPropertyId = lastIdUid(propertyIds, entity.LastPropertyId)
+		for _, relation := range entity.Relations {
+			relation.entity = entity
+			relationIds = append(relationIds, relation.Id)
+			if targetId, renumbered := r.renumberedEntities[relation.TargetId.getUidSafe()]; renumbered {
+				relation.TargetId = targetId
+			}
+		}
+	}
+	merged.LastEntityId = lastIdUid(entityIds, merged.LastEntityId, theirs.LastEntityId)
+	merged.LastIndexId = lastIdUid(indexIds, merged.LastIndexId, theirs.LastIndexId)
+	merged.LastRelationId = lastIdUid(relationIds, merged.LastRelationId, theirs.LastRelationId)
+
+	merged.RetiredEntityUids = uidUnion(merged.RetiredEntityUids, theirs.RetiredEntityUids)
+	merged.RetiredIndexUids = uidUnion(merged.RetiredIndexUids, theirs.RetiredIndexUids)
+	merged.RetiredPropertyUids = uidUnion(merged.RetiredPropertyUids, theirs.RetiredPropertyUids)
+	merged.RetiredRelationUids = uidUnion(merged.RetiredRelationUids, theirs.RetiredRelationUids)
+
+	if theirs.ModelVersion > merged.ModelVersion {
+		merged.ModelVersion = theirs.ModelVersion
+	}
+	if theirs.MinimumParserVersion > merged.MinimumParserVersion {
+		merged.MinimumParserVersion = theirs.MinimumParserVersion
+	}
+	if theirs.Version > merged.Version {
+		merged.Version = theirs.Version
+	}
+
+	if err := merged.Validate(); err != nil {
+		return nil, fmt.Errorf("the resolved model is invalid: %s", err)
+	}
+	return merged, nil
+}
+
+// mergeEntity merges their side of an entity present on both sides into ours
+func (r *conflictResolver) mergeEntity(entity, theirEntity *Entity) error {
+	var uid = entity.Id.getUidSafe()
+	var baseEntity *Entity
+	if r.base != nil {
+		baseEntity, _ = r.base.FindEntityByUid(uid)
+	}
+
+	type entityFields struct {
+		Name  string
+		Flags EntityFlags
+	}
+	var baseFields interface{}
+	if baseEntity != nil {
+		baseFields = entityFields{baseEntity.Name, baseEntity.Flags}
+	}
+	if useTheirs, err := r.pickTheirs("entity "+entity.Name, entityFields{entity.Name, entity.Flags},
+		entityFields{theirEntity.Name, theirEntity.Flags}, baseFields); err != nil {
+		return err
+	} else if useTheirs {
+		entity.Name = theirEntity.Name
+		entity.Flags = theirEntity.Flags
+	}
+
+	// properties removed on their side
+	var properties = make([]*Property, 0, len(entity.Properties))
+	for _, property := range entity.Properties {
+		if !searchSliceUid(r.theirs.RetiredPropertyUids, property.Id.getUidSafe()) {
+			properties = append(properties, property)
+		}
+	}
+	entity.Properties = properties
+
+	var ourLastId = entity.LastPropertyId.getIdSafe()
+	var nextId = maxId(ourLastId, theirEntity.LastPropertyId.getIdSafe())
+	for _, theirProperty := range theirEntity.Properties {
+		var uid = theirProperty.Id.getUidSafe()
+		if property, _ := entity.FindPropertyByUid(uid); property != nil {
+			var baseProperty interface{}
+			if baseEntity != nil {
+				if p, _ := baseEntity.FindPropertyByUid(uid); p != nil {
+					baseProperty = p
+				}
+			}
+			if useTheirs, err := r.pickTheirs("property "+entity.Name+"."+property.Name, property, theirProperty, baseProperty); err != nil {
+				return err
+			} else if useTheirs {
+				*property = *theirProperty
+			}
+		} else if !searchSliceUid(r.ours.RetiredPropertyUids, uid) {
+			// added on their side
+			if other, _ := entity.FindPropertyByName(theirProperty.Name); other != nil {
+				return fmt.Errorf("property %s.%s was added on both sides with different UIDs (%s and %s), remove one of them on its branch first",
+					entity.Name, theirProperty.Name, other.Id, theirProperty.Id)
+			}
+			if theirProperty.Id.getIdSafe() <= ourLastId {
+				nextId++
+				theirProperty.Id = CreateIdUid(nextId, uid)
+			}
+			entity.Properties = append(entity.Properties, theirProperty)
+		}
+	}
+
+	var propertyIds []IdUid
+	for _, property := range entity.Properties {
+		propertyIds = append(propertyIds, property.Id)
+	}
+	entity.LastPropertyId = lastIdUid(propertyIds, entity.LastPropertyId, theirEntity.LastPropertyId)
+
+	// relations removed on their side
+	var relations = make([]*StandaloneRelation, 0, len(entity.Relations))
+	for _, relation := range entity.Relations {
+		if !searchSliceUid(r.theirs.RetiredRelationUids, relation.Id.getUidSafe()) {
+			relations = append(relations, relation)
+		}
+	}
+	entity.Relations = relations
+
+	for _, theirRelation := range theirEntity.Relations {
+		var uid = theirRelation.Id.getUidSafe()
+		if relation, _ := entity.FindRelationByUid(uid); relation != nil {
+			var baseRelation interface{}
+			if baseEntity != nil {
+				if rel, _ := baseEntity.FindRelationByUid(uid); rel != nil {
+					baseRelation = rel
+				}
+			}
+			if useTheirs, err := r.pickTheirs("relation "+entity.Name+"."+relation.Name, relation, theirRelation, baseRelation); err != nil {
+				return err
+			} else if useTheirs {
+				*relation = *theirRelation
+			}
+		} else if !searchSliceUid(r.ours.RetiredRelationUids, uid) {
+			// added on their side, the ID is checked in renumberIndexesAndRelations()
+			if other, _ := entity.FindRelationByName(theirRelation.Name); other != nil {
+				return fmt.Errorf("relation %s.%s was added on both sides with different UIDs (%s and %s), remove one of them on its branch first",
+					entity.Name, theirRelation.Name, other.Id, theirRelation.Id)
+			}
+			entity.Relations = append(entity.Relations, theirRelation)
+		}
+	}
+	return nil
+}
+
+// pickTheirs decides which side of an element changed on both sides to use: theirs if ours is the same as the base,
+// ours if theirs is the same as the base (or if both are the same). Fails if both have changed or the base isn't known.
+func (r *conflictResolver) pickTheirs(what string, ours, theirs, base interface{}) (bool, error) {
+	var oursJSON, _ = json.Marshal(ours)
+	var theirsJSON, _ = json.Marshal(theirs)
+	if bytes.Equal(oursJSON, theirsJSON) {
+		return false, nil
+	}
+
+	if r.base == nil {
+		return false, fmt.Errorf("%s differs on both sides; resolve the conflict manually, or use the diff3 conflict "+
+			"style (`git config merge.conflictStyle diff3`) so that the changed side can be recognized", what)
+	}
+
+	if base != nil {
+		var baseJSON, _ = json.Marshal(base)
+		if bytes.Equal(oursJSON, baseJSON) {
+			return true, nil
+		} else if bytes.Equal(theirsJSON, baseJSON) {
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("%s was changed on both sides, resolve the conflict manually", what)
+}
+
+// renumberIndexesAndRelations gives new IDs to the indexes and relations added on their side which collide with ours
+func (r *conflictResolver) renumberIndexesAndRelations() {
+	var ourLastIndexId = r.ours.LastIndexId.getIdSafe()
+	var nextIndexId = maxId(ourLastIndexId, r.theirs.LastIndexId.getIdSafe())
+	var ourLastRelationId = r.ours.LastRelationId.getIdSafe()
+	var nextRelationId = maxId(ourLastRelationId, r.theirs.LastRelationId.getIdSafe())
+
+	for _, entity := range r.ours.Entities {
+		for _, property := range entity.Properties {
+			if property.IndexId == nil {
+				continue
+			}
+			var uid = property.IndexId.getUidSafe()
+			if !r.ourIndexes[uid] && property.IndexId.getIdSafe() <= ourLastIndexId {
+				nextIndexId++
+				var indexId = CreateIdUid(nextIndexId, uid)
+				property.IndexId = &indexId
+			}
+		}
+
+		for _, relation := range entity.Relations {
+			var uid = relation.Id.getUidSafe()
+			if !r.ourRelations[uid] && relation.Id.getIdSafe() <= ourLastRelationId {
+				nextRelationId++
+				relation.Id = CreateIdUid(nextRelationId, uid)
+			}
+		}
+	}
+}
+
+// renumberDuplicates gives new IDs to the elements added on both sides in the parts merged without conflicts: these
+// are present on both sides and thus not recognized as added on their side, yet their IDs may collide
+func (r *conflictResolver) renumberDuplicates() {
+	var entityIds, indexIds, relationIds []*IdUid
+	for _, entity := range r.ours.Entities {
+		entityIds = append(entityIds, &entity.Id)
+		var propertyIds []*IdUid
+		for _, property := range entity.Properties {
+			propertyIds = append(propertyIds, &property.Id)
+			if property.IndexId != nil {
+				indexIds = append(indexIds, property.IndexId)
+			}
+		}
+		renumberDuplicateIds(propertyIds, entity.LastPropertyId)
+		for _, relation := range entity.Relations {
+			relationIds = append(relationIds, &relation.Id)
+		}
+	}
+
+	for uid, id := range renumberDuplicateIds(entityIds, r.ours.LastEntityId, r.theirs.LastEntityId) {
+		r.renumberedEntities[uid] = id
+	}
+	renumberDuplicateIds(indexIds, r.ours.LastIndexId, r.theirs.LastIndexId)
+	renumberDuplicateIds(relationIds, r.ours.LastRelationId, r.theirs.LastRelationId)
+}
+
+// renumberDuplicateIds gives the elements with the same ID as a previous one new IDs, above all the given ones
+func renumberDuplicateIds(ids []*IdUid, lastIds ...IdUid) map[Uid]IdUid {
+	var next Id
+	for _, id := range ids {
+		next = maxId(next, id.getIdSafe())
+	}
+	for _, id := range lastIds {
+		next = maxId(next, id.getIdSafe())
+	}
+
+	var seen = make(map[Id]bool)
+	var renumbered = make(map[Uid]IdUid)
+	for _, id := range ids {
+		if seen[id.getIdSafe()] {
+			next++
+			var uid = id.getUidSafe()
+			*id = CreateIdUid(next, uid)
+			renumbered[uid] = *id
+		}
+		seen[id.getIdSafe()] = true
+	}
+	return renumbered
+}
+
+// lastIdUid returns the one with the highest ID, preferring the current elements (given first) for the same ID
+func lastIdUid(current []IdUid, lastIds ...IdUid) IdUid {
+	var result IdUid
+	for _, id := range append(current, lastIds...) {
+		if len(id) > 0 && (len(result) == 0 || id.getIdSafe() > result.getIdSafe()) {
+			result = id
+		}
+	}
+	return result
+}
+
+func maxId(a, b Id) Id {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func uidSet(uids []Uid) map[Uid]bool {
+	var result = make(map[Uid]bool, len(uids))
+	for _, uid := range uids {
+		result[uid] = true
+	}
+	return result
+}
+
+// uidUnion returns ours extended by the UIDs only present in theirs
+func uidUnion(ours, theirs []Uid) []Uid {
+	var result = append([]Uid{}, ours...)
+	var known = uidSet(ours)
+	for _, uid := range theirs {
+		if !known[uid] {
+			result = append(result, uid)
+		}
+	}
+	return result
+}
@@ -20,7 +20,17 @@
 
 package generator
 
-import "math/rand"
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
 
 // Options provide configuration for the generator
 type Options struct {
@@ -30,6 +40,182 @@ type Options struct {
 	OutPath        string
 	OutHeadersPath string
 
-	// NOTE - currently only supports one
-	CodeGenerator CodeGenerator
+	// Include and Exclude are glob patterns selecting the sources when InPath is a directory or a pattern. A pattern
+	// without a slash matches the name of a file or of any of its parent directories (e.g. "testdata" or "*_mock.go"),
+	// otherwise it matches the path relative to InputRoot() (e.g. "third_party/*.fbs"). If Include is given, only the
+	// matching files are used, then those matching Exclude are skipped, as well as those listed in IgnoreFileName files.
+	// Hidden and "vendor" directories are always skipped.
+	Include []string
+	Exclude []string
+
+	// PreserveDirs makes the files generated into OutPath and OutHeadersPath keep the directory structure of the sources,
+	// relative to InputRoot(). Otherwise, they're all written directly into those directories.
+	PreserveDirs bool
+
+	// CodeGenerators produce the language bindings. The first one is used to parse the sources and all of them write
+	// their bindings from the same merged model, thus they must all accept the same source files.
+	CodeGenerators []CodeGenerator
+
+	// Check makes Process compare the generated code and the model JSON with the files on disk instead of writing
+	// them. Process then returns a *CheckError if any of the files are not up-to-date.
+	Check bool
+
+	// FailFast makes Process stop at the first source file with errors. By default, the errors of all the source files
+	// are collected and returned together as a diagnostics.List. In either case, nothing is written if there's an error.
+	FailFast bool
+
+	// ModelDiscovery makes Process use a separate model for each directory with sources, e.g. each Go package when
+	// running for "./...": each source uses the objectbox-model.json in its own directory or in the closest parent
+	// directory having one, up to InputRoot(); sources without such a file get a new model created in their directory.
+	// ModelInfoFile must not be set in this mode, nor OutPath and OutHeadersPath unless used with PreserveDirs.
+	ModelDiscovery bool
+
+	// AllowDestructive lets the model changes which remove stored data go through: removing an entity, a property,
+	// an index or a standalone relation. Otherwise, Process fails, unless each of the removals is acknowledged by a
+	// "removed" annotation (listing the UIDs) on any of the entities of the model.
+	AllowDestructive bool
+
+	// PinUids makes Process write the UIDs back into the sources: each entity, property and standalone relation declared
+	// in a source gets its UID from the model as an annotation, so that renames are always explicit. The rewritten
+	// sources are written together with the generated files, see Result.Sources. With Check, the sources without all
+	// the UIDs pinned are reported as not up-to-date. Cache is not used in this mode, all the sources are parsed.
+	// Each file is pinned once per run, even if included by multiple sources. Go: fields of embedded structs are
+	// reported as warnings instead of pinned.
+	PinUids bool
+
+	// Workers is the number of source files parsed and generated concurrently; the number of CPUs if not positive.
+	// The resulting model and files are the same as when processing the sources one after another.
+	Workers int
+
+	// Cache enables the incremental generation cache file (see CacheFile()): sources which haven't changed since the
+	// previous run, nor have the files they include, the sources declaring their relation targets, their entities in
+	// the model JSON and their generated files, are neither parsed nor generated again. Not used when running with Check
+	// or InMemory, always used by Watch().
+	Cache bool
+
+	// LockTimeout is how long to wait for another process holding the model JSON file lock; model.DefaultLockTimeout if
+	// zero, not waiting at all if negative.
+	LockTimeout time.Duration
+
+	// InMemory makes Process generate into Result.Files without writing, removing or locking anything; the caller may
+	// write the result later using Result.Write().
+	InMemory bool
+
+	// Context, if set, cancels the processing between the source files and before writing the output.
+	Context context.Context
+
+	// FS is used for all file access, including the sources and the model JSON file; the OS file system if nil.
+	// Note: Go packages imported by the sources are still loaded from the OS file system.
+	FS vfs.FS
+
+	// DepFile, if set, is written after a successful generation: a Makefile-style dependency file listing the generated
+	// files and the inputs they depend on (sources, included schema files and the model JSON), see Result.Depfile().
+	DepFile string
+
+	// ManifestFile, if set, is written after a successful generation: a JSON file listing all the outputs and inputs,
+	// see Result.Manifest().
+	ManifestFile string
+
+	// ChangelogFiles, if set, are written after a successful generation, describing the model changes of the run (see
+	// Result.Changes): as JSON for files with the ".json" extension (see Result.ChangelogJSON()), as Markdown otherwise
+	// (see Result.ChangelogMarkdown()).
+	ChangelogFiles []string
+
+	// Diagnostics, if set, receives the warnings, notices and progress information of the run instead of the global
+	// reporter (see diagnostics.Report). It may be called concurrently when running with Workers.
+	Diagnostics func(d *diagnostics.Diagnostic)
+
+	check  *checkState  // set by Process when running with Check
+	output *outputState // set by Process, holds the generated files until the whole run succeeds
+	inputs *inputState  // set by Process, collects the files read while parsing the sources
+	cache  *cacheState  // set by Process when running with Cache
+	pinned *outputState // set by Process when running with PinUids, holds the rewritten sources
+
+	sourceFiles []string // set by Process with ModelDiscovery, the sources of the current model instead of InPath
+
+	modelCreated bool // set by Process if the model JSON file didn't exist before
+
+	models openModels // set by Watch, the models kept open (and locked) between the runs
+}
+
+// WriteFile writes a generated file, see the package-level WriteFile().
+// When running with Options.Check, the data is compared to the existing file instead.
+// During Process, the file is only written after all the sources have been processed successfully.
+func (options Options) WriteFile(file string, data []byte, permSource string) error {
+	if options.output != nil {
+		options.output.add(file, data, permSource)
+	}
+	if options.check != nil {
+		return options.check.compare(file, data)
+	}
+	if options.output != nil {
+		return nil
+	}
+	return writeFile(options.FileSystem(), file, data, permSource)
+}
+
+// OutputFile returns the path of a file generated for the given file (a source or the model JSON file) in the output
+// directory dir, see PreserveDirs. Files outside InputRoot() are always written directly into dir.
+func (options Options) OutputFile(dir string, forFile string) string {
+	if options.PreserveDirs {
+		if rel, err := filepath.Rel(options.InputRoot(), forFile); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.Join(dir, rel)
+		}
+	}
+	return filepath.Join(dir, filepath.Base(forFile))
+}
+
+// InputRoot returns the directory the sources are looked up in, i.e. InPath without the file name or pattern
+func (options Options) InputRoot() string {
+	var path = options.InPath
+	if strings.HasSuffix(path, recursionSuffix) {
+		return filepath.Clean(path[0 : len(path)-len(recursionSuffix)])
+	}
+	if finfo, err := options.FileSystem().Stat(path); err == nil && finfo.IsDir() {
+		return filepath.Clean(path)
+	}
+
+	var dir = filepath.Dir(path)
+	for strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// FileSystem returns the file system to use, see Options.FS
+func (options Options) FileSystem() vfs.FS {
+	if options.FS == nil {
+		return vfs.OS
+	}
+	return options.FS
+}
+
+// Report passes a warning, a notice or progress information to Options.Diagnostics or to the global reporter if not set
+func (options Options) Report(d *diagnostics.Diagnostic) {
+	if options.Diagnostics == nil {
+		diagnostics.Report(d)
+	} else {
+		options.Diagnostics(d)
+	}
+}
+
+// lockTimeout returns the model lock timeout, see Options.LockTimeout
+func (options Options) lockTimeout() time.Duration {
+	if options.LockTimeout == 0 {
+		return model.DefaultLockTimeout
+	}
+	return options.LockTimeout
+}
+
+// canceled returns the context error if the processing should stop
+func (options Options) canceled() error {
+	if options.Context == nil {
+		return nil
+	}
+	return options.Context.Err()
+}
+
+// readOnly returns true if the model file must not be written (nor created)
+func (options Options) readOnly() bool {
+	return options.check != nil || options.InMemory
 }
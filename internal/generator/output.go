@@ -0,0 +1,266 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// OutputFile is a file produced by the generator
+type OutputFile struct {
+	Path string
+	Data []byte
+
+	permSource string // see WriteFile(); empty for the model JSON file
+	noBackup   bool   // for a model JSON file created by this run
+	fsys       vfs.FS
+}
+
+// Write writes the file, see writeFiles(). An existing file with the same contents is not written at all, keeping its
+// modification time, so that build tools don't consider the file changed.
+func (file OutputFile) Write() error {
+	return writeFiles(file.fsys, []OutputFile{file})
+}
+
+// Result describes the outcome of a successful Run()
+type Result struct {
+	// Files contains all generated files, including the model JSON file (as the first one, or with Options.ModelDiscovery,
+	// each model JSON file before the files generated from it), whether written or not.
+	Files []OutputFile
+
+	// Removed lists previously generated files which are not generated anymore (and are removed unless InMemory).
+	Removed []string
+
+	// Inputs lists the files the output was generated from: the sources, the files they include (or the other files of
+	// a Go package) and the model JSON file. Sorted by path.
+	Inputs []string
+
+	// Changes lists the changes of the model compared to the model JSON file before the run.
+	Changes []model.Change
+
+	// Sources contains the sources rewritten with the UIDs pinned, see Options.PinUids; written together with Files.
+	Sources []OutputFile
+
+	fsys   vfs.FS
+	report func(*diagnostics.Diagnostic) // see Options.Report()
+	caches []*cacheState                 // saved after the files are written
+}
+
+// Write writes all the files as a unit (see writeFiles()) and then removes the ones not generated anymore, e.g. after
+// running with Options.InMemory
+func (result *Result) Write() error {
+	var files = append(append([]OutputFile{}, result.Files...), result.Sources...)
+	if err := writeFiles(result.fsys, files); err != nil {
+		return err
+	}
+	return removeFiles(result.fsys, result.Removed, result.report)
+}
+
+// listRemoved lists previously generated files in cleanPath which are not among the result files
+func (result *Result) listRemoved(options Options, cleanPath string) error {
+	var generated = make(map[string]bool, len(result.Files))
+	for _, file := range result.Files {
+		generated[filepath.Clean(file.Path)] = true
+	}
+
+	return pathForEach(result.fsys, cleanPath, func(filePath string) error {
+		if generated[filepath.Clean(filePath)] {
+			return nil
+		}
+		for _, codeGenerator := range options.CodeGenerators {
+			if codeGenerator.IsGeneratedFile(filePath) {
+				result.Removed = append(result.Removed, filePath)
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// BackupFile returns the path of the backup of the previous model JSON file, written whenever the model changes
+func BackupFile(modelInfoFile string) string {
+	return modelInfoFile + ".bak"
+}
+
+// pendingWrite is a file being written by writeFiles()
+type pendingWrite struct {
+	path     string
+	temp     string
+	perm     os.FileMode
+	previous []byte // nil if the file didn't exist
+	renamed  bool
+}
+
+// writeFiles writes the files as a unit: each file is first written to a temporary file next to it and only when all
+// of them have been written successfully, they're renamed to the target paths. If anything fails, the already renamed
+// files are restored and the temporary files removed, so either all the files are updated or none of them.
+// Files with unchanged contents are not written at all, missing directories are created. When the model JSON file (the
+// one without a permSource) is changed, its previous contents are kept as a backup (see BackupFile()), unless it was
+// created by this run.
+func writeFiles(fsys vfs.FS, files []OutputFile) (err error) {
+	var pending []*pendingWrite
+	var perms = make(map[string]os.FileMode) // of the files being written, used as permission sources
+
+	defer func() {
+		if err != nil {
+			rollback(fsys, pending)
+		}
+	}()
+
+	var stage = func(path string, data []byte, permSource string) error {
+		var write = &pendingWrite{path: path, temp: vfs.TempName(path)}
+		if existing, err := fsys.ReadFile(path); err == nil {
+			if bytes.Equal(existing, data) {
+				return nil
+			}
+			write.previous = existing
+		}
+
+		// copy permissions either from the existing file or from the source file, see WriteFile()
+		if info, err := fsys.Stat(path); err == nil {
+			write.perm = info.Mode()
+		} else if perm, isPending := perms[filepath.Clean(permSource)]; isPending {
+			write.perm = perm
+		} else if len(permSource) == 0 {
+			write.perm = 0600 // same permissions as a model JSON file created by model.LoadOrCreateModel()
+		} else if info, err := fsys.Stat(permSource); err == nil {
+			write.perm = info.Mode()
+		} else {
+			return err
+		}
+		perms[filepath.Clean(path)] = write.perm
+
+		// the output subdirectories, e.g. with Options.PreserveDirs, may not exist yet
+		if _, err := fsys.Stat(filepath.Dir(path)); os.IsNotExist(err) {
+			if err = fsys.MkdirAll(filepath.Dir(path), 0750); err != nil {
+				return fmt.Errorf("can't create the directory of %s: %s", path, err)
+			}
+		}
+
+		pending = append(pending, write)
+		if err := fsys.WriteFile(write.temp, data, write.perm); err != nil {
+			return fmt.Errorf("can't write %s: %s", path, err)
+		}
+		return nil
+	}
+
+	for _, file := range files {
+		if len(file.permSource) == 0 && !file.noBackup {
+			if previous, err := fsys.ReadFile(file.Path); err == nil && len(previous) > 0 && !bytes.Equal(previous, file.Data) {
+				if err = stage(BackupFile(file.Path), previous, file.Path); err != nil {
+					return err
+				}
+			}
+		}
+		if err = stage(file.Path, file.Data, file.permSource); err != nil {
+			return err
+		}
+	}
+
+	for _, write := range pending {
+		if err = fsys.Rename(write.temp, write.path); err != nil {
+			return fmt.Errorf("can't write %s: %s", write.path, err)
+		}
+		write.renamed = true
+	}
+	return nil
+}
+
+// rollback restores the files already renamed by writeFiles() and removes the remaining temporary files
+func rollback(fsys vfs.FS, pending []*pendingWrite) {
+	for _, write := range pending {
+		if !write.renamed {
+			fsys.Remove(write.temp)
+		} else if write.previous == nil {
+			fsys.Remove(write.path)
+		} else if err := vfs.WriteFileAtomic(fsys, write.path, write.previous, write.perm); err != nil {
+			fmt.Fprintf(os.Stderr, "can't restore %s: %s\n", write.path, err)
+		}
+	}
+}
+
+func removeFiles(fsys vfs.FS, files []string, report func(*diagnostics.Diagnostic)) error {
+	for _, file := range files {
+		report(diagnostics.Info(fmt.Sprintf("Removing %s", file)))
+		if err := fsys.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputState holds the generated files until all the sources have been processed without an error,
+// so that nothing is written (or removed) by a failed run
+type outputState struct {
+	fsys    vfs.FS
+	files   []OutputFile
+	indexes map[string]int // index in files by the cleaned path
+}
+
+func newOutputState(fsys vfs.FS) *outputState {
+	return &outputState{fsys: fsys, indexes: make(map[string]int)}
+}
+
+// add stages the file to be written by commit(); a file generated again replaces the previous contents
+func (state *outputState) add(file string, data []byte, permSource string) {
+	var key = filepath.Clean(file)
+	if index, exists := state.indexes[key]; exists {
+		state.files[index].Data = data
+		return
+	}
+	state.indexes[key] = len(state.files)
+	state.files = append(state.files, OutputFile{Path: file, Data: data, permSource: permSource, fsys: state.fsys})
+}
+
+// data returns the contents of the staged file, if any
+func (state *outputState) data(file string) ([]byte, bool) {
+	if index, exists := state.indexes[filepath.Clean(file)]; exists {
+		return state.files[index].Data, true
+	}
+	return nil, false
+}
+
+// addAll stages the files of the other state, in the order they were added there
+func (state *outputState) addAll(other *outputState) {
+	for _, file := range other.files {
+		state.add(file.Path, file.Data, file.permSource)
+	}
+}
+
+// result collects the staged files and the model
+func (state *outputState) result(options Options, modelInfo *model.ModelInfo) (*Result, error) {
+	data, err := modelInfo.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("can't serialize model-info file %s: %s", options.ModelInfoFile, err)
+	}
+
+	// the model JSON file comes first, it's used as a permission source for the model binding files.
+	// There's no backup of a model JSON file created by this run.
+	var modelFile = OutputFile{Path: options.ModelInfoFile, Data: data, noBackup: options.modelCreated, fsys: state.fsys}
+	return &Result{Files: append([]OutputFile{modelFile}, state.files...), fsys: state.fsys, report: options.Report}, nil
+}
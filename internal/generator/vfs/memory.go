@@ -0,0 +1,235 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is a file system keeping the files in memory. Like on the OS file system, directories must be created (see
+// MkdirAll()) before writing files into them. It's safe for concurrent use.
+type Memory struct {
+	mutex sync.RWMutex
+	files map[string]*memoryFile // by the cleaned path
+	dirs  map[string]time.Time   // by the cleaned path, with the modification time
+}
+
+type memoryFile struct {
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// NewMemory creates an empty in-memory file system
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]*memoryFile), dirs: make(map[string]time.Time)}
+}
+
+// ReadFile implements FS
+func (fsys *Memory) ReadFile(name string) ([]byte, error) {
+	fsys.mutex.RLock()
+	defer fsys.mutex.RUnlock()
+
+	var file = fsys.files[filepath.Clean(name)]
+	if file == nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return append([]byte{}, file.data...), nil
+}
+
+// WriteFile implements FS
+func (fsys *Memory) WriteFile(name string, data []byte, perm os.FileMode) error {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	var path = filepath.Clean(name)
+	if _, isDir := fsys.dirs[path]; isDir {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+
+	if !fsys.dirExists(filepath.Dir(path)) {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	var now = time.Now()
+	if file := fsys.files[path]; file != nil {
+		file.data = append([]byte{}, data...)
+		file.modTime = now
+	} else {
+		fsys.files[path] = &memoryFile{data: append([]byte{}, data...), mode: perm, modTime: now}
+	}
+	return nil
+}
+
+// Stat implements FS
+func (fsys *Memory) Stat(name string) (os.FileInfo, error) {
+	fsys.mutex.RLock()
+	defer fsys.mutex.RUnlock()
+
+	var path = filepath.Clean(name)
+	if file := fsys.files[path]; file != nil {
+		return memoryFileInfo{name: filepath.Base(path), size: int64(len(file.data)), mode: file.mode, modTime: file.modTime}, nil
+	}
+	if modTime, isDir := fsys.dirs[path]; isDir || path == "." {
+		return memoryFileInfo{name: filepath.Base(path), mode: os.ModeDir | 0755, modTime: modTime}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// ReadDir implements FS
+func (fsys *Memory) ReadDir(name string) ([]os.FileInfo, error) {
+	var path = filepath.Clean(name)
+	if info, err := fsys.Stat(path); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: os.ErrInvalid}
+	}
+
+	fsys.mutex.RLock()
+	var names []string
+	for filePath := range fsys.files {
+		if filepath.Dir(filePath) == path {
+			names = append(names, filePath)
+		}
+	}
+	for dirPath := range fsys.dirs {
+		if filepath.Dir(dirPath) == path && dirPath != path {
+			names = append(names, dirPath)
+		}
+	}
+	fsys.mutex.RUnlock()
+
+	sort.Strings(names)
+	var result = make([]os.FileInfo, 0, len(names))
+	for _, entry := range names {
+		if info, err := fsys.Stat(entry); err == nil {
+			result = append(result, info)
+		}
+	}
+	return result, nil
+}
+
+// Remove implements FS
+func (fsys *Memory) Remove(name string) error {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	var path = filepath.Clean(name)
+	if fsys.files[path] != nil {
+		delete(fsys.files, path)
+		return nil
+	}
+	if _, isDir := fsys.dirs[path]; isDir {
+		for other := range fsys.files {
+			if filepath.Dir(other) == path {
+				return &os.PathError{Op: "remove", Path: name, Err: os.ErrExist}
+			}
+		}
+		for other := range fsys.dirs {
+			if filepath.Dir(other) == path && other != path {
+				return &os.PathError{Op: "remove", Path: name, Err: os.ErrExist}
+			}
+		}
+		delete(fsys.dirs, path)
+		return nil
+	}
+	return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+}
+
+// Rename implements FS
+func (fsys *Memory) Rename(oldName, newName string) error {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	var oldPath, newPath = filepath.Clean(oldName), filepath.Clean(newName)
+	var file = fsys.files[oldPath]
+	if file == nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrNotExist}
+	}
+	if _, isDir := fsys.dirs[newPath]; isDir {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrExist}
+	}
+
+	if !fsys.dirExists(filepath.Dir(newPath)) {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrNotExist}
+	}
+
+	delete(fsys.files, oldPath)
+	fsys.files[newPath] = file
+	return nil
+}
+
+// MkdirAll implements FS
+func (fsys *Memory) MkdirAll(path string, perm os.FileMode) error {
+	fsys.mutex.Lock()
+	defer fsys.mutex.Unlock()
+
+	path = filepath.Clean(path)
+	if fsys.files[path] != nil {
+		return &os.PathError{Op: "mkdir", Path: path, Err: os.ErrExist}
+	}
+	fsys.mkdirAll(path, time.Now())
+	return nil
+}
+
+// dirExists checks whether the directory has been created, the current and the root directories always exist; the
+// caller must hold the lock
+func (fsys *Memory) dirExists(path string) bool {
+	if _, exists := fsys.dirs[path]; exists {
+		return true
+	}
+	return path == "." || filepath.Dir(path) == path
+}
+
+// mkdirAll registers the directory and its parents; the caller must hold the lock
+func (fsys *Memory) mkdirAll(path string, modTime time.Time) {
+	for {
+		if _, exists := fsys.dirs[path]; exists {
+			return
+		}
+		fsys.dirs[path] = modTime
+
+		var parent = filepath.Dir(path)
+		if parent == path {
+			return
+		}
+		path = parent
+	}
+}
+
+// memoryFileInfo implements os.FileInfo for Memory
+type memoryFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (info memoryFileInfo) Name() string       { return info.name }
+func (info memoryFileInfo) Size() int64        { return info.size }
+func (info memoryFileInfo) Mode() os.FileMode  { return info.mode }
+func (info memoryFileInfo) ModTime() time.Time { return info.modTime }
+func (info memoryFileInfo) IsDir() bool        { return info.mode.IsDir() }
+func (info memoryFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,194 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package vfs abstracts the file system access of the generator, so that it can run on other than OS files,
+// e.g. in memory for editor integrations, sandboxed build systems or tests.
+package vfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// FS is a read-write file system. Paths use the OS-specific separator, as in the "path/filepath" package.
+type FS interface {
+	// ReadFile returns the contents of the named file
+	ReadFile(name string) ([]byte, error)
+
+	// WriteFile writes the named file, creating it with the given permissions if it doesn't exist
+	WriteFile(name string, data []byte, perm os.FileMode) error
+
+	// Stat returns the file info; the error satisfies os.IsNotExist() if the file doesn't exist
+	Stat(name string) (os.FileInfo, error)
+
+	// ReadDir lists the directory entries, sorted by name
+	ReadDir(name string) ([]os.FileInfo, error)
+
+	// Remove removes the named file
+	Remove(name string) error
+
+	// Rename moves the file, replacing the target file if it exists. Both paths should be in the same directory, so
+	// that the rename is atomic.
+	Rename(oldName, newName string) error
+
+	// MkdirAll creates the directory and all its parents, if they don't exist
+	MkdirAll(path string, perm os.FileMode) error
+}
+
+// OS is the file system of the operating system
+var OS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+func (osFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}
+
+func (osFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(name)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFS) Rename(oldName, newName string) error {
+	return os.Rename(oldName, newName)
+}
+
+func (osFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+var tempCounter uint64
+
+// TempName returns a unique name of a temporary file next to the given file, i.e. in the same directory
+func TempName(name string) string {
+	var counter = atomic.AddUint64(&tempCounter, 1)
+	return filepath.Join(filepath.Dir(name), fmt.Sprintf(".%s.%d-%d.tmp", filepath.Base(name), os.Getpid(), counter))
+}
+
+// WriteFileAtomic writes the file to a temporary file first and then renames it, so that the file is never left
+// partially written, e.g. if the process is killed or the disk is full
+func WriteFileAtomic(fsys FS, name string, data []byte, perm os.FileMode) error {
+	var temp = TempName(name)
+	if err := fsys.WriteFile(temp, data, perm); err != nil {
+		fsys.Remove(temp)
+		return err
+	}
+	if err := fsys.Rename(temp, name); err != nil {
+		fsys.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// Glob returns the names of the files matching the pattern, like filepath.Glob() but using the given file system
+func Glob(fsys FS, pattern string) ([]string, error) {
+	if fsys == OS {
+		return filepath.Glob(pattern)
+	}
+
+	// check the pattern syntax, as filepath.Glob() does
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	if !hasMeta(pattern) {
+		if _, err := fsys.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	var dir, file = filepath.Split(pattern)
+	dir = cleanGlobPath(dir)
+
+	if !hasMeta(dir) {
+		return globDir(fsys, dir, file, nil), nil
+	}
+
+	// prevent infinite recursion, see filepath.Glob()
+	if dir == pattern {
+		return nil, filepath.ErrBadPattern
+	}
+
+	dirs, err := Glob(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, d := range dirs {
+		matches = globDir(fsys, d, file, matches)
+	}
+	return matches, nil
+}
+
+// globDir appends the entries of the directory matching the pattern
+func globDir(fsys FS, dir, pattern string, matches []string) []string {
+	if info, err := fsys.Stat(dir); err != nil || !info.IsDir() {
+		return matches
+	}
+
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return matches
+	}
+
+	for _, entry := range entries {
+		if matched, _ := filepath.Match(pattern, entry.Name()); matched {
+			matches = append(matches, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return matches
+}
+
+func cleanGlobPath(path string) string {
+	switch path {
+	case "":
+		return "."
+	case string(filepath.Separator):
+		return path
+	}
+	return path[0 : len(path)-1] // chop off the trailing separator
+}
+
+// hasMeta reports whether the path contains any of the magic characters recognized by filepath.Match()
+func hasMeta(path string) bool {
+	var magicChars = `*?[`
+	if runtime.GOOS != "windows" {
+		magicChars = `*?[\`
+	}
+	return strings.ContainsAny(path, magicChars)
+}
@@ -0,0 +1,77 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestMemoryGlob(t *testing.T) {
+	var fsys = NewMemory()
+	for _, name := range []string{"a/x.fbs", "a/y.fbs", "a/b/z.fbs", "c/x.fbs", "c/x.go"} {
+		assert.NoErr(t, fsys.MkdirAll(filepath.Dir(filepath.FromSlash(name)), 0755))
+		assert.NoErr(t, fsys.WriteFile(filepath.FromSlash(name), []byte(name), 0644))
+	}
+
+	var glob = func(pattern string) []string {
+		matches, err := Glob(fsys, filepath.FromSlash(pattern))
+		assert.NoErr(t, err)
+		for i := range matches {
+			matches[i] = filepath.ToSlash(matches[i])
+		}
+		return matches
+	}
+
+	assert.Eq(t, []string{"a/b", "a/x.fbs", "a/y.fbs"}, glob("a/*"))
+	assert.Eq(t, []string{"a/x.fbs", "c/x.fbs"}, glob("*/x.fbs"))
+	assert.Eq(t, []string{"c/x.go"}, glob("c/x.go"))
+	assert.Eq(t, 0, len(glob("d/*")))
+
+	info, err := fsys.Stat("a")
+	assert.NoErr(t, err)
+	assert.True(t, info.IsDir())
+
+	assert.Err(t, fsys.Remove("a/b"))
+	assert.NoErr(t, fsys.Remove(filepath.FromSlash("a/b/z.fbs")))
+	assert.NoErr(t, fsys.Remove(filepath.FromSlash("a/b")))
+	_, err = fsys.Stat(filepath.FromSlash("a/b"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMemoryMissingDir(t *testing.T) {
+	var fsys = NewMemory()
+
+	// like on the OS file system, files can't be written into missing directories
+	err := fsys.WriteFile(filepath.FromSlash("a/x.fbs"), nil, 0644)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoErr(t, fsys.WriteFile("x.fbs", nil, 0644))
+	err = fsys.Rename("x.fbs", filepath.FromSlash("a/x.fbs"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoErr(t, fsys.MkdirAll("a", 0755))
+	assert.NoErr(t, fsys.Rename("x.fbs", filepath.FromSlash("a/x.fbs")))
+	assert.NoErr(t, fsys.WriteFile(filepath.FromSlash("a/y.fbs"), nil, 0644))
+}
@@ -0,0 +1,198 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+)
+
+// fileState is used to detect file changes between two polls
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(fsys vfs.FS, path string) (fileState, bool) {
+	finfo, err := fsys.Stat(path)
+	if err != nil {
+		return fileState{}, false
+	}
+	return fileState{modTime: finfo.ModTime(), size: finfo.Size()}, true
+}
+
+// openModels keeps the models open (and locked) for the whole Watch() session, by the model JSON file path
+type openModels map[string]*model.ModelInfo
+
+// load returns the model of options.ModelInfoFile; an open model is read from the file again, discarding the changes a
+// failed run may have left in memory and picking up the changes made to the file in the meantime, e.g. a VCS checkout
+func (models openModels) load(options Options) (*model.ModelInfo, error) {
+	var path = filepath.Clean(options.ModelInfoFile)
+	if modelInfo := models[path]; modelInfo != nil {
+		if err := modelInfo.Reload(); err == nil {
+			return modelInfo, nil
+		}
+
+		// e.g. the file has been removed after a failed first run, start over
+		modelInfo.Close()
+		delete(models, path)
+	}
+
+	modelInfo, err := loadModel(options)
+	if err != nil {
+		return nil, err
+	}
+	models[path] = modelInfo
+	return modelInfo, nil
+}
+
+func (models openModels) close() {
+	for path, modelInfo := range models {
+		modelInfo.Close()
+		delete(models, path)
+	}
+}
+
+// watcher holds the state of Watch() between the generator runs
+type watcher struct {
+	options Options
+	sources map[string]fileState
+}
+
+// Watch runs Process() and then keeps polling the input path for source file changes, until the stop channel is closed.
+// The models stay open (and locked) for the whole time. The cache (see Options.Cache) is always used, so that each run
+// only parses and generates the changed sources and the sources depending on them, e.g. through a relation.
+// Errors of the individual runs are printed, they don't stop watching.
+func Watch(options Options, interval time.Duration, stop <-chan struct{}) error {
+	if options.Check {
+		return fmt.Errorf("can't watch in the check mode")
+	} else if options.InMemory {
+		return fmt.Errorf("can't watch in the in-memory mode")
+	}
+
+	if err := prepare(&options); err != nil {
+		return err
+	}
+
+	options.Cache = true
+	options.models = make(openModels)
+	defer options.models.close()
+
+	// fail early if the model can't be loaded; with model discovery, the models are loaded by the first run
+	if !options.ModelDiscovery {
+		if _, err := options.models.load(options); err != nil {
+			return err
+		}
+	}
+
+	var w = &watcher{options: options}
+	var err error
+	if w.sources, err = w.sourceFiles(); err != nil {
+		return err
+	}
+	w.run(nil)
+
+	options.Report(diagnostics.Info(fmt.Sprintf("Watching %s for changes", options.InPath)))
+
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		sources, err := w.sourceFiles()
+		if err != nil {
+			diagnostics.PrintError(err)
+			continue
+		}
+
+		var changed []string
+		var fullRun = len(sources) != len(w.sources)
+		for path, state := range sources {
+			if previous, exists := w.sources[path]; !exists {
+				fullRun = true
+			} else if previous != state {
+				changed = append(changed, path)
+			}
+		}
+		w.sources = sources
+
+		if fullRun {
+			w.run(nil)
+		} else if len(changed) > 0 {
+			sort.Strings(changed)
+			w.run(changed)
+		}
+	}
+}
+
+// sourceFiles lists the current state of all source files in the input path
+func (w *watcher) sourceFiles() (map[string]fileState, error) {
+	var result = make(map[string]fileState)
+	var err = forEachSource(w.options, func(filePath string) error {
+		if state, exists := statFile(w.options.FileSystem(), filePath); exists {
+			result[filePath] = state
+		}
+		return nil
+	})
+	return result, err
+}
+
+// run performs a run on the input path; files lists the changed files, or is nil if files were added or removed.
+// The cache skips the sources which are not affected by the changes; all of them still need to be merged into the model
+// though, e.g. to find out about removed entities.
+func (w *watcher) run(files []string) {
+	if files != nil {
+		w.options.Report(diagnostics.Info(fmt.Sprintf("Regenerating ObjectBox bindings for %s", strings.Join(files, ", "))))
+	}
+
+	cleanPath, err := implicitClean(w.options)
+	if err == nil {
+		if w.options.ModelDiscovery {
+			_, err = runDiscovered(w.options, cleanPath)
+		} else {
+			err = w.process(cleanPath)
+		}
+	}
+	if err != nil {
+		diagnostics.PrintError(err)
+	}
+}
+
+func (w *watcher) process(cleanPath string) error {
+	modelInfo, err := w.options.models.load(w.options)
+	if err != nil {
+		return err
+	}
+	_, err = processModel(w.options, modelInfo, cleanPath)
+	return err
+}
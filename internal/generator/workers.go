@@ -0,0 +1,63 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// workerCount returns the number of sources to process concurrently, see Options.Workers
+func (options Options) workerCount() int {
+	if options.Workers > 0 {
+		return options.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// forEachConcurrently calls fn for the indexes 0..count-1 using the given number of goroutines.
+// The indexes are handed out in increasing order; once fn returns false, the indexes not yet handed out are skipped.
+func forEachConcurrently(workers int, count int, fn func(index int) bool) {
+	if workers > count {
+		workers = count
+	}
+
+	var next int64 = -1
+	var stopped int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stopped) == 0 {
+				var index = int(atomic.AddInt64(&next, 1))
+				if index >= count {
+					return
+				}
+				if !fn(index) {
+					atomic.StoreInt32(&stopped, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
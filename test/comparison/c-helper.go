@@ -21,10 +21,12 @@
 package comparison
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/objectbox/objectbox-generator/v4/internal/generator"
@@ -58,7 +60,13 @@ func (h cTestHelper) generatorFor(t *testing.T, conf testSpec, sourceFile string
 }
 
 func (cTestHelper) prepareTempDir(t *testing.T, conf testSpec, srcDir, tempDir, tempRoot string) func(err error) error {
-	return nil
+	// map the temp dir paths in received errors (e.g. error positions), so they match the expected ones
+	return func(err error) error {
+		if err == nil {
+			return nil
+		}
+		return errors.New(strings.Replace(err.Error(), tempRoot+string(os.PathSeparator), "", -1))
+	}
 }
 
 func (h cTestHelper) build(t *testing.T, conf testSpec, dir string, expectedError error, errorTransformer func(err error) error) {
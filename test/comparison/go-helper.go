@@ -48,8 +48,8 @@ func (h goTestHelper) generatorFor(t *testing.T, conf testSpec, sourceFile strin
 	source, err := ioutil.ReadFile(sourceFile)
 	assert.NoErr(t, err)
 
-	// make a copy of the default generator
-	var gen = *conf.generator.(*gogenerator.GoGenerator)
+	// make a copy of the default generator's configuration
+	var gen = gogenerator.GoGenerator{ByValue: conf.generator.(*gogenerator.GoGenerator).ByValue}
 
 	if match := goGeneratorArgsRegexp.FindSubmatch(source); len(match) > 1 {
 		var args = argsToMap(string(match[1]))
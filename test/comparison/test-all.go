@@ -229,10 +229,10 @@ func generateAllFiles(t *testing.T, overwriteExpected bool, conf testSpec, srcDi
 		var options = generator.Options{
 			ModelInfoFile: modelInfoFile,
 			// NOTE zero seed for test-only - avoid changes caused by random numbers by fixing them to the same seed
-			Rand:          rand.New(rand.NewSource(0)),
-			CodeGenerator: conf.helper.generatorFor(t, conf, sourceFile, genDir),
-			InPath:        sourceFile,
-			OutPath:       genDir,
+			Rand:           rand.New(rand.NewSource(0)),
+			CodeGenerators: []generator.CodeGenerator{conf.helper.generatorFor(t, conf, sourceFile, genDir)},
+			InPath:         sourceFile,
+			OutPath:        genDir,
 		}
 		err = errorTransformer(generator.Process(options))
 
@@ -252,7 +252,7 @@ func generateAllFiles(t *testing.T, overwriteExpected bool, conf testSpec, srcDi
 
 		assert.NoErr(t, err)
 
-		var bindingFiles = options.CodeGenerator.BindingFiles(sourceFile, options)
+		var bindingFiles = options.CodeGenerators[0].BindingFiles(sourceFile, options)
 		for _, bindingFile := range bindingFiles {
 			var expectedFile = strings.Replace(bindingFile, genDir, expDir, 1) + ".expected"
 			assertSameFile(t, bindingFile, expectedFile, overwriteExpected)
@@ -1,6 +1,10 @@
 package object
 
-// ERROR = can't prepare bindings for cycles/embedding-named.fail.go: embedded struct cycle detected: EmbeddingNamedChainA.BPtr.CPtr on property APtr found in EmbeddingNamedChainA.BPtr.CPtr
+/* ERROR:
+cycles/embedding-named.fail.go:19:2: embedded struct cycle detected: EmbeddingNamedChainA.BPtr.CPtr on property APtr found in EmbeddingNamedChainA.BPtr.CPtr
+cycles/embedding-named.fail.go:11:2: embedded struct cycle detected: EmbeddingNamedChainB.CPtr.APtr on property BPtr found in EmbeddingNamedChainB.CPtr.APtr
+cycles/embedding-named.fail.go:15:2: embedded struct cycle detected: EmbeddingNamedChainC.APtr.BPtr on property CPtr found in EmbeddingNamedChainC.APtr.BPtr
+*/
 
 type EmbeddingNamedChainA struct {
 	Id   uint64
@@ -1,6 +1,10 @@
 package object
 
-// ERROR = can't prepare bindings for cycles/embedding.fail.go: embedded struct cycle detected: EmbeddingChainA.EmbeddingChainB.EmbeddingChainC on property EmbeddingChainA found in EmbeddingChainA.EmbeddingChainB.EmbeddingChainC
+/* ERROR:
+cycles/embedding.fail.go:19:3: embedded struct cycle detected: EmbeddingChainA.EmbeddingChainB.EmbeddingChainC on property EmbeddingChainA found in EmbeddingChainA.EmbeddingChainB.EmbeddingChainC
+cycles/embedding.fail.go:11:3: embedded struct cycle detected: EmbeddingChainB.EmbeddingChainC.EmbeddingChainA on property EmbeddingChainB found in EmbeddingChainB.EmbeddingChainC.EmbeddingChainA
+cycles/embedding.fail.go:15:3: embedded struct cycle detected: EmbeddingChainC.EmbeddingChainA.EmbeddingChainB on property EmbeddingChainC found in EmbeddingChainC.EmbeddingChainA.EmbeddingChainB
+*/
 
 type EmbeddingChainA struct {
 	Id uint64
@@ -1,6 +1,6 @@
 package object
 
-// ERROR = can't prepare bindings for id/none.fail.go: no property recognized as an ID on entity None
+// ERROR = id/none.fail.go:5:6: no property recognized as an ID on entity None
 
 type None struct {
 }
@@ -1,6 +1,6 @@
 package object
 
-// ERROR = can't prepare bindings for id/type-int.fail.go: id field 'Id' has unsupported type 'int' on entity TypeInt - must be one of [int64, uint64, string]
+// ERROR = id/type-int.fail.go:6:2: id field 'Id' has unsupported type 'int' on entity TypeInt - must be one of [int64, uint64, string]
 
 type TypeInt struct {
 	Id int `objectbox:"id"`
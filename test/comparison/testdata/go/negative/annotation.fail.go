@@ -0,0 +1,8 @@
+package negative
+
+// ERROR = negative/annotation.fail.go:7:38: unknown index type foo on property Text found in InvalidAnnotation
+
+type InvalidAnnotation struct {
+	Id   uint64
+	Text string `json:"text" objectbox:"index:foo"`
+}
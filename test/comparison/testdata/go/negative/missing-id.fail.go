@@ -1,6 +1,6 @@
 package negative
 
-// ERROR = can't prepare bindings for negative/missing-id.fail.go: no property recognized as an ID on entity MissingId
+// ERROR = negative/missing-id.fail.go:5:6: no property recognized as an ID on entity MissingId
 
 type MissingId struct {
 	Text string
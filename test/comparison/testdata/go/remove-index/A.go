@@ -1,5 +1,6 @@
 package object
 
+// `objectbox:"removed:6050128673802995827"`
 type A struct {
 	Id uint64 `objectbox:"id"`
 	//Removed string `objectbox:"index"`
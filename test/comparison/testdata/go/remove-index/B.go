@@ -1,5 +1,6 @@
 package object
 
+// `objectbox:"removed:1774932891286980153"`
 type B struct {
 	Id uint64 `objectbox:"id"`
 	//Removed string `objectbox:"index"`
@@ -1,5 +1,6 @@
 package object
 
+// `objectbox:"removed:2669985732393126063"`
 type B struct {
 	Id uint64 `objectbox:"id"`
 	//Removed string
@@ -0,0 +1,199 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/generator"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestPublicAPI(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "generator-api")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(tempDir)
+
+	var schemaFile = filepath.Join(tempDir, "schema.fbs")
+	var modelFile = filepath.Join(tempDir, "objectbox-model.json")
+	var options = generator.Options{
+		InPath:        tempDir,
+		ModelInfoFile: modelFile,
+		Languages:     []generator.Language{generator.LanguageCpp},
+		Rand:          rand.New(rand.NewSource(0)),
+	}
+	var ctx = context.Background()
+
+	var fileNames = func(files []generator.OutputFile) []string {
+		var names []string
+		for _, file := range files {
+			names = append(names, filepath.Base(file.Path))
+		}
+		return names
+	}
+
+	assert.NoErr(t, ioutil.WriteFile(schemaFile, []byte("table A {id: uint64;}"), 0600))
+
+	// generating in memory doesn't touch the disk, not even the model file
+	result, err := generator.Generate(ctx, options)
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{"objectbox-model.json", "schema.obx.hpp", "schema.obx.cpp", "objectbox-model.h"}, fileNames(result.Files))
+	assert.Eq(t, []model.Change{{Kind: model.EntityAdded, Entity: "A", Uid: 8717895732742165505}}, result.Changes)
+	_, err = os.Stat(modelFile)
+	assert.True(t, os.IsNotExist(err))
+
+	// ... until written explicitly
+	assert.NoErr(t, result.Write())
+	_, err = generator.Check(ctx, options)
+	assert.NoErr(t, err)
+
+	// Process writes directly and reports the changes
+	assert.NoErr(t, ioutil.WriteFile(schemaFile, []byte("table A {id: uint64; name: string;}"), 0600))
+	result, err = generator.Process(ctx, options)
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, len(result.Changes))
+	assert.Eq(t, "property A.name added", result.Changes[0].String())
+	_, err = generator.Check(ctx, options)
+	assert.NoErr(t, err)
+
+	// the individual steps
+	parsed, err := generator.Parse(ctx, options, schemaFile)
+	assert.NoErr(t, err)
+	stored, err := generator.LoadModel(nil, modelFile)
+	assert.NoErr(t, err)
+	assert.NoErr(t, generator.Merge(options, parsed, stored))
+	assert.Eq(t, 1, len(stored.Entities))
+	assert.Eq(t, 2, len(stored.Entities[0].Properties))
+
+	// a canceled context stops before processing the sources
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = generator.Process(canceled, options)
+	assert.Eq(t, context.Canceled, err)
+}
+
+func TestMemoryFS(t *testing.T) {
+	var fsys = generator.NewMemoryFS()
+	var write = func(name, content string) {
+		assert.NoErr(t, fsys.MkdirAll(filepath.Dir(name), 0755))
+		assert.NoErr(t, fsys.WriteFile(name, []byte(content), 0644))
+	}
+	var exists = func(name string) bool {
+		_, err := fsys.Stat(name)
+		return err == nil
+	}
+
+	// FlatBuffers schema with an include, parsed from memory
+	write("schema/main.fbs", `include "types/item.fbs"; table Main { id: ulong; name: string; }`)
+	write("schema/types/item.fbs", `table Item { id: ulong; name: string; }`)
+	_, err := generator.Process(context.Background(), generator.Options{
+		InPath:    "schema/main.fbs",
+		Languages: []generator.Language{generator.LanguageC},
+		Rand:      rand.New(rand.NewSource(0)),
+		FS:        fsys,
+	})
+	assert.NoErr(t, err)
+	assert.True(t, exists("schema/main.obx.h"))
+	assert.True(t, exists("schema/objectbox-model.h"))
+	assert.True(t, exists("schema/objectbox-model.json"))
+
+	// Go sources
+	write("gosrc/entity.go", "package gosrc\n\ntype Task struct {\n\tId   uint64\n\tText string\n}\n")
+	result, err := generator.Process(context.Background(), generator.Options{
+		InPath:    "gosrc/entity.go",
+		Languages: []generator.Language{generator.LanguageGo},
+		Rand:      rand.New(rand.NewSource(0)),
+		FS:        fsys,
+	})
+	assert.NoErr(t, err)
+	assert.True(t, exists("gosrc/entity.obx.go"))
+	assert.True(t, exists("gosrc/objectbox-model.go"))
+	assert.Eq(t, 1, len(result.Changes))
+
+	// nothing was written to the disk
+	_, err = os.Stat("schema")
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat("gosrc")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiagnosticsHandler(t *testing.T) {
+	var out bytes.Buffer
+	diagnostics.SetOutput(&out)
+	defer diagnostics.SetOutput(os.Stderr)
+
+	var fsys = generator.NewMemoryFS()
+	var schemaFile = filepath.Join("schema", "a.fbs")
+	assert.NoErr(t, fsys.MkdirAll("schema", 0755))
+	assert.NoErr(t, fsys.WriteFile(schemaFile, []byte("table A {id: uint64; name: string;}"), 0644))
+	assert.NoErr(t, fsys.WriteFile(filepath.Join("schema", "b.fbs"), []byte("table B {id: uint64;}"), 0644))
+
+	var reported []string
+	var options = generator.Options{
+		InPath:           "schema",
+		Languages:        []generator.Language{generator.LanguageC},
+		Rand:             rand.New(rand.NewSource(0)),
+		FS:               fsys,
+		AllowDestructive: true,
+		Diagnostics: func(d *generator.Diagnostic) {
+			reported = append(reported, d.String())
+		},
+	}
+	var ctx = context.Background()
+	_, err := generator.Process(ctx, options)
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{
+		"info[OBX0006]: Requested to generate for directory/pattern schema, performing an implicit cleanup of previously generated files",
+	}, reported)
+
+	// notices and progress go to the handler only, not to the global output
+	reported = nil
+	assert.NoErr(t, fsys.Remove(filepath.Join("schema", "b.fbs")))
+	_, err = generator.Process(ctx, options)
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{
+		"info[OBX0006]: Requested to generate for directory/pattern schema, performing an implicit cleanup of previously generated files",
+		"notice[OBX1004]: removing missing entity B 2:501233450539197794 from the model",
+		"info[OBX0006]: Removing " + filepath.Join("schema", "b.obx.h"),
+	}, reported)
+	assert.Eq(t, "", out.String())
+
+	// so do the warnings of the individual steps
+	reported = nil
+	assert.NoErr(t, fsys.WriteFile(schemaFile, []byte("table A {\nid: uint64;\n/// objectbox:uid=1000\nname: string;\n}"), 0644))
+	parsed, err := generator.Parse(ctx, options, schemaFile)
+	assert.NoErr(t, err)
+	stored, err := generator.LoadModel(fsys, "objectbox-model.json")
+	assert.NoErr(t, err)
+	assert.NoErr(t, generator.Merge(options, parsed, stored))
+	assert.Eq(t, []string{
+		"warning[OBX1003]: new UID was specified for the same property name 'name' - resetting value (recreating the property)",
+	}, reported)
+	assert.Eq(t, "", out.String())
+}
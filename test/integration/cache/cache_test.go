@@ -0,0 +1,199 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cache
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	gogenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/go"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+// countingGenerator counts the parsed sources
+type countingGenerator struct {
+	cgenerator.CGenerator
+	parsed []string
+}
+
+func (gen *countingGenerator) ParseSource(sourceFile string, options generator.Options) (*model.ModelInfo, error) {
+	gen.parsed = append(gen.parsed, filepath.Base(sourceFile))
+	return gen.CGenerator.ParseSource(sourceFile, options)
+}
+
+// backdate sets the modification time of all files in the directory to the past, so that rewrites are detectable
+func backdate(t *testing.T, dir string) time.Time {
+	var past = time.Now().Add(-time.Hour).Truncate(time.Second)
+	files, err := ioutil.ReadDir(dir)
+	assert.NoErr(t, err)
+	for _, file := range files {
+		assert.NoErr(t, os.Chtimes(filepath.Join(dir, file.Name()), past, past))
+	}
+	return past
+}
+
+func modTime(t *testing.T, file string) time.Time {
+	info, err := os.Stat(file)
+	assert.NoErr(t, err)
+	return info.ModTime()
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator-cache")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	var write = func(name, content string) {
+		assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("a.fbs", "table A { id: ulong; name: string; }")
+	write("b.fbs", "include \"inc/common.inc\";\ntable B { id: ulong; }")
+	assert.NoErr(t, os.Mkdir(filepath.Join(dir, "inc"), 0755))
+	write("inc/common.inc", "table Common { id: ulong; }")
+
+	var gen = &countingGenerator{CGenerator: cgenerator.CGenerator{LangVersion: 14}}
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         dir,
+		ModelInfoFile:  filepath.Join(dir, "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{gen},
+		Cache:          true,
+		// entities are removed from the model by some of the steps below
+		AllowDestructive: true,
+	}
+
+	var run = func(expectedParsed ...string) {
+		gen.parsed = nil
+		assert.NoErr(t, generator.Process(options))
+		assert.EqItems(t, expectedParsed, gen.parsed)
+	}
+
+	run("a.fbs", "b.fbs")
+	_, err = os.Stat(generator.CacheFile(options.ModelInfoFile))
+	assert.NoErr(t, err)
+
+	// nothing changed - nothing is parsed and no file is written
+	var past = backdate(t, dir)
+	run()
+	for _, file := range []string{"a.obx.hpp", "a.obx.cpp", "b.obx.hpp", "objectbox-model.h", "objectbox-model.json"} {
+		assert.Eq(t, past, modTime(t, filepath.Join(dir, file)))
+	}
+
+	// a changed include invalidates the source including it
+	write("inc/common.inc", "table Common { id: ulong; value: int; }")
+	run("b.fbs")
+	assert.Eq(t, past, modTime(t, filepath.Join(dir, "a.obx.hpp")))
+
+	// a modified generated file is regenerated
+	write("a.obx.hpp", "modified")
+	run("a.fbs")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "a.obx.hpp"))
+	assert.NoErr(t, err)
+	assert.True(t, strings.Contains(string(data), "struct A"))
+
+	// a changed model JSON invalidates the sources of the changed entities
+	modelJSON, err := ioutil.ReadFile(options.ModelInfoFile)
+	assert.NoErr(t, err)
+	run()
+	assert.NoErr(t, ioutil.WriteFile(options.ModelInfoFile, []byte(strings.Replace(string(modelJSON), `"name": "A"`, `"name": "Renamed"`, 1)), 0644))
+	run("a.fbs")
+
+	// different options invalidate the whole cache
+	options.OutPath = filepath.Join(dir, "out")
+	run("a.fbs", "b.fbs")
+	options.OutPath = ""
+
+	// a removed source is removed from the model and its bindings are removed as usual
+	run("a.fbs", "b.fbs")
+	assert.NoErr(t, os.Remove(filepath.Join(dir, "b.fbs")))
+	run()
+	_, err = os.Stat(filepath.Join(dir, "b.obx.hpp"))
+	assert.True(t, os.IsNotExist(err))
+	modelInfo, err := model.LoadModelFromJSONFile(options.ModelInfoFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, len(modelInfo.Entities))
+}
+
+func TestCachePreserveDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator-cache")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoErr(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, "sub", "a.fbs"), []byte("table A { id: ulong; }"), 0644))
+
+	var gen = &countingGenerator{CGenerator: cgenerator.CGenerator{LangVersion: 14}}
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         filepath.Join(dir, "..."),
+		ModelInfoFile:  filepath.Join(dir, "objectbox-model.json"),
+		OutPath:        filepath.Join(dir, "out"),
+		CodeGenerators: []generator.CodeGenerator{gen},
+		Cache:          true,
+	}
+	assert.NoErr(t, generator.Process(options))
+	_, err = os.Stat(filepath.Join(dir, "out", "a.obx.hpp"))
+	assert.NoErr(t, err)
+
+	// the output directory layout is a part of the cached options
+	gen.parsed = nil
+	options.PreserveDirs = true
+	assert.NoErr(t, generator.Process(options))
+	assert.Eq(t, []string{"a.fbs"}, gen.parsed)
+	_, err = os.Stat(filepath.Join(dir, "out", "sub", "a.obx.hpp"))
+	assert.NoErr(t, err)
+}
+
+func TestCacheGo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator-cache-go")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	var source = filepath.Join(dir, "entity.go")
+	assert.NoErr(t, ioutil.WriteFile(source, []byte("package mypkg\n\ntype Task struct {\n\tId uint64\n\tText string\n}\n"), 0644))
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         source,
+		ModelInfoFile:  filepath.Join(dir, "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&gogenerator.GoGenerator{}},
+		Cache:          true,
+	}
+	assert.NoErr(t, generator.Process(options))
+	expected, err := ioutil.ReadFile(filepath.Join(dir, "objectbox-model.go"))
+	assert.NoErr(t, err)
+
+	// the model file keeps its package when no source is parsed, i.e. all sources are up-to-date in the cache
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, "objectbox-model.go"), []byte("package mypkg\n"), 0644))
+	options.CodeGenerators = []generator.CodeGenerator{&gogenerator.GoGenerator{}}
+	assert.NoErr(t, generator.Process(options))
+	actual, err := ioutil.ReadFile(filepath.Join(dir, "objectbox-model.go"))
+	assert.NoErr(t, err)
+	assert.Eq(t, string(expected), string(actual))
+}
@@ -0,0 +1,105 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package changelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestChangelog(t *testing.T) {
+	var memory = vfs.NewMemory()
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         "schemas",
+		ModelInfoFile:  "objectbox-model.json",
+		ChangelogFiles: []string{"changes.md", "changes.json"},
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:             memory,
+	}
+	var process = func(schema string) {
+		assert.NoErr(t, memory.MkdirAll(options.InPath, 0755))
+		assert.NoErr(t, memory.WriteFile(filepath.Join(options.InPath, "schema.fbs"), []byte(schema), 0644))
+		assert.NoErr(t, generator.Process(options))
+	}
+	var read = func(name string) string {
+		data, err := memory.ReadFile(name)
+		assert.NoErr(t, err)
+		return string(data)
+	}
+
+	process("table A { id: ulong; name: string; count: int; }\ntable B { id: ulong; }")
+	assert.Eq(t, "# ObjectBox model changes\n\n"+
+		"- entity A added (UID 8717895732742165505)\n"+
+		"- entity B added (UID 2259404117704393152)\n", read("changes.md"))
+
+	modelInfo, err := model.ReadModelFS(memory, options.ModelInfoFile)
+	assert.NoErr(t, err)
+	nameUid, err := modelInfo.Entities[0].Properties[1].Id.GetUid()
+	assert.NoErr(t, err)
+
+	// rename & index a property, reset another one with a new UID, sync-enable A and remove B
+	var schema = fmt.Sprintf(`/// objectbox:sync
+/// objectbox:removed=2259404117704393152
+table A {
+	id: ulong;
+	/// objectbox:uid=%d
+	/// objectbox:index
+	title: string;
+	/// objectbox:uid=1
+	count: long;
+}`, nameUid)
+	process(schema)
+	assert.Eq(t, "# ObjectBox model changes\n\n"+
+		"- entity A flags changed from none to SyncEnabled (UID 8717895732742165505)\n"+
+		"- property A.title renamed from name (UID 501233450539197794)\n"+
+		"- index added to property A.title (UID 501233450539197794)\n"+
+		"- property A.count reset (previous UID 3390393562759376202), type changed from Int to Long (UID 1) - **removes stored data**\n"+
+		"- entity B removed (UID 2259404117704393152) - **removes stored data**\n", read("changes.md"))
+
+	var changelog struct {
+		Changes []struct {
+			model.Change
+			Description string
+			Destructive bool
+		}
+	}
+	assert.NoErr(t, json.Unmarshal([]byte(read("changes.json")), &changelog))
+	assert.Eq(t, 5, len(changelog.Changes))
+	assert.Eq(t, model.PropertyReset, changelog.Changes[3].Kind)
+	assert.Eq(t, model.Uid(3390393562759376202), changelog.Changes[3].OldUid)
+	assert.True(t, changelog.Changes[3].Destructive)
+	assert.Eq(t, "entity B removed", changelog.Changes[4].Description)
+
+	// a run without changes still writes the changelogs
+	process(schema)
+	assert.Eq(t, "# ObjectBox model changes\n\nNo changes.\n", read("changes.md"))
+	assert.Eq(t, "{\n  \"changes\": []\n}\n", read("changes.json"))
+}
@@ -0,0 +1,98 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package check
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestCheck(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "generator-check")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(tempDir)
+
+	var schemaFile = filepath.Join(tempDir, "schema.fbs")
+	var modelFile = filepath.Join(tempDir, "objectbox-model.json")
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         tempDir,
+		ModelInfoFile:  modelFile,
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+	}
+	var check = options
+	check.Check = true
+
+	var checkErrorFiles = func() map[string]string {
+		err := generator.Process(check)
+		assert.True(t, err != nil)
+		checkErr, isCheckErr := err.(*generator.CheckError)
+		assert.True(t, isCheckErr)
+		var result = make(map[string]string)
+		for _, diff := range checkErr.Files {
+			result[filepath.Base(diff.File)] = diff.Status
+		}
+		return result
+	}
+
+	assert.NoErr(t, ioutil.WriteFile(schemaFile, []byte("table A {id: uint64;}"), 0600))
+
+	// nothing generated yet
+	assert.Eq(t, map[string]string{
+		"objectbox-model.json": "missing",
+		"objectbox-model.h":    "missing",
+		"schema.obx.hpp":       "missing",
+		"schema.obx.cpp":       "missing",
+	}, checkErrorFiles())
+	_, err = os.Stat(modelFile)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoErr(t, generator.Process(options))
+	assert.NoErr(t, generator.Process(check))
+
+	// a schema change requires regeneration; the check must not modify the model JSON
+	modelJSON, err := ioutil.ReadFile(modelFile)
+	assert.NoErr(t, err)
+	assert.NoErr(t, ioutil.WriteFile(schemaFile, []byte("table A {id: uint64; name: string;}"), 0600))
+	assert.Eq(t, map[string]string{
+		"objectbox-model.json": "modified",
+		"objectbox-model.h":    "modified",
+		"schema.obx.hpp":       "modified",
+		"schema.obx.cpp":       "modified",
+	}, checkErrorFiles())
+	modelJSONAfterCheck, err := ioutil.ReadFile(modelFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, string(modelJSON), string(modelJSONAfterCheck))
+
+	assert.NoErr(t, generator.Process(options))
+	assert.NoErr(t, generator.Process(check))
+
+	// leftover generated files are reported as stale
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "removed.obx.hpp"), []byte{}, 0600))
+	assert.Eq(t, map[string]string{"removed.obx.hpp": "stale"}, checkErrorFiles())
+}
@@ -0,0 +1,225 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package compat
+
+import (
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+const modelFile = "objectbox-model.json"
+const baseFile = "released-model.json"
+
+const baseSchema = `
+/// objectbox:relation(to=B,name=bs)
+table A {
+	id: ulong;
+	/// objectbox:index
+	name: string;
+	count: int;
+}
+table B {
+	id: ulong;
+	name: string;
+}`
+
+func generate(t *testing.T, fsys vfs.FS, schema string, allowDestructive bool) {
+	assert.NoErr(t, fsys.MkdirAll("schemas", 0755))
+	assert.NoErr(t, fsys.WriteFile(filepath.Join("schemas", "schema.fbs"), []byte(schema), 0644))
+	assert.NoErr(t, generator.Process(generator.Options{
+		Rand:             rand.New(rand.NewSource(0)),
+		InPath:           "schemas",
+		ModelInfoFile:    modelFile,
+		CodeGenerators:   []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:               fsys,
+		AllowDestructive: allowDestructive,
+	}))
+}
+
+// release generates the base schema and keeps a copy of its model as the base model; B.old is removed before so that
+// the base model has a retired UID
+func release(t *testing.T) vfs.FS {
+	var memory = vfs.NewMemory()
+	generate(t, memory, strings.Replace(baseSchema, "name: string;\n}", "name: string;\n\told: string;\n}", 1), false)
+	generate(t, memory, baseSchema, true)
+	data, err := memory.ReadFile(modelFile)
+	assert.NoErr(t, err)
+	assert.NoErr(t, memory.WriteFile(baseFile, data, 0644))
+	return memory
+}
+
+func check(t *testing.T, fsys vfs.FS, allowDestructive bool) []string {
+	problems, err := model.CheckCompatibilityFiles(fsys, baseFile, modelFile, allowDestructive)
+	assert.NoErr(t, err)
+	return problems
+}
+
+// edit changes the current model JSON file, e.g. to simulate a bad manual edit or merge
+func edit(t *testing.T, fsys vfs.FS, fn func(modelInfo *model.ModelInfo)) {
+	modelInfo, err := model.ReadModelFS(fsys, modelFile)
+	assert.NoErr(t, err)
+	fn(modelInfo)
+	data, err := modelInfo.Marshal()
+	assert.NoErr(t, err)
+	assert.NoErr(t, fsys.WriteFile(modelFile, data, 0644))
+}
+
+func TestCompatible(t *testing.T) {
+	var memory = release(t)
+	assert.Eq(t, 0, len(check(t, memory, false)))
+
+	// new elements and renames are fine
+	generate(t, memory, `
+/// objectbox:relation(to=B,name=bs)
+/// objectbox:relation(to=C,name=cs)
+table A {
+	id: ulong;
+	/// objectbox:index
+	name: string;
+	/// objectbox:index
+	/// objectbox:uid=2669985732393126063
+	amount: int;
+}
+table B {
+	id: ulong;
+	name: string;
+}
+table C {
+	id: ulong;
+}`, false)
+	assert.Eq(t, 0, len(check(t, memory, false)))
+}
+
+func TestRemovals(t *testing.T) {
+	var memory = release(t)
+	generate(t, memory, `
+table A {
+	id: ulong;
+	name: string;
+}`, true)
+
+	assert.Eq(t, []string{
+		"index removed from property A.name (UID 501233450539197794) removes stored data",
+		"property A.count removed (UID 2669985732393126063) removes stored data",
+		"relation A.bs removed (UID 1774932891286980153) removes stored data",
+		"entity B removed (UID 2259404117704393152) removes stored data",
+	}, check(t, memory, false))
+	assert.Eq(t, 0, len(check(t, memory, true)))
+}
+
+func TestIncompatible(t *testing.T) {
+	var memory = release(t)
+	base, err := model.ReadModelFS(memory, baseFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, len(base.RetiredPropertyUids)) // B.old
+
+	edit(t, memory, func(modelInfo *model.ModelInfo) {
+		var a, b = modelInfo.Entities[0], modelInfo.Entities[1]
+
+		// a UID used with a different meaning: A.count moved to B
+		var count = a.Properties[2]
+		countUid, err := count.Id.GetUid()
+		assert.NoErr(t, err)
+		count.Id = model.CreateIdUid(4, countUid)
+		b.Properties = append(b.Properties, count)
+		b.LastPropertyId = count.Id
+		a.Properties = a.Properties[:2]
+
+		// an ID reused: the ID of A.count for a new property
+		var score = model.CreateProperty(a, 3, 999)
+		score.Name = "score"
+		score.Type = model.PropertyTypeLong
+		a.Properties = append(a.Properties, score)
+		a.LastPropertyId = score.Id
+
+		// an incompatible type change
+		b.Properties[1].Type = model.PropertyTypeInt
+
+		// a retired UID used again, for a new index
+		var indexId = model.CreateIdUid(2, base.RetiredPropertyUids[0])
+		b.Properties[1].IndexId = &indexId
+		modelInfo.LastIndexId = indexId
+	})
+
+	assert.Eq(t, []string{
+		"property A.score 3:999: ID 3 is not higher than the last property ID 3:2669985732393126063 of the base model, it may have been used before",
+		"property B.name 2:8274930044578894929: incompatible type change from String to Int, the stored data can't be converted",
+		"index B.name 2:1543572285742637646: UID 1543572285742637646 has been retired in the base model (property), it must not be used again",
+		"property B.count 4:2669985732393126063: UID 2669985732393126063 is used by property A.count 3:2669985732393126063 in the base model",
+	}, check(t, memory, true))
+
+	// unlike when generating, the model files must exist
+	problems, err := model.CheckCompatibilityFiles(memory, modelFile, "missing.json", false)
+	assert.Err(t, err)
+	assert.Eq(t, 0, len(problems))
+}
+
+func TestRetiredUids(t *testing.T) {
+	var memory = release(t)
+	edit(t, memory, func(modelInfo *model.ModelInfo) {
+		modelInfo.RetiredPropertyUids = []model.Uid{}
+		modelInfo.Entities[1].Properties = modelInfo.Entities[1].Properties[:1]
+		modelInfo.Entities[1].LastPropertyId = modelInfo.Entities[1].Properties[0].Id
+	})
+
+	assert.Eq(t, []string{
+		"property B.name 2:8274930044578894929 has been removed without retiring its UID 8274930044578894929",
+		"UID 1543572285742637646 has been retired in the base model (property) but isn't retired anymore",
+		"entity B lastPropertyId has been lowered from 3:1543572285742637646 to 1:6044372234677422456, IDs of the base model could be reused",
+	}, check(t, memory, true))
+}
+
+func TestReset(t *testing.T) {
+	var memory = release(t)
+
+	// an incompatible type change is fine if the property is reset with a new UID, its previous UID is retired
+	generate(t, memory, `
+/// objectbox:relation(to=B,name=bs)
+table A {
+	id: ulong;
+	/// objectbox:index
+	name: string;
+	/// objectbox:uid=6514913287441276451
+	count: string;
+}
+table B {
+	id: ulong;
+	name: string;
+}`, false)
+
+	current, err := model.ReadModelFS(memory, modelFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, 2, len(current.RetiredPropertyUids)) // B.old and the previous A.count
+	assert.Eq(t, model.Uid(2669985732393126063), current.RetiredPropertyUids[1])
+
+	assert.Eq(t, []string{
+		"property A.count reset (previous UID 2669985732393126063), type changed from Int to String removes stored data",
+	}, check(t, memory, false))
+	assert.Eq(t, 0, len(check(t, memory, true)))
+}
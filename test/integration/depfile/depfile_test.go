@@ -0,0 +1,98 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package depfile
+
+import (
+	"encoding/json"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestDepfileAndManifest(t *testing.T) {
+	var fsys = vfs.NewMemory()
+	var write = func(name, content string) {
+		assert.NoErr(t, fsys.MkdirAll(filepath.Dir(filepath.FromSlash(name)), 0755))
+		assert.NoErr(t, fsys.WriteFile(filepath.FromSlash(name), []byte(content), 0644))
+	}
+	write("schema/main.fbs", `include "inc/common.fbs";
+table Main { id: ulong; name: string; }`)
+	write("schema/inc/common.fbs", "table Common { id: ulong; }")
+	write("schema/my data.fbs", "table Other { id: ulong; }")
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         "schema",
+		OutPath:        "gen",
+		ModelInfoFile:  filepath.FromSlash("schema/objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		FS:             fsys,
+		DepFile:        "gen.d",
+		ManifestFile:   "gen.json",
+	}
+	result, err := generator.Run(options)
+	assert.NoErr(t, err)
+
+	assert.Eq(t, []string{
+		filepath.FromSlash("schema/inc/common.fbs"),
+		filepath.FromSlash("schema/main.fbs"),
+		filepath.FromSlash("schema/my data.fbs"),
+		filepath.FromSlash("schema/objectbox-model.json"),
+	}, result.Inputs)
+
+	depfile, err := fsys.ReadFile("gen.d")
+	assert.NoErr(t, err)
+	assert.Eq(t, strings.Join([]string{
+		`gen/main.obx.hpp \`,
+		` gen/main.obx.cpp \`,
+		` gen/my\ data.obx.hpp \`,
+		` gen/my\ data.obx.cpp \`,
+		` gen/objectbox-model.h: \`,
+		`  schema/inc/common.fbs \`,
+		`  schema/main.fbs \`,
+		`  schema/my\ data.fbs \`,
+		`  schema/objectbox-model.json`,
+		``,
+	}, "\n"), string(depfile))
+
+	data, err := fsys.ReadFile("gen.json")
+	assert.NoErr(t, err)
+	var manifest map[string][]string
+	assert.NoErr(t, json.Unmarshal(data, &manifest))
+	assert.Eq(t, result.Outputs(), manifest["outputs"])
+	assert.Eq(t, result.Inputs, manifest["inputs"])
+	assert.Eq(t, 0, len(manifest["removed"]))
+	assert.Eq(t, filepath.FromSlash("schema/objectbox-model.json"), manifest["outputs"][0])
+
+	// nothing is written in the check mode
+	assert.NoErr(t, fsys.Remove("gen.d"))
+	options.Check = true
+	_, err = generator.Run(options)
+	assert.NoErr(t, err)
+	_, err = fsys.Stat("gen.d")
+	assert.Err(t, err)
+}
@@ -0,0 +1,91 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package destructive
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestDestructiveChanges(t *testing.T) {
+	var memory = vfs.NewMemory()
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         "schemas",
+		ModelInfoFile:  "objectbox-model.json",
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:             memory,
+	}
+	var process = func(schema string) error {
+		assert.NoErr(t, memory.MkdirAll(options.InPath, 0755))
+		assert.NoErr(t, memory.WriteFile(filepath.Join(options.InPath, "schema.fbs"), []byte(schema), 0644))
+		return generator.Process(options)
+	}
+
+	assert.NoErr(t, process("table A { id: ulong; name: string; }\ntable B { id: ulong; }"))
+	modelInfo, err := model.ReadModelFS(memory, options.ModelInfoFile)
+	assert.NoErr(t, err)
+	propertyUid, err := modelInfo.Entities[0].Properties[1].Id.GetUid()
+	assert.NoErr(t, err)
+	entityUid, err := modelInfo.Entities[1].Id.GetUid()
+	assert.NoErr(t, err)
+	modelJSON, err := memory.ReadFile(options.ModelInfoFile)
+	assert.NoErr(t, err)
+
+	// removing a property and an entity is refused, listing both, and the model is left untouched
+	err = process("table A { id: ulong; }")
+	assert.Err(t, err)
+	var d *diagnostics.Diagnostic
+	assert.True(t, errors.As(err, &d))
+	assert.Eq(t, diagnostics.CodeDestructive, d.Code)
+	assert.True(t, strings.Contains(err.Error(), fmt.Sprintf("(UID %d)", propertyUid)))
+	assert.True(t, strings.Contains(err.Error(), fmt.Sprintf("(UID %d)", entityUid)))
+	afterJSON, err := memory.ReadFile(options.ModelInfoFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, string(modelJSON), string(afterJSON))
+
+	// acknowledging only some of the removals isn't enough
+	err = process(fmt.Sprintf("/// objectbox:removed=%d\ntable A { id: ulong; }", propertyUid))
+	assert.Err(t, err)
+	assert.True(t, !strings.Contains(err.Error(), fmt.Sprintf("(UID %d)", propertyUid)))
+
+	assert.NoErr(t, process(fmt.Sprintf("/// objectbox:removed=%d;%d\ntable A { id: ulong; }", propertyUid, entityUid)))
+	modelInfo, err = model.ReadModelFS(memory, options.ModelInfoFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, len(modelInfo.Entities))
+	assert.Eq(t, 1, len(modelInfo.Entities[0].Properties))
+
+	// or the check can be turned off altogether
+	options.AllowDestructive = true
+	assert.NoErr(t, process("table A { id: ulong; name: string; }\ntable C { id: ulong; }"))
+	assert.NoErr(t, process("table C { id: ulong; }"))
+}
@@ -0,0 +1,125 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package discovery
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+// entityNames returns the names of the entities in the given model JSON file
+func entityNames(t *testing.T, fsys vfs.FS, modelInfoFile string) []string {
+	modelInfo, err := model.ReadModelFS(fsys, modelInfoFile)
+	assert.NoErr(t, err)
+	var names []string
+	for _, entity := range modelInfo.Entities {
+		names = append(names, entity.Name)
+	}
+	return names
+}
+
+func TestModelDiscovery(t *testing.T) {
+	var memory = vfs.NewMemory()
+	var write = func(name, content string) {
+		assert.NoErr(t, memory.MkdirAll(filepath.Dir(filepath.FromSlash(name)), 0755))
+		assert.NoErr(t, memory.WriteFile(filepath.FromSlash(name), []byte(content), 0644))
+	}
+	write("project/a/a.fbs", "table A { id: ulong; }")
+	write("project/b/b.fbs", "table B { id: ulong; }")
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         filepath.FromSlash("project/..."),
+		ModelDiscovery: true,
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		FS:             memory,
+	}
+
+	// each directory gets its own model and model binding file
+	assert.NoErr(t, generator.Process(options))
+	var modelA = filepath.FromSlash("project/a/objectbox-model.json")
+	var modelB = filepath.FromSlash("project/b/objectbox-model.json")
+	assert.Eq(t, []string{"A"}, entityNames(t, memory, modelA))
+	assert.Eq(t, []string{"B"}, entityNames(t, memory, modelB))
+	for _, file := range []string{"project/a/objectbox-model.h", "project/b/objectbox-model.h", "project/a/a.obx.hpp", "project/b/b.obx.hpp"} {
+		_, err := memory.Stat(filepath.FromSlash(file))
+		assert.NoErr(t, err)
+	}
+	_, err := memory.Stat(filepath.FromSlash("project/objectbox-model.json"))
+	assert.Err(t, err)
+
+	// a source in a subdirectory uses the model of the closest parent directory; the other model isn't touched and
+	// its entities aren't considered removed
+	write("project/b/sub/c.fbs", "table C { id: ulong; }")
+	result, err := generator.Run(options)
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{"A"}, entityNames(t, memory, modelA))
+	assert.Eq(t, []string{"B", "C"}, entityNames(t, memory, modelB))
+	_, err = memory.Stat(filepath.FromSlash("project/b/sub/objectbox-model.json"))
+	assert.Err(t, err)
+	assert.Eq(t, 1, len(result.Changes))
+	assert.Eq(t, 0, len(result.Removed))
+
+	// everything is up-to-date
+	options.Check = true
+	assert.NoErr(t, generator.Process(options))
+	options.Check = false
+
+	// an error in one of the directories prevents writing any of the models
+	write("project/a/a.fbs", "table A { id: ulong; } table D { id: ulong; }")
+	write("project/b/b.fbs", "table B { id: ulong; name: unknown; }")
+	assert.Err(t, generator.Process(options))
+	assert.Eq(t, []string{"A"}, entityNames(t, memory, modelA))
+
+	// the model can't be given explicitly
+	options.ModelInfoFile = modelA
+	assert.Err(t, generator.Process(options))
+}
+
+func TestModelDiscoveryRoot(t *testing.T) {
+	var memory = vfs.NewMemory()
+	var write = func(name, content string) {
+		assert.NoErr(t, memory.MkdirAll(filepath.Dir(filepath.FromSlash(name)), 0755))
+		assert.NoErr(t, memory.WriteFile(filepath.FromSlash(name), []byte(content), 0644))
+	}
+	write("objectbox-model.json", "unrelated")
+	write("project/a/a.fbs", "table A { id: ulong; }")
+
+	// a model in a parent directory of the input path isn't used (nor locked or changed)
+	assert.NoErr(t, generator.Process(generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         filepath.FromSlash("project/..."),
+		ModelDiscovery: true,
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		FS:             memory,
+	}))
+	assert.Eq(t, []string{"A"}, entityNames(t, memory, filepath.FromSlash("project/a/objectbox-model.json")))
+	data, err := memory.ReadFile("objectbox-model.json")
+	assert.NoErr(t, err)
+	assert.Eq(t, "unrelated", string(data))
+}
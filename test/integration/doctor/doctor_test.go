@@ -0,0 +1,185 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package doctor
+
+import (
+	"encoding/json"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+const modelFile = "objectbox-model.json"
+
+func generate(t *testing.T, fsys vfs.FS) {
+	assert.NoErr(t, fsys.MkdirAll("schemas", 0755))
+	assert.NoErr(t, fsys.WriteFile(filepath.Join("schemas", "schema.fbs"), []byte(`
+/// objectbox:relation(to=B,name=bs)
+table A {
+	id: ulong;
+	name: string;
+}
+table B {
+	id: ulong;
+	name: string;
+}`), 0644))
+	assert.NoErr(t, generator.Process(generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         "schemas",
+		ModelInfoFile:  modelFile,
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:             fsys,
+	}))
+}
+
+// edit changes the model JSON file as a generic JSON document, e.g. to simulate a bad manual edit
+func edit(t *testing.T, fsys vfs.FS, fn func(m map[string]interface{})) {
+	data, err := fsys.ReadFile(modelFile)
+	assert.NoErr(t, err)
+	var m map[string]interface{}
+	assert.NoErr(t, json.Unmarshal(data, &m))
+	fn(m)
+	data, err = json.MarshalIndent(m, "", "  ")
+	assert.NoErr(t, err)
+	assert.NoErr(t, fsys.WriteFile(modelFile, data, 0644))
+}
+
+func entity(m map[string]interface{}, i int) map[string]interface{} {
+	return m["entities"].([]interface{})[i].(map[string]interface{})
+}
+
+func messages(problems []model.Problem) []string {
+	var result []string
+	for _, problem := range problems {
+		result = append(result, problem.Message)
+	}
+	return result
+}
+
+func TestDoctor(t *testing.T) {
+	var memory = vfs.NewMemory()
+	generate(t, memory)
+
+	problems, before, after, err := model.DoctorFile(memory, modelFile, 0, true)
+	assert.NoErr(t, err)
+	assert.Eq(t, 0, len(problems))
+	assert.Eq(t, string(before), string(after))
+
+	// typical results of a manual edit or a bad merge
+	edit(t, memory, func(m map[string]interface{}) {
+		delete(m, "retiredRelationUids")
+		m["retiredPropertyUids"] = []uint64{42, 42}
+		m["lastEntityId"] = "9:1234"
+		entity(m, 0)["lastPropertyId"] = "1:5678"
+		// the ID of B differs from the relation's target ID, the UID is the same
+		entity(m, 1)["id"] = "3:2259404117704393152"
+	})
+
+	problems, before, after, err = model.DoctorFile(memory, modelFile, 0, false)
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{
+		"retiredPropertyUids: UID 42 is listed multiple times",
+		"retiredRelationUids are not defined or not an array",
+		"entity A 1:8717895732742165505: lastPropertyId 1:5678 is lower than the highest ID, should be 2:501233450539197794 (retiring UID 5678)",
+		"relation A.bs 1:3390393562759376202: target ID 2:2259404117704393152 doesn't match entity B 3:2259404117704393152",
+		"lastEntityId 9:1234 doesn't match any current or retired element (retiring UID 1234)",
+	}, messages(problems))
+	for _, problem := range problems {
+		assert.True(t, problem.Fixable)
+	}
+	assert.NotEq(t, string(before), string(after))
+
+	// without -fix, the file isn't changed
+	data, err := memory.ReadFile(modelFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, string(before), string(data))
+
+	_, _, fixed, err := model.DoctorFile(memory, modelFile, 0, true)
+	assert.NoErr(t, err)
+	assert.Eq(t, string(after), string(fixed))
+	data, err = memory.ReadFile(modelFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, string(after), string(data))
+
+	modelInfo, err := model.ReadModelFS(memory, modelFile)
+	assert.NoErr(t, err)
+	assert.NoErr(t, modelInfo.Validate())
+	assert.Eq(t, model.IdUid("3:2259404117704393152"), modelInfo.Entities[0].Relations[0].TargetId)
+	assert.Eq(t, []model.Uid{42, 5678}, modelInfo.RetiredPropertyUids)
+	assert.Eq(t, []model.Uid{1234}, modelInfo.RetiredEntityUids)
+
+	problems, err = modelInfo.Diagnose()
+	assert.NoErr(t, err)
+	assert.Eq(t, 0, len(problems))
+}
+
+func TestDoctorUnfixable(t *testing.T) {
+	var memory = vfs.NewMemory()
+	generate(t, memory)
+
+	edit(t, memory, func(m map[string]interface{}) {
+		var properties = entity(m, 1)["properties"].([]interface{})
+		properties[1].(map[string]interface{})["name"] = "Id"
+		entity(m, 1)["lastPropertyId"] = "1:5678"
+	})
+
+	problems, before, after, err := model.DoctorFile(memory, modelFile, 0, true)
+	assert.NoErr(t, err)
+	assert.Eq(t, 2, len(problems))
+	assert.Eq(t, "property B.Id 2:1774932891286980153: duplicate property name (note that property names are case insensitive)", problems[0].Message)
+	assert.True(t, !problems[0].Fixable)
+	assert.True(t, problems[1].Fixable)
+
+	// the fixable problems are fixed anyway
+	data, err := memory.ReadFile(modelFile)
+	assert.NoErr(t, err)
+	assert.NotEq(t, string(before), string(data))
+	assert.Eq(t, string(after), string(data))
+}
+
+func TestDoctorLastIdRetired(t *testing.T) {
+	var memory = vfs.NewMemory()
+	generate(t, memory)
+
+	// a retired entity may have had a higher ID than the current ones, so the last entity ID can't be derived from
+	// them; no relation has been retired, the last relation ID is repaired
+	edit(t, memory, func(m map[string]interface{}) {
+		m["retiredEntityUids"] = []uint64{1234}
+		delete(m, "lastEntityId")
+		m["lastRelationId"] = "x"
+	})
+
+	problems, before, after, err := model.DoctorFile(memory, modelFile, 0, true)
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{
+		"lastEntityId is missing and can't be repaired: the IDs of the retired UIDs aren't known, they may be higher than the current ones",
+		"lastRelationId x is invalid (invalid id format - uid is missing), should be 1:3390393562759376202",
+	}, messages(problems))
+	assert.True(t, !problems[0].Fixable)
+	assert.True(t, problems[1].Fixable)
+	assert.NotEq(t, string(before), string(after))
+}
@@ -0,0 +1,131 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package errors
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestCollectErrors(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "generator-errors")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(tempDir)
+
+	var modelFile = filepath.Join(tempDir, "objectbox-model.json")
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         tempDir,
+		ModelInfoFile:  modelFile,
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+	}
+
+	var writeSchema = func(name, content string) {
+		assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, name), []byte(content), 0600))
+	}
+
+	var listFiles = func() map[string]string {
+		files, err := filepath.Glob(filepath.Join(tempDir, "*"))
+		assert.NoErr(t, err)
+		var result = make(map[string]string)
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			assert.NoErr(t, err)
+			result[filepath.Base(file)] = string(data)
+		}
+		return result
+	}
+
+	var errorCount = func(err error) int {
+		assert.True(t, err != nil)
+		if list, isList := err.(diagnostics.List); isList {
+			return len(list)
+		}
+		return 1
+	}
+
+	writeSchema("a.fbs", "table A {id: uint64;}")
+	writeSchema("b.fbs", "table B {\n    id: uint64;\n    /// objectbox:index=foo\n    text: string;\n}\ntable C {\n    id: uint64;\n    /// objectbox:index=bar\n    text: string;\n}")
+	writeSchema("c.fbs", "table D {\n    id: uint64;\n    /// objectbox:index=baz\n    text: string;\n}")
+
+	// all errors of all files are reported and nothing is written, not even a new model JSON
+	var before = listFiles()
+	assert.Eq(t, 3, errorCount(generator.Process(options)))
+	assert.Eq(t, before, listFiles())
+
+	// the previous behaviour: stop at the first file with errors
+	var failFast = options
+	failFast.FailFast = true
+	assert.Eq(t, 2, errorCount(generator.Process(failFast)))
+	assert.Eq(t, before, listFiles())
+
+	// existing files are neither updated nor cleaned up on an error
+	assert.NoErr(t, os.Remove(filepath.Join(tempDir, "b.fbs")))
+	assert.NoErr(t, os.Remove(filepath.Join(tempDir, "c.fbs")))
+	assert.NoErr(t, generator.Process(options))
+	writeSchema("stale.obx.hpp", "")
+	writeSchema("a.fbs", "table A {id: uint64; name: string;}")
+	writeSchema("c.fbs", "table D {\n    id: uint64;\n    /// objectbox:index=baz\n    text: string;\n}")
+	before = listFiles()
+	assert.Eq(t, 1, errorCount(generator.Process(options)))
+	assert.Eq(t, before, listFiles())
+}
+
+func TestRemovedEntityNotice(t *testing.T) {
+	var out bytes.Buffer
+	diagnostics.SetOutput(&out)
+	diagnostics.SetFormat(diagnostics.FormatJSON)
+	defer func() {
+		diagnostics.SetFormat(diagnostics.FormatText)
+		diagnostics.SetOutput(os.Stderr)
+	}()
+
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.MkdirAll("schema", 0755))
+	assert.NoErr(t, memory.WriteFile(filepath.Join("schema", "a.fbs"), []byte("table A {id: uint64;}"), 0644))
+	assert.NoErr(t, memory.WriteFile(filepath.Join("schema", "b.fbs"), []byte("table B {id: uint64;}"), 0644))
+
+	var options = generator.Options{
+		Rand:             rand.New(rand.NewSource(0)),
+		InPath:           "schema",
+		ModelInfoFile:    filepath.Join("schema", "objectbox-model.json"),
+		CodeGenerators:   []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		FS:               memory,
+		AllowDestructive: true,
+	}
+	assert.NoErr(t, generator.Process(options))
+	assert.Eq(t, "", out.String())
+
+	// the removal is reported as a notice in the structured output
+	assert.NoErr(t, memory.Remove(filepath.Join("schema", "b.fbs")))
+	assert.NoErr(t, generator.Process(options))
+	assert.Eq(t, `{"severity":"notice","code":"OBX1004","message":"removing missing entity B 2:6050128673802995827 from the model","entity":"B"}`+"\n", out.String())
+}
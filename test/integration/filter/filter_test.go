@@ -0,0 +1,84 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package filter
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestSourceFilter(t *testing.T) {
+	var memory = vfs.NewMemory()
+	var write = func(name, content string) {
+		assert.NoErr(t, memory.MkdirAll(filepath.Dir(filepath.FromSlash(name)), 0755))
+		assert.NoErr(t, memory.WriteFile(filepath.FromSlash(name), []byte(content), 0644))
+	}
+	write("schemas/a.fbs", "table A { id: ulong; }")
+	write("schemas/vendor/v.fbs", "table V { id: ulong; }")
+	write("schemas/.hidden/h.fbs", "table H { id: ulong; }")
+	write("schemas/testdata/t.fbs", "table T { id: ulong; }")
+	write("schemas/third_party/x.fbs", "table X { id: ulong; }")
+	write("schemas/sub/s.fbs", "table S { id: ulong; }")
+	write("schemas/sub/skipped.fbs", "table Skipped { id: ulong; }")
+	write("schemas/sub/"+generator.IgnoreFileName, "# generated mocks\nskip*.fbs\n")
+
+	var options = generator.Options{
+		Rand:          rand.New(rand.NewSource(0)),
+		InPath:        filepath.FromSlash("schemas/..."),
+		ModelInfoFile: "objectbox-model.json",
+		Exclude:       []string{"testdata", "third_party/*.fbs"},
+		// the entities of the sources filtered out are removed from the model
+		AllowDestructive: true,
+		CodeGenerators:   []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:               memory,
+	}
+
+	var entities = func() []string {
+		modelInfo, err := model.ReadModelFS(memory, options.ModelInfoFile)
+		assert.NoErr(t, err)
+		var names []string
+		for _, entity := range modelInfo.Entities {
+			names = append(names, entity.Name)
+		}
+		return names
+	}
+
+	assert.NoErr(t, generator.Process(options))
+	assert.Eq(t, []string{"A", "S"}, entities())
+	_, err := memory.Stat(filepath.FromSlash("schemas/vendor/v.obx.h"))
+	assert.Err(t, err)
+
+	options.Include = []string{"sub/*"}
+	assert.NoErr(t, generator.Process(options))
+	assert.Eq(t, []string{"S"}, entities())
+
+	// a single source file is used even if excluded
+	options.InPath = filepath.FromSlash("schemas/testdata/t.fbs")
+	assert.NoErr(t, generator.Process(options))
+	assert.Eq(t, []string{"S", "T"}, entities())
+}
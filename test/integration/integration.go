@@ -102,10 +102,10 @@ func repoRoot(t *testing.T) string {
 func generateCCpp(t *testing.T, srcPath string, outDir string, cGenerator *cgenerator.CGenerator) {
 	t.Logf("generating code for %s into %s", srcPath, outDir)
 	var options = generator.Options{
-		ModelInfoFile: path.Join(outDir, "objectbox-model.json"),
-		CodeGenerator: cGenerator,
-		InPath:        srcPath,
-		OutPath:       outDir,
+		ModelInfoFile:  path.Join(outDir, "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{cGenerator},
+		InPath:         srcPath,
+		OutPath:        outDir,
 	}
 	assert.NoErr(t, generator.Process(options))
 }
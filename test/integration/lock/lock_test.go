@@ -0,0 +1,81 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package lock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestModelLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator-lock")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, "a.fbs"), []byte("table A { id: ulong; }"), 0644))
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         filepath.Join(dir, "a.fbs"),
+		ModelInfoFile:  filepath.Join(dir, "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		LockTimeout:    100 * time.Millisecond,
+	}
+
+	// the lock is held from loading the model until closing it
+	holder, err := model.LoadOrCreateModel(options.ModelInfoFile)
+	assert.NoErr(t, err)
+
+	var start = time.Now()
+	err = generator.Process(options)
+	assert.Err(t, err)
+	assert.True(t, time.Since(start) >= options.LockTimeout)
+	if !strings.Contains(err.Error(), fmt.Sprintf("in use by process %d (", os.Getpid())) {
+		t.Fatalf("the error doesn't name the lock holder: %s", err)
+	}
+
+	// the lock is released on close, the lock file is removed
+	assert.NoErr(t, holder.Close())
+	assert.NoErr(t, generator.Process(options))
+	_, err = os.Stat(model.LockFile(options.ModelInfoFile))
+	assert.True(t, os.IsNotExist(err))
+
+	// a waiting process gets the lock as soon as it's released
+	holder, err = model.LoadOrCreateModel(options.ModelInfoFile)
+	assert.NoErr(t, err)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		holder.Close()
+	}()
+	options.LockTimeout = 10 * time.Second
+	assert.NoErr(t, generator.Process(options))
+}
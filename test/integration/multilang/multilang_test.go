@@ -0,0 +1,96 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package multilang
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+const schema = `
+table Task {
+	id: uint64;
+	/// objectbox:optional
+	text: string;
+	/// objectbox:relation=Group
+	groupId: ulong;
+}
+
+table Group {
+	id: uint64;
+	name: string;
+}
+`
+
+func generate(t *testing.T, dir string, codeGenerators ...generator.CodeGenerator) {
+	var options = generator.Options{
+		ModelInfoFile:  filepath.Join(dir, "objectbox-model.json"),
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         filepath.Join(dir, "schema.fbs"),
+		CodeGenerators: codeGenerators,
+	}
+	assert.NoErr(t, ioutil.WriteFile(options.InPath, []byte(schema), 0600))
+	assert.NoErr(t, generator.Process(options))
+}
+
+func readFile(t *testing.T, file string) string {
+	data, err := ioutil.ReadFile(file)
+	assert.NoErr(t, err)
+	return string(data)
+}
+
+// TestCAndCpp verifies that generating multiple languages in a single run produces the same output as separate runs.
+func TestCAndCpp(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "generator-multilang")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(tempDir)
+
+	var newC = func() *cgenerator.CGenerator {
+		return &cgenerator.CGenerator{PlainC: true, LangVersion: -1, Optional: "ptr"}
+	}
+	var newCpp = func() *cgenerator.CGenerator {
+		return &cgenerator.CGenerator{LangVersion: 14, Optional: "std::optional"}
+	}
+
+	var combinedDir = filepath.Join(tempDir, "combined")
+	var cDir = filepath.Join(tempDir, "c")
+	var cppDir = filepath.Join(tempDir, "cpp")
+	for _, dir := range []string{combinedDir, cDir, cppDir} {
+		assert.NoErr(t, os.MkdirAll(dir, 0700))
+	}
+
+	generate(t, combinedDir, newC(), newCpp())
+	generate(t, cDir, newC())
+	generate(t, cppDir, newCpp())
+
+	assert.Eq(t, readFile(t, filepath.Join(cDir, "objectbox-model.json")), readFile(t, filepath.Join(combinedDir, "objectbox-model.json")))
+	assert.Eq(t, readFile(t, filepath.Join(cDir, "objectbox-model.h")), readFile(t, filepath.Join(combinedDir, "objectbox-model.h")))
+	assert.Eq(t, readFile(t, filepath.Join(cDir, "schema.obx.h")), readFile(t, filepath.Join(combinedDir, "schema.obx.h")))
+	assert.Eq(t, readFile(t, filepath.Join(cppDir, "schema.obx.hpp")), readFile(t, filepath.Join(combinedDir, "schema.obx.hpp")))
+	assert.Eq(t, readFile(t, filepath.Join(cppDir, "schema.obx.cpp")), readFile(t, filepath.Join(combinedDir, "schema.obx.cpp")))
+}
@@ -0,0 +1,137 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package outdirs
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestPreserveDirs(t *testing.T) {
+	var memory = vfs.NewMemory()
+	var write = func(name, content string) {
+		assert.NoErr(t, memory.MkdirAll(filepath.Dir(filepath.FromSlash(name)), 0755))
+		assert.NoErr(t, memory.WriteFile(filepath.FromSlash(name), []byte(content), 0644))
+	}
+	var exists = func(name string) bool {
+		_, err := memory.Stat(filepath.FromSlash(name))
+		return err == nil
+	}
+	write("schemas/a/user.fbs", "table A { id: ulong; }")
+	write("schemas/b/user.fbs", "table B { id: ulong; }")
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         filepath.FromSlash("schemas/..."),
+		OutPath:        "out",
+		OutHeadersPath: "include",
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		FS:             memory,
+	}
+
+	// both sources would be generated into the same files
+	err := generator.Process(options)
+	assert.Err(t, err)
+	if !strings.Contains(err.Error(), "output path collision") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.True(t, !exists("out/user.obx.cpp"))
+
+	// the directories of the sources are kept, the includes are relative to the headers path
+	options.PreserveDirs = true
+	assert.NoErr(t, generator.Process(options))
+	for _, file := range []string{"include/a/user.obx.hpp", "include/b/user.obx.hpp", "out/a/user.obx.cpp", "out/b/user.obx.cpp", "include/objectbox-model.h"} {
+		assert.True(t, exists(file))
+	}
+	data, err := memory.ReadFile(filepath.FromSlash("out/b/user.obx.cpp"))
+	assert.NoErr(t, err)
+	assert.True(t, strings.Contains(string(data), `#include "b/user.obx.hpp"`))
+
+	// files not generated anymore are removed from the output subdirectories
+	assert.NoErr(t, memory.Remove(filepath.FromSlash("schemas/b/user.fbs")))
+	write("schemas/b/person.fbs", "table B { id: ulong; }")
+	assert.NoErr(t, generator.Process(options))
+	assert.True(t, exists("out/b/person.obx.cpp"))
+	assert.True(t, !exists("out/b/user.obx.cpp"))
+}
+
+func TestPreserveDirsModelDiscovery(t *testing.T) {
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.MkdirAll(filepath.FromSlash("schemas/a"), 0755))
+	assert.NoErr(t, memory.MkdirAll(filepath.FromSlash("schemas/b"), 0755))
+	assert.NoErr(t, memory.WriteFile(filepath.FromSlash("schemas/a/a.fbs"), []byte("table A { id: ulong; }"), 0644))
+	assert.NoErr(t, memory.WriteFile(filepath.FromSlash("schemas/b/b.fbs"), []byte("table B { id: ulong; }"), 0644))
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         filepath.FromSlash("schemas/..."),
+		OutPath:        "out",
+		ModelDiscovery: true,
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:             memory,
+	}
+
+	// all the model files would be generated into the same directory
+	assert.Err(t, generator.Process(options))
+
+	options.PreserveDirs = true
+	assert.NoErr(t, generator.Process(options))
+	for _, file := range []string{"out/a/objectbox-model.h", "out/b/objectbox-model.h", "out/a/a.obx.h", "out/b/b.obx.h"} {
+		_, err := memory.Stat(filepath.FromSlash(file))
+		assert.NoErr(t, err)
+	}
+}
+
+func TestPreserveDirsOnDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator-outdirs")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a", "b"} {
+		assert.NoErr(t, os.MkdirAll(filepath.Join(dir, "schemas", name), 0755))
+		var schema = "table " + strings.ToUpper(name) + " { id: ulong; }"
+		assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, "schemas", name, "user.fbs"), []byte(schema), 0644))
+	}
+
+	// the output subdirectories don't exist yet and are created by the run
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         filepath.Join(dir, "schemas", "..."),
+		OutPath:        filepath.Join(dir, "out"),
+		OutHeadersPath: filepath.Join(dir, "include"),
+		PreserveDirs:   true,
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+	}
+	assert.NoErr(t, generator.Process(options))
+	for _, file := range []string{"include/a/user.obx.hpp", "include/b/user.obx.hpp", "out/a/user.obx.cpp", "out/b/user.obx.cpp"} {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file)))
+		assert.NoErr(t, err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package parallel
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+// generate runs the generator on a fresh in-memory copy of the sources
+func generate(t *testing.T, workers int, failFast bool, broken ...int) (*generator.Result, error) {
+	var fsys = vfs.NewMemory()
+	assert.NoErr(t, fsys.MkdirAll("schema", 0755))
+	for i := 0; i < 40; i++ {
+		var source = fmt.Sprintf("table E%02d { id: ulong; name: string; value: int; }", i)
+		for _, b := range broken {
+			if b == i {
+				source = fmt.Sprintf("/// objectbox:index=foo\ntable E%02d { id: ulong; }", i)
+			}
+		}
+		assert.NoErr(t, fsys.WriteFile(filepath.Join("schema", fmt.Sprintf("e%02d.fbs", i)), []byte(source), 0644))
+	}
+
+	return generator.Run(generator.Options{
+		Rand:           rand.New(rand.NewSource(42)),
+		InPath:         "schema",
+		ModelInfoFile:  filepath.Join("schema", "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true, LangVersion: -1}, &cgenerator.CGenerator{LangVersion: 14}},
+		FS:             fsys,
+		Workers:        workers,
+		FailFast:       failFast,
+		InMemory:       true,
+	})
+}
+
+func TestParallelIsDeterministic(t *testing.T) {
+	sequential, err := generate(t, 1, false)
+	assert.NoErr(t, err)
+
+	for _, workers := range []int{2, 8, 0} {
+		for run := 0; run < 3; run++ {
+			parallel, err := generate(t, workers, false)
+			assert.NoErr(t, err)
+			assert.Eq(t, len(sequential.Files), len(parallel.Files))
+			for i := range sequential.Files {
+				assert.Eq(t, sequential.Files[i].Path, parallel.Files[i].Path)
+				if !bytes.Equal(sequential.Files[i].Data, parallel.Files[i].Data) {
+					t.Fatalf("workers %d: file %s differs from the sequential run", workers, parallel.Files[i].Path)
+				}
+			}
+		}
+	}
+}
+
+func TestParallelErrors(t *testing.T) {
+	_, sequentialErr := generate(t, 1, false, 7, 21, 33)
+	assert.Err(t, sequentialErr)
+
+	_, parallelErr := generate(t, 8, false, 7, 21, 33)
+	assert.Eq(t, sequentialErr.Error(), parallelErr.Error())
+
+	// with FailFast, the first error in the source order is reported, regardless of the workers finishing order
+	_, sequentialErr = generate(t, 1, true, 7, 21, 33)
+	for run := 0; run < 5; run++ {
+		_, parallelErr = generate(t, 8, true, 7, 21, 33)
+		assert.Eq(t, sequentialErr.Error(), parallelErr.Error())
+	}
+}
@@ -0,0 +1,399 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package pin
+
+import (
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/diagnostics"
+	gogenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/go"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+const schema = `namespace ns;
+
+/// A comment
+/// objectbox:relation(to=B,name=bs), relation(name = others, to = B)
+table A {
+    id: ulong;
+    /// objectbox:index,uid
+    name: string;
+    /// docs only
+    count: int;
+}
+
+table B {
+	id: ulong;
+}
+`
+
+// the expected sources contain {Entity}, {Entity.property} and {Entity.relation} placeholders for the UIDs
+const expectedSchema = `namespace ns;
+
+/// A comment
+/// objectbox:relation(to=B,name=bs,uid={A.bs}), relation(name = others, to = B,uid={A.others})
+/// objectbox:uid={A}
+table A {
+    /// objectbox:uid={A.id}
+    id: ulong;
+    /// objectbox:index,uid={A.name}
+    name: string;
+    /// docs only
+    /// objectbox:uid={A.count}
+    count: int;
+}
+
+/// objectbox:uid={B}
+table B {
+	/// objectbox:uid={B.id}
+	id: ulong;
+}
+`
+
+const goSource = `package object
+
+// Task is a task
+// ` + "`" + `objectbox:"sync"` + "`" + `
+type Task struct {
+	Id      uint64
+	Text    string ` + "`" + `json:"text"` + "`" + `
+	Done    bool   ` + "`" + `objectbox:"index"` + "`" + `
+	Owner   *Person ` + "`" + `objectbox:"link"` + "`" + `
+	Helpers []*Person
+}
+
+type (
+	Person struct {
+		Id   uint64 ` + "`" + `objectbox:"id uid"` + "`" + `
+		Name string
+	}
+)
+`
+
+const expectedGoSource = `package object
+
+// Task is a task
+// ` + "`" + `objectbox:"sync uid:{Task}"` + "`" + `
+type Task struct {
+	Id      uint64    ` + "`" + `objectbox:"uid:{Task.Id}"` + "`" + `
+	Text    string    ` + "`" + `json:"text" objectbox:"uid:{Task.Text}"` + "`" + `
+	Done    bool      ` + "`" + `objectbox:"index uid:{Task.Done}"` + "`" + `
+	Owner   *Person   ` + "`" + `objectbox:"link uid:{Task.Owner}"` + "`" + `
+	Helpers []*Person ` + "`" + `objectbox:"uid:{Task.Helpers}"` + "`" + `
+}
+
+type (
+	// ` + "`" + `objectbox:"uid:{Person}"` + "`" + `
+	Person struct {
+		Id   uint64 ` + "`" + `objectbox:"id uid:{Person.Id}"` + "`" + `
+		Name string ` + "`" + `objectbox:"uid:{Person.Name}"` + "`" + `
+	}
+)
+`
+
+var placeholderRegexp = regexp.MustCompile(`\{([A-Za-z.]+)\}`)
+
+// expand replaces the placeholders with the UIDs of the model elements
+func expand(t *testing.T, modelInfo *model.ModelInfo, text string) string {
+	return placeholderRegexp.ReplaceAllStringFunc(text, func(placeholder string) string {
+		var path = strings.Split(placeholder[1:len(placeholder)-1], ".")
+		entity, err := modelInfo.FindEntityByName(path[0])
+		assert.NoErr(t, err)
+		var id = entity.Id
+		if len(path) > 1 {
+			if property, err := entity.FindPropertyByName(path[1]); err == nil {
+				id = property.Id
+			} else {
+				relation, err := entity.FindRelationByName(path[1])
+				assert.NoErr(t, err)
+				id = relation.Id
+			}
+		}
+		uid, err := id.GetUid()
+		assert.NoErr(t, err)
+		return strconv.FormatUint(uid, 10)
+	})
+}
+
+func fbsOptions(fsys vfs.FS) generator.Options {
+	return generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         "a.fbs",
+		ModelInfoFile:  "objectbox-model.json",
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:             fsys,
+		PinUids:        true,
+	}
+}
+
+func TestPinFbs(t *testing.T) {
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.WriteFile("a.fbs", []byte(schema), 0644))
+
+	result, err := generator.Run(fbsOptions(memory))
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, len(result.Sources))
+	assert.Eq(t, "a.fbs", result.Sources[0].Path)
+
+	modelInfo, err := model.ReadModelFS(memory, "objectbox-model.json")
+	assert.NoErr(t, err)
+	data, err := memory.ReadFile("a.fbs")
+	assert.NoErr(t, err)
+	assert.Eq(t, expand(t, modelInfo, expectedSchema), string(data))
+
+	// nothing left to pin
+	result, err = generator.Run(fbsOptions(memory))
+	assert.NoErr(t, err)
+	assert.Eq(t, 0, len(result.Sources))
+
+	// a rename is recognized by the pinned UID
+	assert.NoErr(t, memory.WriteFile("a.fbs", []byte(strings.Replace(string(data), "count: int;", "amount: int;", 1)), 0644))
+	var options = fbsOptions(memory)
+	options.PinUids = false
+	result, err = generator.Run(options)
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, len(result.Changes))
+	assert.Eq(t, model.PropertyRenamed, result.Changes[0].Kind)
+	assert.Eq(t, "count", result.Changes[0].OldName)
+}
+
+func TestPinFbsCompact(t *testing.T) {
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.WriteFile("a.fbs", []byte(`table A { id: ulong; name: string; }
+
+table B {
+  id: ulong; count: int; // the count
+  /// objectbox:index
+  text: string; }
+`), 0644))
+
+	// each field is put on a line of its own, so that its uid annotation can precede it
+	result, err := generator.Run(fbsOptions(memory))
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, len(result.Sources))
+
+	modelInfo, err := model.ReadModelFS(memory, "objectbox-model.json")
+	assert.NoErr(t, err)
+	data, err := memory.ReadFile("a.fbs")
+	assert.NoErr(t, err)
+	assert.Eq(t, expand(t, modelInfo, `/// objectbox:uid={A}
+table A {
+  /// objectbox:uid={A.id}
+  id: ulong;
+  /// objectbox:uid={A.name}
+  name: string;
+}
+
+/// objectbox:uid={B}
+table B {
+  /// objectbox:uid={B.id}
+  id: ulong;
+  /// objectbox:uid={B.count}
+  count: int; // the count
+  /// objectbox:index
+  /// objectbox:uid={B.text}
+  text: string;
+}
+`), string(data))
+
+	// nothing left to pin
+	result, err = generator.Run(fbsOptions(memory))
+	assert.NoErr(t, err)
+	assert.Eq(t, 0, len(result.Sources))
+}
+
+func TestPinFbsUnsupportedLayout(t *testing.T) {
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.WriteFile("a.fbs", []byte("table B { id: ulong; } table A { id: ulong; }\n"), 0644))
+
+	err := generator.Process(fbsOptions(memory))
+	assert.Err(t, err)
+	// two tables on the same line are not split
+	if !strings.Contains(err.Error(), "it must be at the start of a line") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestPinFbsSharedInclude(t *testing.T) {
+	var memory = vfs.NewMemory()
+	var write = func(name, content string) {
+		assert.NoErr(t, memory.MkdirAll(filepath.Dir(filepath.FromSlash(name)), 0755))
+		assert.NoErr(t, memory.WriteFile(filepath.FromSlash(name), []byte(content), 0644))
+	}
+	var shared = "table C {\n  id: ulong;\n}\n"
+	write("shared/c.fbs", shared)
+	write("project/a/a.fbs", "include \"../../shared/c.fbs\";\ntable A {\n  id: ulong;\n}\n")
+	write("project/b/b.fbs", "include \"../../shared/c.fbs\";\ntable B {\n  id: ulong;\n}\n")
+
+	// the included file is pinned only once per run; it can't carry the UIDs of two different models
+	var options = fbsOptions(memory)
+	options.InPath = filepath.FromSlash("project/...")
+	options.ModelInfoFile = ""
+	options.ModelDiscovery = true
+	err := generator.Process(options)
+	assert.Err(t, err)
+	if !strings.Contains(err.Error(), "can't pin the UIDs in "+filepath.FromSlash("shared/c.fbs")) {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err := memory.ReadFile(filepath.FromSlash("shared/c.fbs"))
+	assert.NoErr(t, err)
+	assert.Eq(t, shared, string(data))
+	_, err = memory.Stat(filepath.FromSlash("project/a/objectbox-model.json"))
+	assert.Err(t, err)
+}
+
+func TestPinFbsCheck(t *testing.T) {
+	// without pinning, the empty uid annotation would only print the UID to paste
+	var schema = strings.Replace(schema, "index,uid", "index", 1)
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.WriteFile("a.fbs", []byte(schema), 0644))
+
+	// generate without pinning first, the check then reports the source
+	var options = fbsOptions(memory)
+	options.PinUids = false
+	assert.NoErr(t, generator.Process(options))
+
+	options = fbsOptions(memory)
+	options.Check = true
+	err := generator.Process(options)
+	checkErr, isCheckErr := err.(*generator.CheckError)
+	assert.True(t, isCheckErr)
+	assert.Eq(t, 1, len(checkErr.Files))
+	assert.Eq(t, "a.fbs", checkErr.Files[0].File)
+	assert.Eq(t, "modified", checkErr.Files[0].Status)
+
+	data, err := memory.ReadFile("a.fbs")
+	assert.NoErr(t, err)
+	assert.Eq(t, schema, string(data))
+}
+
+func TestPinGo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator-pin")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	// a formatted source stays formatted
+	source, err := format.Source([]byte(goSource))
+	assert.NoErr(t, err)
+	var sourceFile = filepath.Join(dir, "task.go")
+	assert.NoErr(t, ioutil.WriteFile(sourceFile, source, 0644))
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         sourceFile,
+		ModelInfoFile:  filepath.Join(dir, "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&gogenerator.GoGenerator{}},
+		PinUids:        true,
+	}
+	assert.NoErr(t, generator.Process(options))
+
+	modelInfo, err := model.ReadModel(options.ModelInfoFile)
+	assert.NoErr(t, err)
+	data, err := ioutil.ReadFile(sourceFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, expand(t, modelInfo, expectedGoSource), string(data))
+
+	result, err := generator.Run(options)
+	assert.NoErr(t, err)
+	assert.Eq(t, 0, len(result.Sources))
+}
+
+func TestPinGoEmbedded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator-pin")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	// the embedded struct is declared in another file of the package, so that it's not an entity itself
+	var baseFile = filepath.Join(dir, "base.go")
+	var base = `package object
+
+type Base struct {
+	Created int64
+	Updated int64{tag}
+}
+`
+	assert.NoErr(t, ioutil.WriteFile(baseFile, []byte(strings.Replace(base, "{tag}", "", 1)), 0644))
+	var sourceFile = filepath.Join(dir, "task.go")
+	assert.NoErr(t, ioutil.WriteFile(sourceFile, []byte(`package object
+
+type Task struct {
+	Id   uint64
+	Base `+"`"+`objectbox:"inline"`+"`"+`
+}
+`), 0644))
+
+	var reported []string
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         sourceFile,
+		ModelInfoFile:  filepath.Join(dir, "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&gogenerator.GoGenerator{}},
+		PinUids:        true,
+		Diagnostics: func(d *diagnostics.Diagnostic) {
+			if d.Severity == diagnostics.SeverityWarning {
+				reported = append(reported, d.String())
+			}
+		},
+	}
+	assert.NoErr(t, generator.Process(options))
+
+	// the fields of the embedded struct are reported instead of pinned
+	modelInfo, err := model.ReadModel(options.ModelInfoFile)
+	assert.NoErr(t, err)
+	var warning = func(line int, property string) string {
+		return expand(t, modelInfo, fmt.Sprintf("%s:%d:2: warning[OBX1005]: UID of Task.%s isn't pinned because it's declared in the "+
+			"embedded struct Base; unless the struct is embedded in other entities too, add uid:{Task.%s} to its tag manually", baseFile, line, property, property))
+	}
+	assert.Eq(t, []string{warning(4, "Created"), warning(5, "Updated")}, reported)
+	data, err := ioutil.ReadFile(baseFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, strings.Replace(base, "{tag}", "", 1), string(data))
+	data, err = ioutil.ReadFile(sourceFile)
+	assert.NoErr(t, err)
+	assert.Eq(t, expand(t, modelInfo, `package object
+
+// `+"`"+`objectbox:"uid:{Task}"`+"`"+`
+type Task struct {
+	Id   uint64 `+"`"+`objectbox:"uid:{Task.Id}"`+"`"+`
+	Base `+"`"+`objectbox:"inline"`+"`"+`
+}
+`), string(data))
+
+	// a field pinned manually isn't reported anymore
+	base = strings.Replace(base, "{tag}", " `objectbox:\"uid:{Task.Updated}\"`", 1)
+	assert.NoErr(t, ioutil.WriteFile(baseFile, []byte(expand(t, modelInfo, base)), 0644))
+	reported = nil
+	assert.NoErr(t, generator.Process(options))
+	assert.Eq(t, []string{warning(4, "Created")}, reported)
+}
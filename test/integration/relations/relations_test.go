@@ -0,0 +1,120 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package relations
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	gogenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/go"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+const schemaA = `namespace na;
+
+/// objectbox:relation(to = B, name = bs)
+table A {
+	id: ulong;
+	/// objectbox:relation=B
+	bId: ulong;
+}
+`
+
+const schemaB = `namespace nb;
+
+table B {
+	id: ulong;
+	/// objectbox:relation=A
+	aId: ulong;
+}
+`
+
+func TestCrossFileRelations(t *testing.T) {
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.MkdirAll("schema", 0755))
+	assert.NoErr(t, memory.WriteFile(filepath.Join("schema", "a.fbs"), []byte(schemaA), 0644))
+	assert.NoErr(t, memory.WriteFile(filepath.Join("schema", "b.fbs"), []byte(schemaB), 0644))
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         "schema",
+		ModelInfoFile:  filepath.Join("schema", "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		Cache:          true,
+		FS:             memory,
+	}
+
+	var assertBindings = func() {
+		data, err := memory.ReadFile(filepath.Join("schema", "a.obx.hpp"))
+		assert.NoErr(t, err)
+		for _, expected := range []string{"namespace nb { struct B; }", "obx::RelationProperty<A, nb::B> bId;", "obx::RelationStandalone<A, nb::B> bs;"} {
+			if !strings.Contains(string(data), expected) {
+				t.Fatalf("a.obx.hpp doesn't contain %q:\n%s", expected, data)
+			}
+		}
+
+		data, err = memory.ReadFile(filepath.Join("schema", "b.obx.hpp"))
+		assert.NoErr(t, err)
+		assert.True(t, strings.Contains(string(data), "namespace na { struct A; }"))
+	}
+
+	// the standalone relation target is declared in a file merged later
+	assert.NoErr(t, generator.Process(options))
+	assertBindings()
+
+	// the relation target namespace is known even if its source is up-to-date in the cache
+	assert.NoErr(t, memory.WriteFile(filepath.Join("schema", "a.fbs"), []byte("// changed\n"+schemaA), 0644))
+	assert.NoErr(t, generator.Process(options))
+	assertBindings()
+
+	// a changed relation target invalidates the cached bindings of the sources relating to it
+	assert.NoErr(t, memory.WriteFile(filepath.Join("schema", "b.fbs"), []byte(strings.Replace(schemaB, "namespace nb;", "namespace other;", 1)), 0644))
+	assert.NoErr(t, generator.Process(options))
+	data, err := memory.ReadFile(filepath.Join("schema", "a.obx.hpp"))
+	assert.NoErr(t, err)
+	assert.True(t, strings.Contains(string(data), "obx::RelationProperty<A, other::B> bId;"))
+}
+
+func TestCrossFileRelationCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator-relations")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package object\n\ntype A struct {\n\tId uint64\n\tB  *B `objectbox:\"link\"`\n}\n"), 0644))
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, "b.go"), []byte("package object\n\ntype B struct {\n\tId uint64\n\tA  *A `objectbox:\"link\"`\n}\n"), 0644))
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         dir,
+		ModelInfoFile:  filepath.Join(dir, "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&gogenerator.GoGenerator{}},
+	}
+	err = generator.Process(options)
+	assert.Err(t, err)
+	assert.Eq(t, "relation cycle detected: A.B.A (A)", err.Error())
+}
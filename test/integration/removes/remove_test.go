@@ -21,6 +21,7 @@
 package rename
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -98,11 +99,13 @@ table EntityB {
 
 	// STEP-2 start
 	conf.CreateCMake(t, integration.CppDefault, "step-2.cpp")
-	conf.Generate(t, map[string]string{"": "", "schema.fbs": `
+	// the removal of the entity must be acknowledged, otherwise the generator refuses to remove it
+	conf.Generate(t, map[string]string{"": "", "schema.fbs": fmt.Sprintf(`
+/// objectbox:removed=%d
 table EntityB {
 	id:uint64;
 	name:string;
-}`})
+}`, entityUids[0])})
 	modelInfo, err = model.LoadModelFromJSONFile(modelJSONFile)
 	assert.NoErr(t, err)
 	assert.Eq(t, 1, len(modelInfo.Entities))
@@ -0,0 +1,246 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package resolve
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+// generateModel runs the generator on the schema, starting with the given model JSON (if any), returns the new one
+func generateModel(t *testing.T, seed int64, modelJSON []byte, schema string) []byte {
+	var memory = vfs.NewMemory()
+	if modelJSON != nil {
+		assert.NoErr(t, memory.WriteFile("objectbox-model.json", modelJSON, 0644))
+	}
+	assert.NoErr(t, memory.MkdirAll("schemas", 0755))
+	assert.NoErr(t, memory.WriteFile(filepath.Join("schemas", "schema.fbs"), []byte(schema), 0644))
+	assert.NoErr(t, generator.Process(generator.Options{
+		Rand:           rand.New(rand.NewSource(seed)),
+		InPath:         "schemas",
+		ModelInfoFile:  "objectbox-model.json",
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:             memory,
+	}))
+	data, err := memory.ReadFile("objectbox-model.json")
+	assert.NoErr(t, err)
+	return data
+}
+
+// mergeFile merges the files the way git does, returning the result with conflict markers
+func mergeFile(t *testing.T, ours, base, theirs []byte, diff3 bool) []byte {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir, err := ioutil.TempDir("", "objectbox-resolve")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(dir)
+
+	var args = []string{"merge-file", "-p"}
+	if diff3 {
+		args = append(args, "--diff3")
+	}
+	for name, data := range map[string][]byte{"ours": ours, "base": base, "theirs": theirs} {
+		assert.NoErr(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+	args = append(args, filepath.Join(dir, "ours"), filepath.Join(dir, "base"), filepath.Join(dir, "theirs"))
+
+	// the exit code is the number of conflicts
+	out, _ := exec.Command("git", args...).Output()
+	assert.True(t, strings.Contains(string(out), "<<<<<<<"))
+	return out
+}
+
+func parseModel(t *testing.T, data []byte) *model.ModelInfo {
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.WriteFile("objectbox-model.json", data, 0644))
+	modelInfo, err := model.ReadModelFS(memory, "objectbox-model.json")
+	assert.NoErr(t, err)
+	return modelInfo
+}
+
+func describe(modelInfo *model.ModelInfo) []string {
+	var names []string
+	for _, entity := range modelInfo.Entities {
+		var properties []string
+		for _, property := range entity.Properties {
+			properties = append(properties, property.Name)
+		}
+		names = append(names, entity.Name+"("+strings.Join(properties, ",")+")")
+	}
+	return names
+}
+
+func TestResolve(t *testing.T) {
+	var base = generateModel(t, 0, nil, "table A { id: ulong; name: string; }")
+
+	// both branches add an entity and a property, each with an index
+	var ours = generateModel(t, 1, base, `
+table A {
+	id: ulong;
+	name: string;
+	/// objectbox:index
+	ours: string;
+}
+table B {
+	id: ulong;
+	/// objectbox:index
+	name: string;
+}`)
+	var theirs = generateModel(t, 2, base, `
+table A {
+	id: ulong;
+	name: string;
+	/// objectbox:index
+	theirs: string;
+}
+table C {
+	id: ulong;
+	/// objectbox:index
+	name: string;
+}`)
+
+	for _, diff3 := range []bool{true, false} {
+		resolved, err := model.ResolveConflicts(mergeFile(t, ours, base, theirs, diff3))
+		assert.NoErr(t, err)
+		assert.Eq(t, []string{"A(id,name,ours,theirs)", "B(id,name)", "C(id,name)"}, describe(resolved))
+
+		// their new elements got new IDs
+		var getId = func(idUid model.IdUid) model.Id {
+			id, err := idUid.GetId()
+			assert.NoErr(t, err)
+			return id
+		}
+		assert.Eq(t, model.Id(3), getId(resolved.Entities[2].Id))
+		assert.Eq(t, resolved.Entities[2].Id, resolved.LastEntityId)
+		assert.Eq(t, model.Id(4), getId(resolved.Entities[0].Properties[3].Id))
+		assert.Eq(t, resolved.Entities[0].Properties[3].Id, resolved.Entities[0].LastPropertyId)
+		assert.Eq(t, model.Id(4), getId(resolved.LastIndexId))
+		var indexIds = map[model.Id]bool{}
+		for _, entity := range resolved.Entities {
+			for _, property := range entity.Properties {
+				if property.IndexId != nil {
+					indexIds[getId(*property.IndexId)] = true
+				}
+			}
+		}
+		assert.Eq(t, map[model.Id]bool{1: true, 2: true, 3: true, 4: true}, indexIds)
+
+		// the result is what the generator would have created for the merged sources, it doesn't change it anymore
+		data, err := resolved.Marshal()
+		assert.NoErr(t, err)
+		assert.Eq(t, string(data), string(generateModel(t, 3, data, `
+table A {
+	id: ulong;
+	name: string;
+	/// objectbox:index
+	ours: string;
+	/// objectbox:index
+	theirs: string;
+}
+table B {
+	id: ulong;
+	/// objectbox:index
+	name: string;
+}
+table C {
+	id: ulong;
+	/// objectbox:index
+	name: string;
+}`)))
+	}
+}
+
+func TestResolveChangedOnOneSide(t *testing.T) {
+	var base = generateModel(t, 0, nil, "table A { id: ulong; name: string; }\ntable B { id: ulong; }\ntable C { id: ulong; }")
+	var baseModel = parseModel(t, base)
+	aUid, err := baseModel.Entities[0].Id.GetUid()
+	assert.NoErr(t, err)
+	cUid, err := baseModel.Entities[2].Id.GetUid()
+	assert.NoErr(t, err)
+
+	// ours renames A and adds D, theirs adds a property to A (changing the adjacent lastPropertyId) and removes C
+	var ours = generateModel(t, 1, base, fmt.Sprintf(`
+/// objectbox:uid=%d
+table Renamed { id: ulong; name: string; }
+table B { id: ulong; }
+table C { id: ulong; }
+table D { id: ulong; }`, aUid))
+	var theirs = generateModel(t, 2, base, fmt.Sprintf(`
+/// objectbox:removed=%d
+table A { id: ulong; name: string; extra: string; }
+table B { id: ulong; }`, cUid))
+
+	// only the base tells which side has changed the entity
+	_, err = model.ResolveConflicts(mergeFile(t, ours, base, theirs, false))
+	assert.Err(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "entity Renamed differs on both sides"))
+
+	resolved, err := model.ResolveConflicts(mergeFile(t, ours, base, theirs, true))
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{"Renamed(id,name,extra)", "B(id)", "D(id)"}, describe(resolved))
+	assert.Eq(t, []model.Uid{cUid}, resolved.RetiredEntityUids)
+}
+
+func TestResolveCleanlyMergedParts(t *testing.T) {
+	var schema = "table A {\nid: ulong;\na1: string;\na2: string;\na3: string;\n}\ntable B {\nid: ulong;\nb1: string;\nb2: string;\nb3: string;\n}"
+	var base = generateModel(t, 0, nil, schema)
+
+	// indexes added to distant properties, only the lastIndexId conflicts: both indexes are present on both sides
+	var ours = generateModel(t, 1, base, strings.Replace(schema, "a3: string;", "/// objectbox:index\na3: string;", 1))
+	var theirs = generateModel(t, 2, base, strings.Replace(schema, "b3: string;", "/// objectbox:index\nb3: string;", 1))
+
+	resolved, err := model.ResolveConflicts(mergeFile(t, ours, base, theirs, true))
+	assert.NoErr(t, err)
+	assert.Eq(t, "1:", string(*resolved.Entities[0].Properties[3].IndexId)[0:2])
+	assert.Eq(t, "2:", string(*resolved.Entities[1].Properties[3].IndexId)[0:2])
+	assert.Eq(t, *resolved.Entities[1].Properties[3].IndexId, resolved.LastIndexId)
+}
+
+func TestResolveErrors(t *testing.T) {
+	var base = generateModel(t, 0, nil, "table A { id: ulong; }")
+
+	_, err := model.ResolveConflicts(base)
+	assert.Eq(t, "no conflict markers found", err.Error())
+
+	_, err = model.ResolveConflicts(append([]byte("<<<<<<< ours\n"), base...))
+	assert.Eq(t, "unterminated conflict at the end of the file", err.Error())
+
+	// both sides add the same entity
+	var ours = generateModel(t, 1, base, "table A { id: ulong; }\ntable B { id: ulong; }")
+	var theirs = generateModel(t, 2, base, "table A { id: ulong; }\ntable B { id: ulong; }")
+	_, err = model.ResolveConflicts(mergeFile(t, ours, base, theirs, true))
+	assert.Err(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "entity B was added on both sides with different UIDs"))
+}
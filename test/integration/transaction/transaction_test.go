@@ -0,0 +1,115 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package transaction
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+// failingFS fails writing (or renaming to) the files with the given name
+type failingFS struct {
+	*vfs.Memory
+	failWrite  string
+	failRename string
+}
+
+func (fsys failingFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if len(fsys.failWrite) > 0 && strings.Contains(filepath.Base(name), fsys.failWrite) {
+		return errors.New("disk full")
+	}
+	return fsys.Memory.WriteFile(name, data, perm)
+}
+
+func (fsys failingFS) Rename(oldName, newName string) error {
+	if len(fsys.failRename) > 0 && filepath.Base(newName) == fsys.failRename {
+		return errors.New("access denied")
+	}
+	return fsys.Memory.Rename(oldName, newName)
+}
+
+// snapshot returns the contents of all the files
+func snapshot(t *testing.T, fsys *vfs.Memory) map[string]string {
+	var result = make(map[string]string)
+	entries, err := fsys.ReadDir("schema")
+	assert.NoErr(t, err)
+	for _, entry := range entries {
+		data, err := fsys.ReadFile(filepath.Join("schema", entry.Name()))
+		assert.NoErr(t, err)
+		result[entry.Name()] = string(data)
+	}
+	return result
+}
+
+func TestTransactionalWrites(t *testing.T) {
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, memory.MkdirAll("schema", 0755))
+	var write = func(name, content string) {
+		assert.NoErr(t, memory.WriteFile(filepath.Join("schema", name), []byte(content), 0644))
+	}
+	write("a.fbs", "table A { id: ulong; }")
+	write("b.fbs", "table B { id: ulong; }")
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         "schema",
+		ModelInfoFile:  filepath.Join("schema", "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		FS:             memory,
+	}
+	assert.NoErr(t, generator.Process(options))
+
+	// the first run creates the model, there's nothing to back up
+	_, err := memory.Stat(generator.BackupFile(options.ModelInfoFile))
+	assert.True(t, os.IsNotExist(err))
+
+	write("a.fbs", "table A { id: ulong; name: string; }")
+	write("b.fbs", "table B { id: ulong; name: string; }")
+	var before = snapshot(t, memory)
+
+	// failing to write a file, or to rename one to its final name, leaves all files as they were, without temp files
+	for _, fsys := range []failingFS{{Memory: memory, failWrite: "b.obx.cpp"}, {Memory: memory, failRename: "b.obx.hpp"}} {
+		options.FS = fsys
+		err = generator.Process(options)
+		assert.Err(t, err)
+		assert.Eq(t, before, snapshot(t, memory))
+	}
+
+	// a successful run keeps the previous model as a backup
+	options.FS = memory
+	assert.NoErr(t, generator.Process(options))
+	var after = snapshot(t, memory)
+	assert.Eq(t, before["objectbox-model.json"], after["objectbox-model.json.bak"])
+	assert.True(t, after["objectbox-model.json"] != before["objectbox-model.json"])
+	assert.True(t, strings.Contains(after["b.obx.hpp"], "name"))
+	for name := range after {
+		assert.True(t, !strings.HasSuffix(name, ".tmp"))
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package typechange
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+func TestIsCompatibleType(t *testing.T) {
+	var longs = []model.PropertyType{model.PropertyTypeLong, model.PropertyTypeRelation, model.PropertyTypeDate, model.PropertyTypeDateNano}
+	var isDate = map[model.PropertyType]bool{model.PropertyTypeDate: true, model.PropertyTypeDateNano: true}
+
+	// all stored as 64-bit integers, but milliseconds and nanoseconds can't be mixed
+	for _, from := range longs {
+		for _, to := range longs {
+			var property = &model.Property{Type: to}
+			var expected = from == to || !(isDate[from] && isDate[to])
+			if property.IsCompatibleType(from) != expected {
+				t.Fatalf("type change from %s to %s: expected compatible=%v", model.PropertyTypeNames[from], model.PropertyTypeNames[to], expected)
+			}
+		}
+	}
+
+	for _, types := range [][2]model.PropertyType{
+		{model.PropertyTypeString, model.PropertyTypeLong},
+		{model.PropertyTypeInt, model.PropertyTypeLong},
+		{model.PropertyTypeLong, model.PropertyTypeDouble},
+		{model.PropertyTypeString, model.PropertyTypeStringVector},
+	} {
+		var property = &model.Property{Type: types[1]}
+		assert.True(t, !property.IsCompatibleType(types[0]))
+		assert.True(t, property.IsCompatibleType(types[1]))
+	}
+}
+
+// generate writes the schema with the given declaration of A.value and runs the generator on it
+func generate(fsys vfs.FS, value string) error {
+	var schema = `table B {
+	id: ulong;
+}
+table A {
+	id: ulong;
+	` + strings.Replace(value, "\n", "\n\t", -1) + `
+}`
+	if err := fsys.WriteFile("schema.fbs", []byte(schema), 0644); err != nil {
+		return err
+	}
+	return generator.Process(generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         "schema.fbs",
+		ModelInfoFile:  "objectbox-model.json",
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{PlainC: true}},
+		FS:             fsys,
+		// the index of a relation is removed when changing the type back
+		AllowDestructive: true,
+	})
+}
+
+func readValue(t *testing.T, fsys vfs.FS) (*model.ModelInfo, *model.Property) {
+	modelInfo, err := model.ReadModelFS(fsys, "objectbox-model.json")
+	assert.NoErr(t, err)
+	entity, err := modelInfo.FindEntityByName("A")
+	assert.NoErr(t, err)
+	property, err := entity.FindPropertyByName("value")
+	assert.NoErr(t, err)
+	return modelInfo, property
+}
+
+func TestTypeChange(t *testing.T) {
+	var memory = vfs.NewMemory()
+	assert.NoErr(t, generate(memory, "value: long;"))
+	_, property := readValue(t, memory)
+	var id = property.Id
+
+	// the stored data is kept, the property keeps its ID/UID
+	for _, value := range []string{
+		"/// objectbox:relation=B\nvalue: ulong;",
+		"/// objectbox:date\nvalue: long;",
+		"value: long;",
+	} {
+		assert.NoErr(t, generate(memory, value))
+		_, property = readValue(t, memory)
+		assert.Eq(t, id, property.Id)
+	}
+
+	// milliseconds can't be read as nanoseconds
+	assert.NoErr(t, generate(memory, "/// objectbox:date\nvalue: long;"))
+	err := generate(memory, "/// objectbox:date-nano\nvalue: long;")
+	assert.Err(t, err)
+	assert.True(t, strings.Contains(err.Error(), "incompatible type change from Date to DateNano"))
+
+	// unless the property is reset with a new UID, retiring the previous one
+	assert.NoErr(t, generate(memory, "/// objectbox:date-nano,uid=4224530217427133440\nvalue: long;"))
+	modelInfo, property := readValue(t, memory)
+	assert.Eq(t, model.PropertyTypeDateNano, property.Type)
+	assert.NotEq(t, id, property.Id)
+	assert.Eq(t, "4224530217427133440", strings.Split(string(property.Id), ":")[1])
+	uid, err := id.GetUid()
+	assert.NoErr(t, err)
+	assert.Eq(t, []model.Uid{uid}, modelInfo.RetiredPropertyUids)
+
+	// an incompatible reset works the same way
+	assert.NoErr(t, generate(memory, "/// objectbox:uid=2969101245133284659\nvalue: string;"))
+	_, property = readValue(t, memory)
+	assert.Eq(t, model.PropertyTypeString, property.Type)
+}
@@ -0,0 +1,152 @@
+/*
+ * ObjectBox Generator - a build time tool for ObjectBox
+ * Copyright (C) 2024 ObjectBox Ltd. All rights reserved.
+ * https://objectbox.io
+ *
+ * This file is part of ObjectBox Generator.
+ *
+ * ObjectBox Generator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ * ObjectBox Generator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with ObjectBox Generator.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package watch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/objectbox/objectbox-generator/v4/internal/generator"
+	cgenerator "github.com/objectbox/objectbox-generator/v4/internal/generator/c"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/model"
+	"github.com/objectbox/objectbox-generator/v4/internal/generator/vfs"
+	"github.com/objectbox/objectbox-generator/v4/test/assert"
+)
+
+// waitForFile waits until the given file exists and contains the given string (or doesn't exist if contains is empty)
+func waitForFile(t *testing.T, file string, contains string) {
+	var deadline = time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		data, err := ioutil.ReadFile(file)
+		if len(contains) == 0 && os.IsNotExist(err) {
+			return
+		} else if len(contains) > 0 && err == nil && strings.Contains(string(data), contains) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for %s to contain %q", file, contains)
+}
+
+func TestWatch(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "generator-watch")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(tempDir)
+
+	var modelFile = filepath.Join(tempDir, "objectbox-model.json")
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         tempDir,
+		ModelInfoFile:  modelFile,
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+		// properties and entities are removed by the edits below
+		AllowDestructive: true,
+	}
+
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "a.fbs"), []byte("table A {id: uint64;}"), 0600))
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "b.fbs"), []byte("table B {id: uint64;}"), 0600))
+
+	var stop = make(chan struct{})
+	var result = make(chan error)
+	go func() {
+		result <- generator.Watch(options, 10*time.Millisecond, stop)
+	}()
+
+	waitForFile(t, filepath.Join(tempDir, "b.obx.hpp"), "struct B")
+
+	// a changed file is regenerated
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "a.fbs"), []byte("table A {id: uint64; name: string;}"), 0600))
+	waitForFile(t, filepath.Join(tempDir, "a.obx.hpp"), "name")
+
+	// errors don't stop watching
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "a.fbs"), []byte("table A {id: uint64; name: unknown;}"), 0600))
+	time.Sleep(100 * time.Millisecond)
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "a.fbs"), []byte("table A {id: uint64; text: string;}"), 0600))
+	waitForFile(t, filepath.Join(tempDir, "a.obx.hpp"), "text")
+
+	// the model stays locked between the runs, which use the cache
+	_, err = model.LoadOrCreateModelFS(vfs.OS, modelFile, 10*time.Millisecond)
+	assert.Err(t, err)
+	_, err = os.Stat(generator.CacheFile(modelFile))
+	assert.NoErr(t, err)
+
+	// a removed file triggers a full run, removing its entity from the model
+	assert.NoErr(t, os.Remove(filepath.Join(tempDir, "b.fbs")))
+	waitForFile(t, filepath.Join(tempDir, "b.obx.hpp"), "")
+
+	close(stop)
+	assert.NoErr(t, <-result)
+
+	modelJSON, err := ioutil.ReadFile(modelFile)
+	assert.NoErr(t, err)
+	assert.True(t, strings.Contains(string(modelJSON), `"name": "text"`))
+	assert.True(t, !strings.Contains(string(modelJSON), `"name": "name"`))
+	assert.True(t, !strings.Contains(string(modelJSON), `"name": "B"`))
+
+	// the model is released when the watching stops
+	modelInfo, err := model.LoadOrCreateModelFS(vfs.OS, modelFile, 10*time.Millisecond)
+	assert.NoErr(t, err)
+	assert.NoErr(t, modelInfo.Close())
+}
+
+func TestWatchCrossFileRelation(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "generator-watch")
+	assert.NoErr(t, err)
+	defer os.RemoveAll(tempDir)
+
+	var options = generator.Options{
+		Rand:           rand.New(rand.NewSource(0)),
+		InPath:         tempDir,
+		ModelInfoFile:  filepath.Join(tempDir, "objectbox-model.json"),
+		CodeGenerators: []generator.CodeGenerator{&cgenerator.CGenerator{LangVersion: 14}},
+	}
+
+	var schemaA = "table A {\n\tid: uint64;\n\t/// objectbox:relation=B\n\tbId: uint64;%s\n}"
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "a.fbs"), []byte(fmt.Sprintf(schemaA, "")), 0600))
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "b.fbs"), []byte("namespace ns;\ntable B {id: uint64;}"), 0600))
+
+	var stop = make(chan struct{})
+	var result = make(chan error)
+	go func() {
+		result <- generator.Watch(options, 10*time.Millisecond, stop)
+	}()
+
+	var header = filepath.Join(tempDir, "a.obx.hpp")
+	waitForFile(t, header, "obx::RelationProperty<A, ns::B> bId;")
+
+	// the relation target declared in the other (unchanged) file keeps its namespace
+	assert.NoErr(t, ioutil.WriteFile(filepath.Join(tempDir, "a.fbs"), []byte(fmt.Sprintf(schemaA, " rating: int;")), 0600))
+	waitForFile(t, header, "rating")
+
+	close(stop)
+	assert.NoErr(t, <-result)
+
+	data, err := ioutil.ReadFile(header)
+	assert.NoErr(t, err)
+	assert.True(t, strings.Contains(string(data), "namespace ns { struct B; }"))
+	assert.True(t, strings.Contains(string(data), "obx::RelationProperty<A, ns::B> bId;"))
+}
@@ -43,6 +43,15 @@ void fbs_error_free(const char* error);
 /// @return a pointer to the loaded FB of the schema. Must be freed after use by calling fbs_schema_free()
 FBS_bytes* fbs_schema_parse_file(const char* filename, const char** out_error);
 
+/// Parses a FlatBuffers schema from memory, without accessing the file system.
+/// @param out_error - error if any occurred in which case you must free it using fbs_error_free() after reading.
+/// @param filenames paths of the given files; the first one is the schema to parse, the others are used to resolve
+///        its (transitive) include statements, relative to the directory of the including file.
+/// @param contents null-terminated contents of the files, in the same order as filenames.
+/// @param count number of the given files, at least one.
+/// @return a pointer to the loaded FB of the schema. Must be freed after use by calling fbs_schema_free()
+FBS_bytes* fbs_schema_parse(const char** filenames, const char** contents, size_t count, const char** out_error);
+
 /// Frees memory of both FBS_bytes as well as the inner schema->data
 void fbs_schema_free(FBS_bytes* schema);
 
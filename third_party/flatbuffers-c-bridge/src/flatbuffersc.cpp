@@ -22,6 +22,10 @@
 #include <flatbuffers/idl.h>
 #include <flatbuffers/util.h>
 
+#include <map>
+#include <mutex>
+#include <vector>
+
 #include "utils.h"
 
 void fbs_error_free(const char* error) {
@@ -31,6 +35,81 @@ void fbs_error_free(const char* error) {
     free((void*) error);
 }
 
+namespace {
+
+FBS_bytes* parseSchema(const char* contents, const char* filename) {
+    auto options = flatbuffers::IDLOptions();
+    options.binary_schema_comments = true;  // include doc comments in the binary schema
+
+    flatbuffers::Parser parser(options);
+    if (!parser.Parse(contents, nullptr, filename)) {
+        throw std::runtime_error(parser.error_);
+    }
+
+    if (!parser.error_.empty()) {
+        bool ignore = false;
+        if (parser.has_warning_) {
+            if (parser.error_.find("warning: field names should be lowercase snake_case, got:") != std::string::npos) {
+                ignore = true;
+            }
+        }
+        if (!ignore) {
+            throw std::runtime_error(parser.error_);
+        }
+    }
+
+    parser.Serialize();
+
+    size_t size = parser.builder_.GetSize();
+    VERIFY_STATE(size > 0);
+    return mallocedBytesCopy("schema", parser.builder_.GetBufferPointer(), size);
+}
+
+/// Removes "." and "dir/.." elements so that paths constructed by the parser match the given file names.
+std::string normalizePath(const std::string& path) {
+    std::vector<std::string> elements;
+    size_t start = 0;
+    while (start <= path.size()) {
+        size_t end = path.find('/', start);
+        if (end == std::string::npos) end = path.size();
+        std::string element = path.substr(start, end - start);
+        if (element == "..") {
+            if (!elements.empty() && !elements.back().empty() && elements.back() != "..") {
+                elements.pop_back();
+            } else {
+                elements.push_back(element);
+            }
+        } else if (element != "." && !(element.empty() && !elements.empty())) {
+            elements.push_back(element);
+        }
+        start = end + 1;
+    }
+
+    std::string result;
+    for (size_t i = 0; i < elements.size(); i++) {
+        if (i > 0) result += '/';
+        result += elements[i];
+    }
+    return result;
+}
+
+// Files available to the parser during fbs_schema_parse(). The FlatBuffers file hooks are global, thus the mutex.
+std::mutex memoryFilesMutex;
+std::map<std::string, std::string>* memoryFiles = nullptr;
+
+bool memoryFileExists(const char* filename) {
+    return memoryFiles->find(normalizePath(flatbuffers::PosixPath(filename))) != memoryFiles->end();
+}
+
+bool memoryFileLoad(const char* filename, bool, std::string* dest) {
+    auto it = memoryFiles->find(normalizePath(flatbuffers::PosixPath(filename)));
+    if (it == memoryFiles->end()) return false;
+    *dest = it->second;
+    return true;
+}
+
+}  // namespace
+
 FBS_bytes* fbs_schema_parse_file(const char* filename, const char** out_error) {
     return runCpp(out_error, nullptr, [&]() -> FBS_bytes* {
         VERIFY_ARGUMENT_NOT_NULL(filename);
@@ -40,31 +119,40 @@ FBS_bytes* fbs_schema_parse_file(const char* filename, const char** out_error) {
             throw std::invalid_argument(std::string("unable to load file: ") + filename);
         }
 
-        auto options = flatbuffers::IDLOptions();
-        options.binary_schema_comments = true;  // include doc comments in the binary schema
+        return parseSchema(contents.c_str(), filename);
+    });
+}
+
+FBS_bytes* fbs_schema_parse(const char** filenames, const char** contents, size_t count, const char** out_error) {
+    return runCpp(out_error, nullptr, [&]() -> FBS_bytes* {
+        VERIFY_ARGUMENT_NOT_NULL(filenames);
+        VERIFY_ARGUMENT_NOT_NULL(contents);
+        VERIFY_STATE(count > 0);
 
-        flatbuffers::Parser parser(options);
-        if (!parser.Parse(contents.c_str(), nullptr, filename)) {
-            throw std::runtime_error(parser.error_);
+        std::map<std::string, std::string> files;
+        for (size_t i = 0; i < count; i++) {
+            VERIFY_ARGUMENT_NOT_NULL(filenames[i]);
+            VERIFY_ARGUMENT_NOT_NULL(contents[i]);
+            files[normalizePath(flatbuffers::PosixPath(filenames[i]))] = contents[i];
         }
 
-        if (!parser.error_.empty()) {
-            bool ignore = false;
-            if (parser.has_warning_) {
-                if (parser.error_.find("warning: field names should be lowercase snake_case, got:") != std::string::npos) {
-                    ignore = true;
-                }
-            }
-            if (!ignore) {
-                throw std::runtime_error(parser.error_);
-            }
-        }
+        std::lock_guard<std::mutex> lock(memoryFilesMutex);
+        memoryFiles = &files;
+        auto previousLoad = flatbuffers::SetLoadFileFunction(memoryFileLoad);
+        auto previousExists = flatbuffers::SetFileExistsFunction(memoryFileExists);
 
-        parser.Serialize();
+        // restore the file system access even if the parser throws
+        struct Restore {
+            flatbuffers::LoadFileFunction load;
+            flatbuffers::FileExistsFunction exists;
+            ~Restore() {
+                flatbuffers::SetLoadFileFunction(load);
+                flatbuffers::SetFileExistsFunction(exists);
+                memoryFiles = nullptr;
+            }
+        } restore{previousLoad, previousExists};
 
-        size_t size = parser.builder_.GetSize();
-        VERIFY_STATE(size > 0);
-        return mallocedBytesCopy("schema", parser.builder_.GetBufferPointer(), size);
+        return parseSchema(contents[0], filenames[0]);
     });
 }
 
@@ -92,6 +92,26 @@ TEST_CASE("schema-parser", "") {
     fbs_schema_free(schema);
 }
 
+TEST_CASE("schema-parser-memory", "") {
+    Error error;
+    const char* filenames[] = {"dir/main.fbs", "dir/sub/../other.fbs"};
+    const char* contents[] = {"include \"other.fbs\";\n/// Main doc\ntable Main { other: Other; }",
+                              "table Other { name: string; }"};
+    FBS_bytes* schema = fbs_schema_parse(filenames, contents, 2, error.ptr());
+    CAPTURE(error.text);
+    REQUIRE(schema != nullptr);
+
+    std::string str(static_cast<char*>(schema->data), schema->size);
+    REQUIRE_THAT(str, Catch::Contains("Main doc"));
+    REQUIRE_THAT(str, Catch::Contains("Other"));
+    fbs_schema_free(schema);
+
+    // only the given files are available
+    schema = fbs_schema_parse(filenames, contents, 1, error.ptr());
+    REQUIRE(schema == nullptr);
+    REQUIRE_THAT(error.text, Catch::Contains("unable to locate include file: other.fbs"));
+}
+
 TEST_CASE("flatc-main", "") {
     Error error;
 